@@ -0,0 +1,73 @@
+package alerts
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+)
+
+// monitorBuffer is how many slot changes the monitor may fall behind before
+// it has to resubscribe.
+const monitorBuffer = 1024
+
+// Monitor evaluates the capacity thresholds of a parking lot after every
+// park, unpark and maintenance change, and sends the resulting alerts to the
+// notifiers.
+type Monitor struct {
+	repo      *repository.Repository
+	bus       *events.Bus
+	notifiers []Notifier
+}
+
+func NewMonitor(repo *repository.Repository, bus *events.Bus, notifiers ...Notifier) *Monitor {
+	return &Monitor{repo: repo, bus: bus, notifiers: notifiers}
+}
+
+// Run follows the slot changes on the event bus until the context is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	for {
+		sub := m.bus.SubscribeAll(monitorBuffer)
+		if !m.follow(ctx, sub) {
+			m.bus.Unsubscribe(sub)
+			return
+		}
+		// Dropped for falling behind, thresholds are state based so
+		// resubscribing and carrying on is enough
+		log.Warn().Msg("capacity monitor fell behind the event bus, resubscribing")
+	}
+}
+
+// follow evaluates the lot of every change until the subscription is
+// dropped, and reports false once the context is cancelled.
+func (m *Monitor) follow(ctx context.Context, sub *events.Subscription) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case change, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			m.Evaluate(change.ParkingLotID)
+		}
+	}
+}
+
+// Evaluate checks the thresholds of a parking lot and notifies the alerts it raised or cleared.
+func (m *Monitor) Evaluate(parkingLotID uint) []models.CapacityAlert {
+	alerts, err := m.repo.EvaluateCapacityThresholds(parkingLotID)
+	if err != nil {
+		log.Error().Err(err).Uint("parking_lot_id", parkingLotID).Msg("capacity evaluation failed")
+		return nil
+	}
+	for _, alert := range alerts {
+		for _, notifier := range m.notifiers {
+			if err := notifier.Notify(alert); err != nil {
+				log.Error().Err(err).Uint("parking_lot_id", parkingLotID).Msg("sending capacity alert failed")
+			}
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,70 @@
+package alerts
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/smtp"
+	"parkingManagementSystem/models"
+	"strings"
+	"time"
+)
+
+// Notifier delivers capacity alerts to operators.
+type Notifier interface {
+	Notify(alert models.CapacityAlert) error
+}
+
+// LogNotifier writes alerts to the application log on stdout.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(alert models.CapacityAlert) error {
+	log.Warn().
+		Uint("parking_lot_id", alert.ParkingLotID).
+		Int("threshold_percent", alert.ThresholdPercent).
+		Str("slot_type", alert.SlotType).
+		Str("kind", alert.Kind).
+		Int("occupied_slots", alert.OccupiedSlots).
+		Int("capacity_slots", alert.CapacitySlots).
+		Msg("capacity alert")
+	return nil
+}
+
+// SMTPNotifier mails alerts through an SMTP server without authentication,
+// such as a local relay or a development mail catcher.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	To   []string
+}
+
+func NewSMTPNotifier(addr, from string, to []string) *SMTPNotifier {
+	return &SMTPNotifier{Addr: addr, From: from, To: to}
+}
+
+func (n *SMTPNotifier) Notify(alert models.CapacityAlert) error {
+	slots := "slots"
+	if alert.SlotType != "" {
+		slots = alert.SlotType + " slots"
+	}
+	subject := fmt.Sprintf("Parking lot %d %s %s %d%% capacity", alert.ParkingLotID, slots, alertVerb(alert.Kind), alert.ThresholdPercent)
+	body := fmt.Sprintf("Parking lot %d is at %.1f%% occupancy (%d of %d %s).\r\n",
+		alert.ParkingLotID, alert.OccupancyPercent, alert.OccupiedSlots, alert.CapacitySlots, slots)
+
+	message := strings.Join([]string{
+		"From: " + n.From,
+		"To: " + strings.Join(n.To, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		body,
+	}, "\r\n")
+	return smtp.SendMail(n.Addr, nil, n.From, n.To, []byte(message))
+}
+
+func alertVerb(kind string) string {
+	if kind == models.CapacityAlertCleared {
+		return "dropped below"
+	}
+	return "reached"
+}
@@ -0,0 +1,238 @@
+package anpr
+
+import (
+	"errors"
+	"fmt"
+	"parkingManagementSystem/gates"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"time"
+)
+
+var (
+	ErrUnknownLane  = errors.New("unknown camera lane")
+	ErrNotInReview  = errors.New("camera event is not waiting for review")
+	ErrMissingPlate = errors.New("camera event has no plate")
+)
+
+// CameraRead is the plate read posted by a camera, and the line format of
+// recorded camera logs.
+type CameraRead struct {
+	Plate      string    `json:"plate"`
+	Confidence float64   `json:"confidence"`
+	Lane       string    `json:"lane"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+func (read CameraRead) Event() models.AnprEvent {
+	return models.AnprEvent{
+		Plate:      read.Plate,
+		Confidence: read.Confidence,
+		LaneID:     read.Lane,
+		CapturedAt: read.Timestamp,
+	}
+}
+
+// Processor turns plate reads from the entry and exit cameras into parks and
+// unparks. Registered cars go through ParkCar and UnparkCar, other plates get
+// a walk-in ticket session keyed by the plate.
+type Processor struct {
+	repo          *repository.Repository
+	gates         *gates.Manager
+	minConfidence float64
+	dedupWindow   time.Duration
+}
+
+func NewProcessor(repo *repository.Repository, gateManager *gates.Manager, minConfidence float64, dedupWindow time.Duration) *Processor {
+	return &Processor{
+		repo:          repo,
+		gates:         gateManager,
+		minConfidence: minConfidence,
+		dedupWindow:   dedupWindow,
+	}
+}
+
+// Process handles a new camera event and stores it with the outcome.
+// Repeat reads of the same plate on the same lane are marked as duplicates,
+// whatever their confidence, and other low-confidence reads are parked in
+// the review queue.
+func (p *Processor) Process(event *models.AnprEvent) error {
+	event.Plate = repository.NormalizePlate(event.Plate)
+	if event.Plate == "" {
+		return ErrMissingPlate
+	}
+	if event.CapturedAt.IsZero() {
+		event.CapturedAt = time.Now()
+	}
+
+	lane, err := p.repo.GetCameraLane(event.LaneID)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrUnknownLane, event.LaneID, err)
+	}
+	event.Direction = lane.Direction
+	event.ParkingLotID = lane.ParkingLotID
+
+	duplicate, err := p.repo.HasRecentAnprRead(event.Plate, event.LaneID, event.CapturedAt, p.dedupWindow)
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		event.Status = models.AnprStatusDuplicate
+		return p.repo.SaveAnprEvent(event)
+	}
+
+	if event.Confidence < p.minConfidence {
+		event.Status = models.AnprStatusReview
+		event.Reason = fmt.Sprintf("confidence %.2f below %.2f", event.Confidence, p.minConfidence)
+		return p.repo.SaveAnprEvent(event)
+	}
+
+	p.apply(event, lane)
+	return p.repo.SaveAnprEvent(event)
+}
+
+// ResolveReview lets an attendant confirm or correct the plate of a
+// low-confidence read, which is then handled like a confident one. An empty
+// plate rejects the read.
+func (p *Processor) ResolveReview(eventID uint, plate, attendant string) (*models.AnprEvent, error) {
+	event, err := p.repo.GetAnprEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != models.AnprStatusReview {
+		return nil, ErrNotInReview
+	}
+	event.ReviewedBy = attendant
+
+	if plate == "" {
+		event.Status = models.AnprStatusRejected
+		event.Reason = "rejected by attendant"
+		return event, p.repo.SaveAnprEvent(event)
+	}
+	event.Plate = repository.NormalizePlate(plate)
+	event.Reason = ""
+
+	lane, err := p.repo.GetCameraLane(event.LaneID)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrUnknownLane, event.LaneID, err)
+	}
+
+	// The corrected plate may have been read and handled on the lane while
+	// the event waited for review.
+	duplicate, err := p.repo.HasRecentAnprRead(event.Plate, event.LaneID, event.CapturedAt, p.dedupWindow)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		event.Status = models.AnprStatusDuplicate
+		return event, p.repo.SaveAnprEvent(event)
+	}
+
+	p.apply(event, lane)
+	return event, p.repo.SaveAnprEvent(event)
+}
+
+// apply parks or unparks the vehicle and sets the outcome on the event.
+func (p *Processor) apply(event *models.AnprEvent, lane *models.CameraLane) {
+	var err error
+	if lane.Direction == models.GateDirectionExit {
+		err = p.exit(event)
+	} else {
+		err = p.entry(event)
+	}
+
+	gateEvent := models.GateEvent{
+		GateID:          lane.GateID,
+		Direction:       lane.Direction,
+		ParkingLotID:    &lane.ParkingLotID,
+		CarID:           event.CarID,
+		TicketSessionID: event.TicketSessionID,
+	}
+	switch {
+	case err != nil:
+		event.Status = models.AnprStatusFailed
+		event.Reason = err.Error()
+		if lane.GateID != "" {
+			p.gates.Deny(gateEvent, event.Reason)
+		}
+	case event.Status == models.AnprStatusUnmatchedExit:
+		if lane.GateID != "" {
+			p.gates.Deny(gateEvent, event.Reason)
+		}
+	default:
+		event.Status = models.AnprStatusProcessed
+		if lane.GateID != "" {
+			p.gates.Allow(gateEvent)
+		}
+	}
+}
+
+func (p *Processor) entry(event *models.AnprEvent) error {
+	car, err := p.repo.GetCarByPlate(event.Plate)
+	if err != nil {
+		return err
+	}
+
+	if car == nil {
+		sessions, err := p.repo.FindOpenTicketSessions(event.Plate, 0, 0)
+		if err != nil {
+			return err
+		}
+		if len(sessions) > 0 {
+			return errors.New("plate already has an open session")
+		}
+		session, err := p.repo.IssueTicket(event.ParkingLotID, event.Plate)
+		if err != nil {
+			return err
+		}
+		event.TicketSessionID = &session.ID
+		return nil
+	}
+
+	event.CarID = &car.ID
+	if car.ParkingSlotID != nil {
+		return errors.New("car is already parked")
+	}
+	return p.repo.ParkCar(event.ParkingLotID, car.ID)
+}
+
+func (p *Processor) exit(event *models.AnprEvent) error {
+	car, err := p.repo.GetCarByPlate(event.Plate)
+	if err != nil {
+		return err
+	}
+	if car != nil && car.ParkingSlotID != nil {
+		event.CarID = &car.ID
+		parkingSlot, err := p.repo.GetParkingSlot(*car.ParkingSlotID)
+		if err != nil {
+			return err
+		}
+		// A car parked in another lot does not leave through this lane
+		if parkingSlot.ParkingLotID == event.ParkingLotID {
+			charge, err := p.repo.UnparkCar(car.ID)
+			if err != nil {
+				return err
+			}
+			event.AmountToBePaid = charge.TotalAmountToBePaid
+			return nil
+		}
+	}
+
+	sessions, err := p.repo.FindOpenTicketSessions(event.Plate, event.ParkingLotID, 0)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		event.Status = models.AnprStatusUnmatchedExit
+		event.Reason = "no open session for plate in this lot"
+		return nil
+	}
+
+	session, err := p.repo.CloseTicket(sessions[0].TicketCode, "")
+	if err != nil {
+		return err
+	}
+	event.TicketSessionID = &session.ID
+	event.AmountToBePaid = session.TotalAmountToBePaid
+	return nil
+}
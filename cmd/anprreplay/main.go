@@ -0,0 +1,92 @@
+// Command anprreplay feeds a recorded camera log through the plate-recognition
+// event processor, against the database configured in the environment.
+//
+// The log holds one JSON read per line, or a single JSON array of reads:
+//
+//	{"plate":"AB 123","confidence":0.93,"lane":"north-in","timestamp":"2024-03-01T08:00:00Z"}
+//
+//	go run ./cmd/anprreplay -file camera.log -realtime
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"os"
+	"parkingManagementSystem/anpr"
+	"parkingManagementSystem/config"
+	"parkingManagementSystem/state"
+	"time"
+)
+
+func main() {
+	file := flag.String("file", "", "recorded camera log to replay")
+	realtime := flag.Bool("realtime", false, "wait between reads as long as the cameras did")
+	flag.Parse()
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	_ = godotenv.Load()
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Config parsing failed")
+	}
+
+	reads, err := readLog(*file)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read camera log")
+	}
+
+	appState := state.NewState(cfg)
+	statuses := map[string]int{}
+	for i, read := range reads {
+		if *realtime && i > 0 {
+			time.Sleep(read.Timestamp.Sub(reads[i-1].Timestamp))
+		}
+
+		event := read.Event()
+		if err := appState.Anpr.Process(&event); err != nil {
+			log.Error().Err(err).Int("line", i+1).Str("plate", read.Plate).Msg("Failed to process camera event")
+			statuses["error"]++
+			continue
+		}
+		statuses[event.Status]++
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n", event.CapturedAt.Format(time.RFC3339), event.LaneID, event.Plate, event.Status, event.Reason)
+	}
+
+	for status, count := range statuses {
+		fmt.Printf("%s: %d\n", status, count)
+	}
+}
+
+func readLog(path string) ([]anpr.CameraRead, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var reads []anpr.CameraRead
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &reads)
+		return reads, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var read anpr.CameraRead
+		if err := json.Unmarshal(scanner.Bytes(), &read); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		reads = append(reads, read)
+	}
+	return reads, scanner.Err()
+}
@@ -0,0 +1,83 @@
+// Command chargersim simulates the charger of a parking slot. It charges the
+// parked vehicle at the power of the charger and reports the transaction to
+// the charger telemetry endpoint, the way an OCPP charge point would.
+//
+//	go run ./cmd/chargersim -url http://localhost:8080 -slot 12 -power 11 -energy 5 -speedup 60
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"os"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8080", "base URL of the parking management system")
+	slot := flag.Uint("slot", 0, "ID of the parking slot the charger belongs to")
+	power := flag.Float64("power", 11, "charging power in kW")
+	energy := flag.Float64("energy", 10, "energy to deliver in kWh before stopping")
+	meter := flag.Int64("meter", 0, "energy register of the charger at the start, in Wh")
+	interval := flag.Duration("interval", 10*time.Second, "time between meter values")
+	speedup := flag.Float64("speedup", 1, "how many times faster than real time the vehicle charges")
+	flag.Parse()
+	if *slot == 0 || *power <= 0 || *energy <= 0 || *speedup <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	send := func(action string, meterWh int64) {
+		telemetry := models.ChargerTelemetry{
+			ParkingSlotID: *slot,
+			Action:        action,
+			MeterWh:       meterWh,
+			Timestamp:     time.Now(),
+		}
+		if err := post(client, *url+"/chargers/telemetry", telemetry); err != nil {
+			log.Fatal().Err(err).Str("action", action).Msg("telemetry failed")
+		}
+		log.Info().Str("action", action).Int64("meter_wh", meterWh).Msg("telemetry sent")
+	}
+
+	start := *meter
+	target := start + int64(*energy*1000)
+	send(models.ChargerActionStart, start)
+
+	// Every interval of wall time charges speedup intervals worth of energy
+	whPerTick := int64(*power * 1000 * interval.Hours() * *speedup)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	current := start
+	for current < target {
+		<-ticker.C
+		current += whPerTick
+		if current >= target {
+			current = target
+			break
+		}
+		send(models.ChargerActionMeterValues, current)
+	}
+	send(models.ChargerActionStop, current)
+}
+
+func post(client *http.Client, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
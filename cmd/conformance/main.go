@@ -0,0 +1,57 @@
+// Command conformance runs the storage conformance suite against a database,
+// migrating it first. Use a scratch database: the suite creates its own lots.
+//
+//	go run ./cmd/conformance -url sqlite:///tmp/pms-conformance.db
+//	go run ./cmd/conformance -url "host=localhost user=pms dbname=pms_conformance sslmode=disable"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm/logger"
+	"os"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/repository/conformance"
+	"time"
+)
+
+func main() {
+	_ = godotenv.Load()
+	databaseUrl := flag.String("url", os.Getenv("DATABASE_URL"), "database to run the suite against")
+	flag.Parse()
+	if *databaseUrl == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+	repo, err := repository.NewRepository(*databaseUrl)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to the database")
+	}
+	// Expected misses and refused entries are not worth a log line
+	repo.DB.Logger = logger.Default.LogMode(logger.Silent)
+	if err := repo.Migrate(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to migrate the database")
+	}
+
+	failed := 0
+	for _, result := range conformance.Run(repo) {
+		status := "ok"
+		if result.Err != nil {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%-4s\t%s\t%s\n", status, result.Name, result.Duration.Round(time.Millisecond))
+		if result.Err != nil {
+			fmt.Printf("\t%v\n", result.Err)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d checks failed\n", failed)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,122 @@
+// Command gatesim simulates barrier gates speaking the line protocol of
+// gates.TCPController, for running the system locally without hardware.
+//
+//	go run ./cmd/gatesim -addr :7070
+//	GATE_CONTROLLER_ADDR=localhost:7070 go run .
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net"
+	"parkingManagementSystem/gates"
+	"strings"
+	"sync"
+	"time"
+)
+
+type simulator struct {
+	openDuration time.Duration
+	delay        time.Duration
+	faulty       map[string]bool
+
+	mu     sync.Mutex
+	status map[string]string
+	timers map[string]*time.Timer
+}
+
+func main() {
+	addr := flag.String("addr", ":7070", "address to listen on")
+	openDuration := flag.Duration("open-duration", 5*time.Second, "how long a gate stays open before closing by itself")
+	delay := flag.Duration("delay", 0, "delay before answering a command, to simulate slow gates and timeouts")
+	faulty := flag.String("faulty", "", "comma separated gate IDs that answer every command with an error")
+	flag.Parse()
+
+	sim := &simulator{
+		openDuration: *openDuration,
+		delay:        *delay,
+		faulty:       map[string]bool{},
+		status:       map[string]string{},
+		timers:       map[string]*time.Timer{},
+	}
+	for _, gateID := range strings.Split(*faulty, ",") {
+		if gateID != "" {
+			sim.faulty[gateID] = true
+		}
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("listen failed")
+	}
+	log.Info().Str("addr", *addr).Msg("gate simulator listening")
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Error().Err(err).Msg("accept failed")
+			continue
+		}
+		go sim.serve(conn)
+	}
+}
+
+func (sim *simulator) serve(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		reply := sim.handle(line)
+		log.Info().Str("remote", conn.RemoteAddr().String()).Str("command", line).Str("reply", reply).Msg("gate command")
+		if sim.delay > 0 {
+			time.Sleep(sim.delay)
+		}
+		if _, err := fmt.Fprintf(conn, "%s\n", reply); err != nil {
+			return
+		}
+	}
+}
+
+func (sim *simulator) handle(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return "ERR malformed command"
+	}
+	command, gateID := fields[0], fields[1]
+	if sim.faulty[gateID] {
+		return "ERR gate fault"
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	switch command {
+	case "OPEN":
+		sim.status[gateID] = gates.StatusOpen
+		if timer, ok := sim.timers[gateID]; ok {
+			timer.Stop()
+		}
+		sim.timers[gateID] = time.AfterFunc(sim.openDuration, func() {
+			sim.mu.Lock()
+			defer sim.mu.Unlock()
+			sim.status[gateID] = gates.StatusClosed
+			log.Info().Str("gate_id", gateID).Msg("gate closed automatically")
+		})
+		return "OK"
+	case "CLOSE":
+		if timer, ok := sim.timers[gateID]; ok {
+			timer.Stop()
+		}
+		sim.status[gateID] = gates.StatusClosed
+		return "OK"
+	case "STATUS":
+		status, ok := sim.status[gateID]
+		if !ok {
+			status = gates.StatusClosed
+		}
+		return fmt.Sprintf("STATUS %s %s", gateID, status)
+	default:
+		return "ERR unknown command"
+	}
+}
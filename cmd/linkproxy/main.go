@@ -0,0 +1,73 @@
+// Command linkproxy stands for the network link between an edge site and the
+// central instance, so a partition can be simulated locally. The edge site
+// syncs through the proxy, and the link is cut and restored on the control
+// address:
+//
+//	go run ./cmd/linkproxy -listen :8090 -target http://localhost:8080 -control :8091
+//	curl -X POST localhost:8091/down   # cut the link
+//	curl -X POST localhost:8091/up     # restore it
+//
+// While the link is down every request is dropped without a response, the
+// way a dead uplink looks to the edge site.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+)
+
+func main() {
+	listen := flag.String("listen", ":8090", "address the edge site sends to")
+	target := flag.String("target", "http://localhost:8080", "URL of the central instance")
+	control := flag.String("control", ":8091", "address to cut and restore the link on")
+	flag.Parse()
+
+	targetUrl, err := url.Parse(*target)
+	if err != nil || targetUrl.Host == "" {
+		log.Fatal().Str("target", *target).Msg("Target must be an absolute URL")
+	}
+
+	var down atomic.Bool
+	proxy := httputil.NewSingleHostReverseProxy(targetUrl)
+	go func() {
+		controls := http.NewServeMux()
+		controls.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+			down.Store(true)
+			log.Warn().Msg("Link down")
+			fmt.Fprintln(w, "down")
+		})
+		controls.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
+			down.Store(false)
+			log.Info().Msg("Link up")
+			fmt.Fprintln(w, "up")
+		})
+		controls.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if down.Load() {
+				fmt.Fprintln(w, "down")
+				return
+			}
+			fmt.Fprintln(w, "up")
+		})
+		log.Fatal().Err(http.ListenAndServe(*control, controls)).Msg("Control server stopped")
+	}()
+
+	log.Info().Str("listen", *listen).Str("target", *target).Str("control", *control).Msg("Proxying the link")
+	err = http.ListenAndServe(*listen, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !down.Load() {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+		// Drop the connection without answering
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		conn.Close()
+	}))
+	log.Fatal().Err(err).Msg("Proxy stopped")
+}
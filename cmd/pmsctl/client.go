@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiBackend runs the subcommands through the HTTP API of a running instance.
+type apiBackend struct {
+	client *http.Client
+	apiUrl string
+}
+
+func newAPIBackend(apiUrl string) *apiBackend {
+	return &apiBackend{
+		client: &http.Client{Timeout: 30 * time.Second},
+		apiUrl: strings.TrimSuffix(apiUrl, "/"),
+	}
+}
+
+func (b *apiBackend) ListLots() ([]models.ParkingLot, error) {
+	var parkingLots []models.ParkingLot
+	err := b.do(http.MethodGet, "/parking-lots", nil, nil, &parkingLots)
+	return parkingLots, err
+}
+
+func (b *apiBackend) CreateLot(parkingLot *models.ParkingLot, slots int) error {
+	body := map[string]interface{}{
+		"name":            parkingLot.Name,
+		"location":        parkingLot.Location,
+		"description":     parkingLot.Description,
+		"operator":        parkingLot.Operator,
+		"slots":           slots,
+		"lost_ticket_fee": parkingLot.LostTicketFee,
+	}
+	return b.do(http.MethodPost, "/createParking", nil, body, parkingLot)
+}
+
+func (b *apiBackend) Status(parkingLotID uint) ([]slotStatus, error) {
+	var slots []slotStatus
+	err := b.do(http.MethodGet, "/parking-lot/status", url.Values{"parking_lot_id": {formatUint(parkingLotID)}}, nil, &slots)
+	return slots, err
+}
+
+func (b *apiBackend) SetMaintenance(parkingSlotID uint, inMaintenance bool) error {
+	path := "/parking-slots/out-of-maintenance"
+	if inMaintenance {
+		path = "/parking-slots/maintenance"
+	}
+	return b.do(http.MethodPost, path, url.Values{"parking_slot_id": {formatUint(parkingSlotID)}}, nil, nil)
+}
+
+func (b *apiBackend) Park(parkingLotID, carID uint) error {
+	query := url.Values{"parking_lot_id": {formatUint(parkingLotID)}, "car_id": {formatUint(carID)}}
+	return b.do(http.MethodPost, "/parkCar", query, nil, nil)
+}
+
+func (b *apiBackend) Unpark(carID uint) (*models.ParkingCharge, error) {
+	var charge models.ParkingCharge
+	if err := b.do(http.MethodPost, "/unparkCar", url.Values{"car_id": {formatUint(carID)}}, nil, &charge); err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func (b *apiBackend) History(from, to time.Time) ([]models.ParkingHistory, error) {
+	var history []models.ParkingHistory
+	query := url.Values{"from": {from.Format("2006-01-02")}, "to": {to.Format("2006-01-02")}}
+	err := b.do(http.MethodGet, "/history/export", query, nil, &history)
+	return history, err
+}
+
+// do sends a request and decodes the data of the response into data, unless
+// it is nil. Responses other than 2xx are returned as errors with their
+// message.
+func (b *apiBackend) do(method, path string, query url.Values, body interface{}, data interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	reqUrl := b.apiUrl + path
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, reqUrl, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		utils.CommonResponse
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s %s responded with %s", method, path, resp.Status)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s (%s)", response.Message, resp.Status)
+	}
+	if data == nil || len(response.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Data, data)
+}
+
+func formatUint(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"parkingManagementSystem/config"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"time"
+)
+
+// directBackend runs the subcommands against the database, with the checks
+// the HTTP handlers make before calling the repository.
+type directBackend struct {
+	repo *repository.Repository
+}
+
+// newDirectBackend connects to the database, allocating slots with the
+// settings of the service like state.NewState does.
+func newDirectBackend(databaseUrl string, cfg *config.Config) (*directBackend, error) {
+	repo, err := repository.NewRepository(databaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	// Misses are reported as errors, not worth a log line
+	repo.DB.Logger = logger.Default.LogMode(logger.Silent)
+	repo.SensorAwareAllocation = cfg.SensorAwareAllocation
+	repo.MaintenanceLeadTime = time.Duration(cfg.MaintenanceLeadMinutes) * time.Minute
+	return &directBackend{repo: repo}, nil
+}
+
+func (b *directBackend) ListLots() ([]models.ParkingLot, error) {
+	return b.repo.GetParkingLots()
+}
+
+func (b *directBackend) CreateLot(parkingLot *models.ParkingLot, slots int) error {
+	return b.repo.CreateParkingLot(parkingLot, slots)
+}
+
+func (b *directBackend) Status(parkingLotID uint) ([]slotStatus, error) {
+	if _, err := b.repo.GetParkingLot(parkingLotID); err != nil {
+		return nil, notFound(err, "parking lot")
+	}
+	parkingSlots, err := b.repo.GetParkingSlots(parkingLotID)
+	if err != nil {
+		return nil, err
+	}
+	slots := make([]slotStatus, 0, len(parkingSlots))
+	for _, slot := range parkingSlots {
+		slots = append(slots, slotStatus{
+			ParkingSlotID:    slot.ID,
+			RelativeID:       slot.RelativeID,
+			IsInMaintenance:  slot.IsInMaintenance,
+			IsBooked:         slot.IsBooked,
+			SlotType:         slot.SlotType,
+			Zone:             slot.Zone,
+			Label:            slot.Label,
+			IsDraining:       slot.IsDraining,
+			IsDecommissioned: slot.DecommissionedAt != nil,
+			CarID:            slot.CarID,
+			TicketSessionID:  slot.TicketSessionID,
+		})
+	}
+	return slots, nil
+}
+
+func (b *directBackend) SetMaintenance(parkingSlotID uint, inMaintenance bool) error {
+	var parkingSlot models.ParkingSlot
+	if err := b.repo.First(&parkingSlot, parkingSlotID).Error; err != nil {
+		return notFound(err, "parking slot")
+	}
+	if parkingSlot.IsBooked && !parkingSlot.IsInMaintenance {
+		return fmt.Errorf("parking slot is already booked")
+	}
+	if parkingSlot.IsInMaintenance == inMaintenance {
+		if inMaintenance {
+			return fmt.Errorf("parking slot is already in maintenance")
+		}
+		return fmt.Errorf("parking slot is not in maintenance")
+	}
+	return b.repo.SetParkingSlotMaintenance(&parkingSlot, inMaintenance)
+}
+
+func (b *directBackend) Park(parkingLotID, carID uint) error {
+	var car models.Car
+	if err := b.repo.First(&car, carID).Error; err != nil {
+		return notFound(err, "car")
+	}
+	if car.ParkingSlotID != nil {
+		return fmt.Errorf("car is already parked")
+	}
+	return b.repo.ParkCar(parkingLotID, carID)
+}
+
+func (b *directBackend) Unpark(carID uint) (*models.ParkingCharge, error) {
+	var car models.Car
+	if err := b.repo.First(&car, carID).Error; err != nil {
+		return nil, notFound(err, "car")
+	}
+	if car.ParkingSlotID == nil {
+		return nil, fmt.Errorf("car is already unparked")
+	}
+	return b.repo.UnparkCar(carID)
+}
+
+func (b *directBackend) History(from, to time.Time) ([]models.ParkingHistory, error) {
+	return b.repo.GetParkingHistory(from, to.AddDate(0, 0, 1))
+}
+
+func notFound(err error, what string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s not found", what)
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+// Command pmsctl administers the parking management system from the shell. It
+// talks to a running instance over the HTTP API, or straight to its database
+// when -db is given, which also works while the service is down:
+//
+//	go run ./cmd/pmsctl lots list
+//	go run ./cmd/pmsctl lots create -name "Central" -location "Main St" -slots 40
+//	go run ./cmd/pmsctl status -lot 1
+//	go run ./cmd/pmsctl maintenance on -slot 12
+//	go run ./cmd/pmsctl park -lot 1 -car 7
+//	go run ./cmd/pmsctl unpark -car 7
+//	go run ./cmd/pmsctl history -from 2024-03-01 -to 2024-03-31 > march.csv
+//	go run ./cmd/pmsctl -db sqlite:///var/lib/pms/pms.db migrate status
+//
+// Every subcommand prints JSON instead of a table with -json. Changes made
+// with -db skip what only the service does: gates are not opened and live
+// status streams are not notified, while webhooks still go out once the
+// service delivers its outbox. Slots are allocated with the settings of the
+// service, read from the same environment.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"os"
+	"parkingManagementSystem/config"
+	"parkingManagementSystem/models"
+	"strconv"
+	"time"
+)
+
+const usage = `usage: pmsctl [-api URL | -db DATABASE_URL] [-json] <command> [flags]
+
+commands:
+  lots list                                   list the parking lots
+  lots create -name -location -slots N        create a parking lot
+  status -lot ID                              show the slots of a parking lot
+  maintenance on|off -slot ID                 put a slot in or out of maintenance
+  park -lot ID -car ID                        park a car
+  unpark -car ID                              unpark a car and show its charge
+  history -from YYYY-MM-DD -to YYYY-MM-DD     export the daily history as CSV
+  migrate up|down|status                      run schema migrations (needs -db)
+`
+
+// backend is what the subcommands run against, the HTTP API of a running
+// instance or its database.
+type backend interface {
+	ListLots() ([]models.ParkingLot, error)
+	CreateLot(parkingLot *models.ParkingLot, slots int) error
+	Status(parkingLotID uint) ([]slotStatus, error)
+	SetMaintenance(parkingSlotID uint, inMaintenance bool) error
+	Park(parkingLotID, carID uint) error
+	Unpark(carID uint) (*models.ParkingCharge, error)
+	History(from, to time.Time) ([]models.ParkingHistory, error)
+}
+
+// slotStatus is a slot as shown by the parking lot status endpoint.
+type slotStatus struct {
+	ParkingSlotID    uint   `json:"parking_slot_id"`
+	RelativeID       uint   `json:"relative_id"`
+	IsInMaintenance  bool   `json:"is_in_maintenance"`
+	IsBooked         bool   `json:"is_booked"`
+	SlotType         string `json:"slot_type"`
+	Zone             string `json:"zone,omitempty"`
+	Label            string `json:"label,omitempty"`
+	IsDraining       bool   `json:"is_draining,omitempty"`
+	IsDecommissioned bool   `json:"is_decommissioned,omitempty"`
+	CarID            *uint  `json:"carID,omitempty"`
+	TicketSessionID  *uint  `json:"ticket_session_id,omitempty"`
+}
+
+func main() {
+	_ = godotenv.Load()
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+	apiUrl := os.Getenv("PMS_API_URL")
+	if apiUrl == "" {
+		apiUrl = "http://localhost:8080"
+	}
+	flags := flag.NewFlagSet("pmsctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	api := flags.String("api", apiUrl, "URL of a running instance")
+	db := flags.String("db", "", "database to work on directly instead of the API")
+	asJSON := flags.Bool("json", false, "print JSON instead of tables")
+	flags.Parse(os.Args[1:])
+
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	out := &output{json: *asJSON}
+
+	// Migrations only make sense against the database
+	if args[0] == "migrate" {
+		if *db == "" {
+			fail(fmt.Errorf("migrate needs -db"))
+		}
+		runMigrate(*db, args[1:], out)
+		return
+	}
+
+	var b backend
+	if *db != "" {
+		cfg, err := config.NewConfig()
+		if err != nil {
+			fail(err)
+		}
+		direct, err := newDirectBackend(*db, cfg)
+		if err != nil {
+			fail(err)
+		}
+		b = direct
+	} else {
+		b = newAPIBackend(*api)
+	}
+
+	if err := run(b, args, out); err != nil {
+		fail(err)
+	}
+}
+
+func run(b backend, args []string, out *output) error {
+	command, args := args[0], args[1:]
+	switch command {
+	case "lots":
+		if len(args) == 0 {
+			return errUsage
+		}
+		switch args[0] {
+		case "list":
+			parkingLots, err := b.ListLots()
+			if err != nil {
+				return err
+			}
+			return out.lots(parkingLots)
+		case "create":
+			flags := flag.NewFlagSet("lots create", flag.ExitOnError)
+			name := flags.String("name", "", "name of the parking lot")
+			location := flags.String("location", "", "location of the parking lot")
+			description := flags.String("description", "", "description of the parking lot")
+			operator := flags.String("operator", "", "company running the parking lot")
+			slots := flags.Int("slots", 0, "number of parking slots")
+			lostTicketFee := flags.Int("lost-ticket-fee", models.DefaultLostTicketFee, "fee charged for a lost ticket")
+			flags.Parse(args[1:])
+			if *slots < 1 {
+				return fmt.Errorf("number of slots must be greater than 0")
+			}
+			if *lostTicketFee < 0 {
+				return fmt.Errorf("lost ticket fee must not be negative")
+			}
+			parkingLot := models.ParkingLot{
+				Name:          *name,
+				Location:      *location,
+				Description:   *description,
+				Operator:      *operator,
+				LostTicketFee: *lostTicketFee,
+			}
+			if err := b.CreateLot(&parkingLot, *slots); err != nil {
+				return err
+			}
+			return out.lots([]models.ParkingLot{parkingLot})
+		}
+		return errUsage
+	case "status":
+		flags := flag.NewFlagSet("status", flag.ExitOnError)
+		parkingLotID := flags.Uint("lot", 0, "parking lot ID")
+		flags.Parse(args)
+		if *parkingLotID == 0 {
+			return errUsage
+		}
+		slots, err := b.Status(*parkingLotID)
+		if err != nil {
+			return err
+		}
+		return out.status(slots)
+	case "maintenance":
+		if len(args) == 0 || (args[0] != "on" && args[0] != "off") {
+			return errUsage
+		}
+		flags := flag.NewFlagSet("maintenance", flag.ExitOnError)
+		parkingSlotID := flags.Uint("slot", 0, "parking slot ID")
+		flags.Parse(args[1:])
+		if *parkingSlotID == 0 {
+			return errUsage
+		}
+		if err := b.SetMaintenance(*parkingSlotID, args[0] == "on"); err != nil {
+			return err
+		}
+		return out.message(fmt.Sprintf("parking slot %d maintenance %s", *parkingSlotID, args[0]))
+	case "park":
+		flags := flag.NewFlagSet("park", flag.ExitOnError)
+		parkingLotID := flags.Uint("lot", 0, "parking lot ID")
+		carID := flags.Uint("car", 0, "car ID")
+		flags.Parse(args)
+		if *parkingLotID == 0 || *carID == 0 {
+			return errUsage
+		}
+		if err := b.Park(*parkingLotID, *carID); err != nil {
+			return err
+		}
+		return out.message(fmt.Sprintf("car %d parked in parking lot %d", *carID, *parkingLotID))
+	case "unpark":
+		flags := flag.NewFlagSet("unpark", flag.ExitOnError)
+		carID := flags.Uint("car", 0, "car ID")
+		flags.Parse(args)
+		if *carID == 0 {
+			return errUsage
+		}
+		charge, err := b.Unpark(*carID)
+		if err != nil {
+			return err
+		}
+		return out.charge(charge)
+	case "history":
+		flags := flag.NewFlagSet("history", flag.ExitOnError)
+		from := flags.String("from", "", "first day, YYYY-MM-DD")
+		to := flags.String("to", "", "last day, YYYY-MM-DD, included")
+		flags.Parse(args)
+		fromDate, err := time.Parse("2006-01-02", *from)
+		if err != nil {
+			return fmt.Errorf("invalid -from date: %w", err)
+		}
+		toDate, err := time.Parse("2006-01-02", *to)
+		if err != nil {
+			return fmt.Errorf("invalid -to date: %w", err)
+		}
+		if toDate.Before(fromDate) {
+			return fmt.Errorf("-to must not be before -from")
+		}
+		history, err := b.History(fromDate, toDate)
+		if err != nil {
+			return err
+		}
+		return out.history(history)
+	}
+	return errUsage
+}
+
+var errUsage = fmt.Errorf("unknown command or missing flags, run pmsctl -h for usage")
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "pmsctl:", err)
+	os.Exit(1)
+}
+
+func formatID(id *uint) string {
+	if id == nil {
+		return "-"
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
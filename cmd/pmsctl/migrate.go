@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"parkingManagementSystem/migrations"
+	"parkingManagementSystem/repository"
+)
+
+// runMigrate runs the migrate subcommand, like the migrate command of the
+// service does.
+func runMigrate(databaseUrl string, args []string, out *output) {
+	if len(args) != 1 {
+		fail(errUsage)
+	}
+	repo, err := repository.NewRepository(databaseUrl)
+	if err != nil {
+		fail(err)
+	}
+	migrator, err := repo.SchemaMigrator()
+	if err != nil {
+		fail(err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			fail(err)
+		}
+		err = out.message(fmt.Sprintf("applied %d migrations", applied))
+		if err != nil {
+			fail(err)
+		}
+	case "down":
+		reverted, err := migrator.Down()
+		if errors.Is(err, migrations.ErrNoMigrationApplied) {
+			err = out.message("no migration to revert")
+		} else if err == nil {
+			err = out.message(fmt.Sprintf("reverted %04d_%s", reverted.Version, reverted.Name))
+		}
+		if err != nil {
+			fail(err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err == nil {
+			err = out.migrations(statuses)
+		}
+		if err != nil {
+			fail(err)
+		}
+	default:
+		fail(errUsage)
+	}
+}
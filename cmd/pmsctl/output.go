@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"parkingManagementSystem/migrations"
+	"parkingManagementSystem/models"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// output prints the results of the subcommands as tables, or as JSON.
+type output struct {
+	json bool
+}
+
+func (o *output) lots(parkingLots []models.ParkingLot) error {
+	if o.json {
+		return o.encode(parkingLots)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tLOCATION\tOPERATOR\tTIMEZONE")
+	for _, parkingLot := range parkingLots {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", parkingLot.ID, parkingLot.Name, parkingLot.Location, parkingLot.Operator, parkingLot.Timezone)
+	}
+	return w.Flush()
+}
+
+func (o *output) status(slots []slotStatus) error {
+	if o.json {
+		return o.encode(slots)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SLOT ID\tRELATIVE ID\tSTATE\tTYPE\tZONE\tCAR\tTICKET")
+	free := 0
+	for _, slot := range slots {
+		state := "free"
+		switch {
+		case slot.IsInMaintenance:
+			state = "maintenance"
+		case slot.IsBooked:
+			state = "occupied"
+		default:
+			free++
+		}
+		if slot.IsDraining {
+			state += ", draining"
+		}
+		zone := slot.Zone
+		if zone == "" {
+			zone = "-"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", slot.ParkingSlotID, slot.RelativeID, state, slot.SlotType, zone, formatID(slot.CarID), formatID(slot.TicketSessionID))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d slots free\n", free, len(slots))
+	return nil
+}
+
+func (o *output) charge(charge *models.ParkingCharge) error {
+	if o.json {
+		return o.encode(charge)
+	}
+	fmt.Printf("parked %d hours, %d to pay\n", charge.TotalParkingTime, charge.TotalAmountToBePaid)
+	return nil
+}
+
+// history prints the history as CSV, a row per day.
+func (o *output) history(history []models.ParkingHistory) error {
+	if o.json {
+		return o.encode(history)
+	}
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"date", "cars_parked", "total_parking_time", "total_revenue_earned", "lost_ticket_revenue", "overstay_fine_revenue", "energy_revenue", "valet_revenue"})
+	for _, day := range history {
+		w.Write([]string{
+			day.Date.Format("2006-01-02"),
+			strconv.Itoa(day.CarsParked),
+			strconv.Itoa(day.TotalParkingTime),
+			strconv.FormatInt(day.TotalRevenueEarned, 10),
+			strconv.FormatInt(day.LostTicketRevenue, 10),
+			strconv.FormatInt(day.OverstayFineRevenue, 10),
+			strconv.FormatInt(day.EnergyRevenue, 10),
+			strconv.FormatInt(day.ValetRevenue, 10),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (o *output) message(message string) error {
+	if o.json {
+		return o.encode(map[string]string{"message": message})
+	}
+	fmt.Println(message)
+	return nil
+}
+
+func (o *output) encode(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func (o *output) migrations(statuses []migrations.Status) error {
+	if o.json {
+		return o.encode(statuses)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
@@ -1,16 +1,96 @@
 package config
 
-import "github.com/caarlos0/env/v6"
+import (
+	"fmt"
+	"github.com/caarlos0/env/v6"
+	"strings"
+)
 
 type Config struct {
-	ApplicationPort int    `env:"APPLICATION_PORT"`
-	DatabaseUrl     string `env:"DATABASE_URL"`
-	MockVendor      bool   `env:"MOCK_VENDOR"`
-	BaseUrl         string `env:"BASE_URL"`
+	ApplicationPort            int      `env:"APPLICATION_PORT"`
+	HttpReadTimeoutSeconds     int      `env:"HTTP_READ_TIMEOUT_SECONDS" envDefault:"15"`
+	HttpWriteTimeoutSeconds    int      `env:"HTTP_WRITE_TIMEOUT_SECONDS" envDefault:"30"`
+	HttpIdleTimeoutSeconds     int      `env:"HTTP_IDLE_TIMEOUT_SECONDS" envDefault:"120"`
+	ShutdownDelaySeconds       int      `env:"SHUTDOWN_DELAY_SECONDS" envDefault:"0"`    // Time readiness fails before draining starts
+	ShutdownTimeoutSeconds     int      `env:"SHUTDOWN_TIMEOUT_SECONDS" envDefault:"30"` // Time requests in flight get to finish
+	DatabaseUrl                string   `env:"DATABASE_URL"`
+	DatabaseConnectSeconds     int      `env:"DATABASE_CONNECT_SECONDS" envDefault:"60"` // Time to keep retrying the database at startup
+	MockVendor                 bool     `env:"MOCK_VENDOR"`
+	BaseUrl                    string   `env:"BASE_URL"`
+	TicketSigningKey           string   `env:"TICKET_SIGNING_KEY"`
+	GateControllerAddr         string   `env:"GATE_CONTROLLER_ADDR"`
+	GateTimeoutSeconds         int      `env:"GATE_TIMEOUT_SECONDS" envDefault:"5"`
+	AnprMinConfidence          float64  `env:"ANPR_MIN_CONFIDENCE" envDefault:"0.8"`
+	AnprDedupSeconds           int      `env:"ANPR_DEDUP_SECONDS" envDefault:"60"`
+	SensorReconcileSeconds     int      `env:"SENSOR_RECONCILE_SECONDS" envDefault:"60"`
+	SensorVacantMinutes        int      `env:"SENSOR_VACANT_MINUTES" envDefault:"30"`
+	SensorAwareAllocation      bool     `env:"SENSOR_AWARE_ALLOCATION"`
+	WebhookDispatchSeconds     int      `env:"WEBHOOK_DISPATCH_SECONDS" envDefault:"5"`
+	WebhookTimeoutSeconds      int      `env:"WEBHOOK_TIMEOUT_SECONDS" envDefault:"10"`
+	AlertSmtpAddr              string   `env:"ALERT_SMTP_ADDR"`
+	AlertSmtpFrom              string   `env:"ALERT_SMTP_FROM" envDefault:"pms@localhost"`
+	AlertSmtpTo                []string `env:"ALERT_SMTP_TO" envSeparator:","`
+	OverstayScanSeconds        int      `env:"OVERSTAY_SCAN_SECONDS" envDefault:"60"`
+	MaintenanceScheduleSeconds int      `env:"MAINTENANCE_SCHEDULE_SECONDS" envDefault:"60"`
+	MaintenanceLeadMinutes     int      `env:"MAINTENANCE_LEAD_MINUTES" envDefault:"120"`
+	SearchCacheSeconds         int      `env:"SEARCH_CACHE_SECONDS" envDefault:"15"`
+	OverflowRadiusMeters       float64  `env:"OVERFLOW_RADIUS_METERS" envDefault:"5000"`
+	OverflowHoldMinutes        int      `env:"OVERFLOW_HOLD_MINUTES" envDefault:"15"`
+	AutoMigrate                bool     `env:"AUTO_MIGRATE" envDefault:"true"`
+
+	// Edge sync
+	CentralUrl          string      `env:"CENTRAL_URL"`       // Set on an edge site, the central instance it syncs to
+	EdgeSiteID          string      `env:"EDGE_SITE_ID"`      // Name of the edge site at the central instance
+	SyncSecret          string      `env:"SYNC_SECRET"`       // Set on an edge site, the secret it signs its batches with
+	SyncSiteSecrets     SiteSecrets `env:"SYNC_SITE_SECRETS"` // Set on the central instance, the secret of every edge site
+	SyncIntervalSeconds int         `env:"SYNC_INTERVAL_SECONDS" envDefault:"10"`
+	SyncTimeoutSeconds  int         `env:"SYNC_TIMEOUT_SECONDS" envDefault:"10"`
 }
 
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
-	err := env.Parse(cfg)
-	return cfg, err
+	if err := env.Parse(cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// validate rejects the intervals of the background workers that are not
+// positive, which would make their tickers panic, and a webhook timeout
+// that would let a delivery hang past its lease.
+func (cfg *Config) validate() error {
+	durations := []struct {
+		name  string
+		value int
+	}{
+		{"SENSOR_RECONCILE_SECONDS", cfg.SensorReconcileSeconds},
+		{"WEBHOOK_DISPATCH_SECONDS", cfg.WebhookDispatchSeconds},
+		{"OVERSTAY_SCAN_SECONDS", cfg.OverstayScanSeconds},
+		{"MAINTENANCE_SCHEDULE_SECONDS", cfg.MaintenanceScheduleSeconds},
+		{"SYNC_INTERVAL_SECONDS", cfg.SyncIntervalSeconds},
+		{"WEBHOOK_TIMEOUT_SECONDS", cfg.WebhookTimeoutSeconds},
+	}
+	for _, duration := range durations {
+		if duration.value <= 0 {
+			return fmt.Errorf("%s must be greater than 0, got %d", duration.name, duration.value)
+		}
+	}
+	return nil
+}
+
+// SiteSecrets maps the ID of every edge site to the secret it signs its
+// batches with, given as comma separated site:secret pairs.
+type SiteSecrets map[string]string
+
+func (secrets *SiteSecrets) UnmarshalText(text []byte) error {
+	parsed := SiteSecrets{}
+	for _, pair := range strings.Split(string(text), ",") {
+		siteID, secret, _ := strings.Cut(strings.TrimSpace(pair), ":")
+		if siteID == "" || secret == "" {
+			return fmt.Errorf("want site:secret pairs, got %q", pair)
+		}
+		parsed[siteID] = secret
+	}
+	*secrets = parsed
+	return nil
 }
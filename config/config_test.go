@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestNewConfigIntervals(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "positive interval", env: map[string]string{"WEBHOOK_DISPATCH_SECONDS": "1"}},
+		{name: "zero interval", env: map[string]string{"SENSOR_RECONCILE_SECONDS": "0"}, wantErr: true},
+		{name: "negative interval", env: map[string]string{"SYNC_INTERVAL_SECONDS": "-5"}, wantErr: true},
+		{name: "zero scan interval", env: map[string]string{"OVERSTAY_SCAN_SECONDS": "0"}, wantErr: true},
+		{name: "site secrets", env: map[string]string{"SYNC_SITE_SECRETS": "north:s1,south:s2"}},
+		{name: "site without a secret", env: map[string]string{"SYNC_SITE_SECRETS": "north:s1,south:"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, err := NewConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
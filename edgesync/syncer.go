@@ -0,0 +1,266 @@
+// Package edgesync sends the visits and payments of an edge site to the
+// central instance. An edge site runs the service on its own store, so
+// entries and exits keep working while the link to the central instance is
+// down. Its outbox events queue up meanwhile and are sent in order once the
+// link is back.
+package edgesync
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/utils"
+	"parkingManagementSystem/webhooks"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize = 100
+	// maxBackoff caps the wait between attempts while the central instance
+	// cannot be reached.
+	maxBackoff = 5 * time.Minute
+	// EventsPath is where the central instance accepts the events of edge sites.
+	EventsPath = "/sync/events"
+	// MaxClockSkew is how far the timestamp of a batch may be from the clock
+	// of the central instance.
+	MaxClockSkew = 5 * time.Minute
+)
+
+// Every batch is signed with the secret of the site, which the central
+// instance looks up by the site header, the same way webhook deliveries are:
+//
+//	X-PMS-Signature: sha256=hex(HMAC-SHA256(secret, X-PMS-Timestamp + "." + body))
+const (
+	SiteHeader      = "X-PMS-Site"
+	TimestampHeader = "X-PMS-Timestamp"
+	SignatureHeader = "X-PMS-Signature"
+)
+
+// Status is how far an edge site got with sending its events.
+type Status struct {
+	SiteID        string     `json:"site_id"`
+	CentralURL    string     `json:"central_url"`
+	Pending       int64      `json:"pending"` // Events waiting to be sent
+	LastSyncAt    *time.Time `json:"last_sync_at,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Failures      int        `json:"failures"` // Failed attempts since the last sync
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// Syncer periodically sends the pending events of the edge site to the
+// central instance. While it cannot be reached, attempts back off
+// exponentially from the interval up to maxBackoff.
+type Syncer struct {
+	repo       *repository.Repository
+	client     *http.Client
+	centralUrl string
+	siteID     string
+	secret     string
+	interval   time.Duration
+
+	mu     sync.Mutex
+	status Status
+}
+
+func NewSyncer(repo *repository.Repository, centralUrl, siteID, secret string, interval, timeout time.Duration) *Syncer {
+	centralUrl = strings.TrimSuffix(centralUrl, "/")
+	return &Syncer{
+		repo:       repo,
+		client:     &http.Client{Timeout: timeout},
+		centralUrl: centralUrl,
+		siteID:     siteID,
+		secret:     secret,
+		interval:   interval,
+		status:     Status{SiteID: siteID, CentralURL: centralUrl},
+	}
+}
+
+// Run syncs every interval until the context is cancelled.
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.due() {
+				continue
+			}
+			if _, err := s.Sync(ctx); err != nil {
+				log.Warn().Err(err).Str("site_id", s.siteID).Msg("edge sync failed")
+			}
+		}
+	}
+}
+
+// Sync sends every pending event, in batches, and returns the number of
+// events the central instance applied.
+func (s *Syncer) Sync(ctx context.Context) (int, error) {
+	synced := 0
+	for {
+		outboxEvents, err := s.repo.GetUnsyncedEvents(batchSize)
+		if err != nil {
+			return synced, err
+		}
+		if len(outboxEvents) == 0 {
+			s.succeeded()
+			return synced, nil
+		}
+
+		result, err := s.send(ctx, outboxEvents)
+		if result != nil {
+			if err := s.repo.MarkEventsSynced(result.Applied); err != nil {
+				return synced, err
+			}
+			synced += len(result.Applied)
+			for _, conflict := range result.Conflicts {
+				log.Warn().
+					Uint("event_id", conflict.EventID).
+					Uint("relative_id", conflict.RelativeID).
+					Str("kind", conflict.Kind).
+					Str("resolution", conflict.Resolution).
+					Msg("edge sync conflict")
+			}
+		}
+		if err == nil && result.Error != "" {
+			err = fmt.Errorf("central instance stopped at an event: %s", result.Error)
+		}
+		if err != nil {
+			s.failed(err)
+			return synced, err
+		}
+		if len(outboxEvents) < batchSize {
+			s.succeeded()
+			return synced, nil
+		}
+	}
+}
+
+// Status returns how far the site got with sending its events.
+func (s *Syncer) Status() (Status, error) {
+	pending, err := s.repo.CountUnsyncedEvents()
+	if err != nil {
+		return Status{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Pending = pending
+	return status, nil
+}
+
+func (s *Syncer) send(ctx context.Context, outboxEvents []models.OutboxEvent) (*models.SyncResult, error) {
+	batch := models.SyncBatch{SiteID: s.siteID, Events: make([]models.SyncEvent, 0, len(outboxEvents))}
+	for _, outboxEvent := range outboxEvents {
+		batch.Events = append(batch.Events, models.SyncEvent{
+			ID:        outboxEvent.ID,
+			EventType: outboxEvent.EventType,
+			CreatedAt: outboxEvent.CreatedAt,
+			Data:      json.RawMessage(outboxEvent.Payload),
+		})
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.centralUrl+EventsPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SiteHeader, s.siteID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+webhooks.Sign(s.secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("central instance responded with %s", resp.Status)
+	}
+
+	var response struct {
+		utils.CommonResponse
+		Data models.SyncResult `json:"data"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// due reports whether the backoff after failed attempts is over.
+func (s *Syncer) due() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status.NextAttemptAt == nil || !time.Now().Before(*s.status.NextAttemptAt)
+}
+
+func (s *Syncer) succeeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.status.LastSyncAt = &now
+	s.status.LastAttemptAt = &now
+	s.status.LastError = ""
+	s.status.Failures = 0
+	s.status.NextAttemptAt = nil
+}
+
+func (s *Syncer) failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.status.LastAttemptAt = &now
+	s.status.LastError = err.Error()
+	s.status.Failures++
+	next := now.Add(s.backoff(s.status.Failures))
+	s.status.NextAttemptAt = &next
+}
+
+func (s *Syncer) backoff(failures int) time.Duration {
+	delay := s.interval
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// Verify reports whether a batch was signed with the secret of the site at
+// most MaxClockSkew away from now, so a captured batch cannot be replayed
+// later.
+func Verify(secret, timestamp, signature string, body []byte, now time.Time) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return false
+	}
+	expected := "sha256=" + webhooks.Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,64 @@
+package edgesync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const (
+		secret    = "edge-secret"
+		timestamp = "1710000000"
+		// hex(HMAC-SHA256("edge-secret", "1710000000." + body))
+		signature = "sha256=498650838c18be6a5b247e1b1ecd9fdc52b97b60b1ab916d6be4eeaa03e4322e"
+	)
+	body := []byte(`{"events":[]}`)
+	signedAt := time.Unix(1710000000, 0)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		want      bool
+	}{
+		{name: "valid", secret: secret, timestamp: timestamp, signature: signature, body: body, now: signedAt, want: true},
+		{name: "other secret", secret: "other-secret", timestamp: timestamp, signature: signature, body: body, now: signedAt, want: false},
+		{name: "other timestamp", secret: secret, timestamp: "1710000001", signature: signature, body: body, now: signedAt, want: false},
+		{name: "altered body", secret: secret, timestamp: timestamp, signature: signature, body: []byte(`{"events":[{}]}`), now: signedAt, want: false},
+		{name: "missing scheme", secret: secret, timestamp: timestamp, signature: signature[len("sha256="):], body: body, now: signedAt, want: false},
+		{name: "upper case hex", secret: secret, timestamp: timestamp, signature: "sha256=498650838C18BE6A5B247E1B1ECD9FDC52B97B60B1AB916D6BE4EEAA03E4322E", body: body, now: signedAt, want: false},
+		{name: "sent a minute ago", secret: secret, timestamp: timestamp, signature: signature, body: body, now: signedAt.Add(time.Minute), want: true},
+		{name: "replayed later", secret: secret, timestamp: timestamp, signature: signature, body: body, now: signedAt.Add(MaxClockSkew + time.Second), want: false},
+		{name: "from the future", secret: secret, timestamp: timestamp, signature: signature, body: body, now: signedAt.Add(-MaxClockSkew - time.Second), want: false},
+		{name: "timestamp not a number", secret: secret, timestamp: "yesterday", signature: signature, body: body, now: signedAt, want: false},
+		{name: "empty signature", secret: secret, timestamp: timestamp, signature: "", body: body, now: signedAt, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, tt.now); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s := &Syncer{interval: maxBackoff / 16}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: s.interval},
+		{failures: 2, want: 2 * s.interval},
+		{failures: 5, want: maxBackoff},
+		{failures: 30, want: maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package enforcement
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"time"
+)
+
+// Scanner periodically looks for vehicles that stay longer than the maximum
+// stay of their lot and raises or escalates their overstay violations.
+type Scanner struct {
+	repo     *repository.Repository
+	interval time.Duration
+}
+
+func NewScanner(repo *repository.Repository, interval time.Duration) *Scanner {
+	return &Scanner{repo: repo, interval: interval}
+}
+
+// Run scans every interval until the context is cancelled.
+func (s *Scanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Scan(); err != nil {
+				log.Error().Err(err).Msg("overstay scan failed")
+			}
+		}
+	}
+}
+
+// Scan runs a single pass and returns the violations it raised or escalated.
+func (s *Scanner) Scan() ([]models.OverstayViolation, error) {
+	violations, err := s.repo.ScanOverstays(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, violation := range violations {
+		log.Warn().
+			Uint("parking_lot_id", violation.ParkingLotID).
+			Uint("relative_id", violation.RelativeID).
+			Int("level", violation.Level).
+			Int("fine_amount", violation.FineAmount).
+			Msg("overstay violation")
+	}
+	return violations, nil
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"parkingManagementSystem/models"
+	"sync"
+)
+
+const (
+	ReasonPark        = "park"
+	ReasonUnpark      = "unpark"
+	ReasonMaintenance = "maintenance"
+	ReasonReconfigure = "reconfigure"
+	ReasonRelocate    = "relocate"
+)
+
+// SlotChange is published whenever a parking slot changes state.
+type SlotChange struct {
+	ParkingLotID uint               `json:"parking_lot_id"`
+	Reason       string             `json:"reason"`
+	Slot         models.ParkingSlot `json:"slot"`
+}
+
+// Bus fans slot changes out to the subscribers of each parking lot. Publish
+// never blocks: a subscriber whose buffer is full is dropped and its channel
+// closed, so it has to resubscribe and start again from a fresh snapshot.
+// A nil Bus discards everything published to it.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[*Subscription]struct{}
+	all         map[*Subscription]struct{}
+}
+
+// Subscription receives the slot changes of one parking lot, or of every
+// lot, on C.
+type Subscription struct {
+	C            <-chan SlotChange
+	ch           chan SlotChange
+	parkingLotID uint
+	all          bool
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[uint]map[*Subscription]struct{}{},
+		all:         map[*Subscription]struct{}{},
+	}
+}
+
+// Subscribe starts delivering the changes of the parking lot, buffering up
+// to buffer changes for a slow reader.
+func (b *Bus) Subscribe(parkingLotID uint, buffer int) *Subscription {
+	ch := make(chan SlotChange, buffer)
+	sub := &Subscription{C: ch, ch: ch, parkingLotID: parkingLotID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[parkingLotID] == nil {
+		b.subscribers[parkingLotID] = map[*Subscription]struct{}{}
+	}
+	b.subscribers[parkingLotID][sub] = struct{}{}
+	return sub
+}
+
+// SubscribeAll starts delivering the changes of every parking lot.
+func (b *Bus) SubscribeAll(buffer int) *Subscription {
+	ch := make(chan SlotChange, buffer)
+	sub := &Subscription{C: ch, ch: ch, all: true}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops the subscription. It is safe to call more than once.
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+func (b *Bus) Publish(change SlotChange) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers[change.ParkingLotID] {
+		b.send(sub, change)
+	}
+	for sub := range b.all {
+		b.send(sub, change)
+	}
+}
+
+func (b *Bus) send(sub *Subscription, change SlotChange) {
+	select {
+	case sub.ch <- change:
+	default:
+		b.remove(sub)
+	}
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	if sub.all {
+		if _, ok := b.all[sub]; ok {
+			delete(b.all, sub)
+			close(sub.ch)
+		}
+		return
+	}
+	subs := b.subscribers[sub.parkingLotID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.parkingLotID)
+	}
+	close(sub.ch)
+}
@@ -0,0 +1,25 @@
+package gates
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	StatusOpen   = "OPEN"
+	StatusClosed = "CLOSED"
+	StatusFault  = "FAULT"
+)
+
+var ErrGateTimeout = errors.New("gate did not respond in time")
+
+// GateController drives the barrier gates at the entries and exits of the parking lots.
+type GateController interface {
+	// Open raises the barrier of the gate. It returns ErrGateTimeout when
+	// the gate does not confirm before the context expires.
+	Open(ctx context.Context, gateID string) error
+	// Close lowers the barrier of the gate.
+	Close(ctx context.Context, gateID string) error
+	// Status reports whether the gate is OPEN, CLOSED or in FAULT.
+	Status(ctx context.Context, gateID string) (string, error)
+}
@@ -0,0 +1,120 @@
+package gates
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var ErrNoGateController = errors.New("no gate controller configured")
+
+// EventRecorder persists gate decisions.
+type EventRecorder interface {
+	CreateGateEvent(event *models.GateEvent) error
+}
+
+// Manager turns authorization decisions into gate commands and keeps a log
+// of every decision. Callers must only ask it to open a gate once the park
+// or pay step has been committed.
+type Manager struct {
+	controller GateController // Nil when the lots have no gates
+	recorder   EventRecorder
+	timeout    time.Duration
+}
+
+func NewManager(controller GateController, recorder EventRecorder, timeout time.Duration) *Manager {
+	return &Manager{controller: controller, recorder: recorder, timeout: timeout}
+}
+
+// Allow opens the gate for an authorized vehicle. The returned event tells
+// whether the gate confirmed, timed out or failed, or was skipped because no
+// controller is configured; a failure does not undo the committed park or
+// pay step, an attendant has to override the gate.
+// The command is not tied to the request, since the vehicle is entitled to
+// pass even if the client that authorized it went away.
+func (m *Manager) Allow(event models.GateEvent) *models.GateEvent {
+	event.Decision = models.GateDecisionOpen
+	if err := m.command(context.Background(), event.GateID, true); err != nil {
+		event.Decision = failureDecision(err)
+		event.Reason = err.Error()
+	}
+	return m.record(event)
+}
+
+// Deny keeps the gate closed and records why the vehicle was refused.
+func (m *Manager) Deny(event models.GateEvent, reason string) *models.GateEvent {
+	event.Decision = models.GateDecisionDenied
+	event.Reason = reason
+	return m.record(event)
+}
+
+// Override opens or closes a gate on behalf of an operator, regardless of
+// any parking session.
+func (m *Manager) Override(ctx context.Context, gateID string, open bool, operator, reason string) (*models.GateEvent, error) {
+	if m.controller == nil {
+		return nil, ErrNoGateController
+	}
+	err := m.command(ctx, gateID, open)
+
+	event := models.GateEvent{
+		GateID:   gateID,
+		Decision: models.GateDecisionOverrideClose,
+		Reason:   reason,
+		Operator: operator,
+	}
+	if open {
+		event.Decision = models.GateDecisionOverrideOpen
+	}
+	if err != nil {
+		event.Decision = failureDecision(err)
+		event.Reason = err.Error()
+	}
+	return m.record(event), err
+}
+
+// Status asks the controller for the current state of the gate.
+func (m *Manager) Status(ctx context.Context, gateID string) (string, error) {
+	if m.controller == nil {
+		return "", ErrNoGateController
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.controller.Status(ctx, gateID)
+}
+
+func (m *Manager) command(ctx context.Context, gateID string, open bool) error {
+	if m.controller == nil {
+		return ErrNoGateController
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	if open {
+		return m.controller.Open(ctx, gateID)
+	}
+	return m.controller.Close(ctx, gateID)
+}
+
+func (m *Manager) record(event models.GateEvent) *models.GateEvent {
+	log.Info().
+		Str("gate_id", event.GateID).
+		Str("direction", event.Direction).
+		Str("decision", event.Decision).
+		Str("reason", event.Reason).
+		Msg("gate decision")
+	if err := m.recorder.CreateGateEvent(&event); err != nil {
+		log.Error().Err(err).Str("gate_id", event.GateID).Msg("failed to record gate decision")
+	}
+	return &event
+}
+
+func failureDecision(err error) string {
+	if errors.Is(err, ErrNoGateController) {
+		return models.GateDecisionSkipped
+	}
+	if errors.Is(err, ErrGateTimeout) {
+		return models.GateDecisionTimeout
+	}
+	return models.GateDecisionFault
+}
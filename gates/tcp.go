@@ -0,0 +1,107 @@
+package gates
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TCPController talks to gate hardware over a line-based protocol on a TCP
+// socket, which also covers serial devices behind a serial-over-TCP bridge.
+//
+// Every command is a single line and is answered by a single line:
+//
+//	OPEN <gate_id>    ->  OK | ERR <message>
+//	CLOSE <gate_id>   ->  OK | ERR <message>
+//	STATUS <gate_id>  ->  STATUS <gate_id> OPEN|CLOSED|FAULT | ERR <message>
+//
+// Lines end with "\n"; a trailing "\r" is ignored.
+type TCPController struct {
+	Addr string
+	// Timeout applies to a whole command when the context has no deadline.
+	Timeout time.Duration
+}
+
+func NewTCPController(addr string, timeout time.Duration) *TCPController {
+	return &TCPController{Addr: addr, Timeout: timeout}
+}
+
+func (c *TCPController) Open(ctx context.Context, gateID string) error {
+	return c.expectOK(ctx, "OPEN", gateID)
+}
+
+func (c *TCPController) Close(ctx context.Context, gateID string) error {
+	return c.expectOK(ctx, "CLOSE", gateID)
+}
+
+func (c *TCPController) Status(ctx context.Context, gateID string) (string, error) {
+	reply, err := c.send(ctx, "STATUS", gateID)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(reply)
+	if len(fields) != 3 || fields[0] != "STATUS" || fields[1] != gateID {
+		return "", fmt.Errorf("unexpected gate reply %q", reply)
+	}
+	return fields[2], nil
+}
+
+func (c *TCPController) expectOK(ctx context.Context, command, gateID string) error {
+	reply, err := c.send(ctx, command, gateID)
+	if err != nil {
+		return err
+	}
+	if reply != "OK" {
+		return fmt.Errorf("unexpected gate reply %q", reply)
+	}
+	return nil
+}
+
+// send writes one command and reads its reply on a fresh connection, so a
+// controller that restarted never leaves a stale socket behind.
+func (c *TCPController) send(ctx context.Context, command, gateID string) (string, error) {
+	if strings.ContainsAny(gateID, " \r\n") || gateID == "" {
+		return "", fmt.Errorf("invalid gate id %q", gateID)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return "", wrapTimeout(err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s %s\n", command, gateID); err != nil {
+		return "", wrapTimeout(err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", wrapTimeout(err)
+	}
+	reply = strings.TrimRight(reply, "\r\n")
+	if message, found := strings.CutPrefix(reply, "ERR "); found {
+		return "", fmt.Errorf("gate %s: %s", gateID, message)
+	}
+	return reply, nil
+}
+
+func wrapTimeout(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrGateTimeout, err)
+	}
+	return err
+}
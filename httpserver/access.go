@@ -0,0 +1,204 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+const (
+	// entryBlockedCode is the response code of an entry refused by the blocklist.
+	entryBlockedCode = "entry_blocked"
+	// lotClosedCode is the response code of an entry outside the opening hours.
+	lotClosedCode = "lot_closed"
+	// valetOnlyCode is the response code of a driver parking themselves in a valet lot.
+	valetOnlyCode = "valet_only"
+	// edgeOperatedCode is the response code of an entry recorded centrally into a lot of an edge site.
+	edgeOperatedCode = "edge_operated"
+)
+
+// blockedEntriesLimit is the number of refused entries returned from the audit trail.
+const blockedEntriesLimit = 100
+
+// respondIfEntryRefused responds to an entry refused by the blocklist, because
+// the lot is closed, because only valet staff park there or because an edge
+// site operates the lot, and reports whether it did.
+func respondIfEntryRefused(w http.ResponseWriter, err error, logger zerolog.Logger) bool {
+	var blocked *repository.EntryBlockedError
+	if errors.As(err, &blocked) {
+		logger.Warn().Uint("access_list_entry_id", blocked.Entry.ID).Msg("Refused blocklisted entry")
+		utils.RespondWithErrorCode(w, entryBlockedCode, "Entry is blocked: "+blocked.Entry.Reason, http.StatusForbidden, logger)
+		return true
+	}
+	if errors.Is(err, repository.ErrLotClosed) {
+		utils.RespondWithErrorCode(w, lotClosedCode, "Parking lot is closed", http.StatusConflict, logger)
+		return true
+	}
+	if errors.Is(err, repository.ErrValetOnly) {
+		utils.RespondWithErrorCode(w, valetOnlyCode, "Parking lot only accepts valet parking", http.StatusConflict, logger)
+		return true
+	}
+	if errors.Is(err, repository.ErrEdgeOperated) {
+		utils.RespondWithErrorCode(w, edgeOperatedCode, "Parking lot is operated by an edge site, vehicles enter there", http.StatusConflict, logger)
+		return true
+	}
+	return false
+}
+
+func handleCreateAccessListEntry(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCreateAccessListEntry").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var entry models.AccessListEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		if entry.ListType != models.AccessListBlock && entry.ListType != models.AccessListAllow {
+			utils.RespondWithError(w, "List type must be block or allow", http.StatusBadRequest, logger)
+			return
+		}
+		if repository.NormalizePlate(entry.Plate) == "" && entry.UserID == nil {
+			utils.RespondWithError(w, "Plate or user ID is required", http.StatusBadRequest, logger)
+			return
+		}
+		if entry.Reason == "" {
+			utils.RespondWithError(w, "Reason is required", http.StatusBadRequest, logger)
+			return
+		}
+		entry.ID = 0
+
+		if err := s.Repository.CreateAccessListEntry(&entry); err != nil {
+			logger.Error().Err(err).Msg("Failed to create access list entry")
+			utils.RespondWithError(w, "Failed to create access list entry", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new entry
+		logger.Info().Uint("access_list_entry_id", entry.ID).Str("list_type", entry.ListType).Msg("Access list entry created successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Access list entry created successfully",
+			Data:    entry,
+		}, logger)
+	}
+}
+
+func handleGetAccessListEntries(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetAccessListEntries").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		listType := r.URL.Query().Get("list_type")
+		if listType != models.AccessListBlock && listType != models.AccessListAllow {
+			utils.RespondWithError(w, "List type must be block or allow", http.StatusBadRequest, logger)
+			return
+		}
+		var parkingLotID uint64
+		if value := r.URL.Query().Get("parking_lot_id"); value != "" {
+			var err error
+			parkingLotID, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		entries, err := s.Repository.GetAccessListEntries(listType, uint(parkingLotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch access list entries")
+			utils.RespondWithError(w, "Failed to fetch access list entries", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Access list entries fetched successfully",
+			Data:    entries,
+		}, logger)
+	}
+}
+
+func handleDeleteAccessListEntry(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleDeleteAccessListEntry").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		entryID, err := strconv.ParseUint(r.URL.Query().Get("entry_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid entry ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		err = s.Repository.DeleteAccessListEntry(uint(entryID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Access list entry not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to delete access list entry")
+			utils.RespondWithError(w, "Failed to delete access list entry", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Access list entry deleted successfully",
+			Data:    nil,
+		}, logger)
+	}
+}
+
+func handleGetBlockedEntries(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetBlockedEntries").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		blockedEntries, err := s.Repository.GetBlockedEntries(uint(parkingLotID), blockedEntriesLimit)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch blocked entries")
+			utils.RespondWithError(w, "Failed to fetch blocked entries", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Blocked entries fetched successfully",
+			Data:    blockedEntries,
+		}, logger)
+	}
+}
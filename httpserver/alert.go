@@ -0,0 +1,164 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+// capacityAlertsLimit is the number of alerts returned from the history.
+const capacityAlertsLimit = 100
+
+func handleCreateCapacityThreshold(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCreateCapacityThreshold").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var threshold models.CapacityThreshold
+		if err := json.NewDecoder(r.Body).Decode(&threshold); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		if threshold.Percent < 1 || threshold.Percent > 100 {
+			utils.RespondWithError(w, "Percent must be between 1 and 100", http.StatusBadRequest, logger)
+			return
+		}
+		if threshold.SlotType != "" && !isSlotType(threshold.SlotType) {
+			utils.RespondWithError(w, "Unknown slot type", http.StatusBadRequest, logger)
+			return
+		}
+		if threshold.HysteresisPercent < 0 || threshold.HysteresisPercent >= threshold.Percent {
+			utils.RespondWithError(w, "Hysteresis percent must be between 0 and percent", http.StatusBadRequest, logger)
+			return
+		}
+		if threshold.HysteresisPercent == 0 {
+			threshold.HysteresisPercent = models.DefaultHysteresisPercent
+		}
+		threshold.ID = 0
+		threshold.IsTriggered = false
+
+		if err := s.Repository.CreateCapacityThreshold(&threshold); err != nil {
+			logger.Error().Err(err).Msg("Failed to create capacity threshold")
+			utils.RespondWithError(w, "Failed to create capacity threshold", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// A lot that is already above the new threshold alerts right away
+		s.Alerts.Evaluate(threshold.ParkingLotID)
+
+		// Log the new threshold
+		logger.Info().Uint("parking_lot_id", threshold.ParkingLotID).Int("percent", threshold.Percent).Msg("Capacity threshold created successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Capacity threshold created successfully",
+			Data:    threshold,
+		}, logger)
+	}
+}
+
+func handleGetCapacityThresholds(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetCapacityThresholds").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		thresholds, err := s.Repository.GetCapacityThresholds(uint(parkingLotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch capacity thresholds")
+			utils.RespondWithError(w, "Failed to fetch capacity thresholds", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Capacity thresholds fetched successfully",
+			Data:    thresholds,
+		}, logger)
+	}
+}
+
+func handleDeleteCapacityThreshold(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleDeleteCapacityThreshold").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		thresholdID, err := strconv.ParseUint(r.URL.Query().Get("threshold_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid threshold ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		err = s.Repository.DeleteCapacityThreshold(uint(thresholdID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Capacity threshold not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to delete capacity threshold")
+			utils.RespondWithError(w, "Failed to delete capacity threshold", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Capacity threshold deleted successfully",
+			Data:    nil,
+		}, logger)
+	}
+}
+
+func handleGetCapacityAlerts(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetCapacityAlerts").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		alerts, err := s.Repository.GetCapacityAlerts(uint(parkingLotID), capacityAlertsLimit)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch capacity alerts")
+			utils.RespondWithError(w, "Failed to fetch capacity alerts", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Capacity alerts fetched successfully",
+			Data:    alerts,
+		}, logger)
+	}
+}
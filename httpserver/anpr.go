@@ -0,0 +1,169 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/anpr"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+func handleCreateCameraLane(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCreateCameraLane").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var lane models.CameraLane
+		if err := json.NewDecoder(r.Body).Decode(&lane); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		if lane.LaneID == "" {
+			utils.RespondWithError(w, "Lane ID is required", http.StatusBadRequest, logger)
+			return
+		}
+		if lane.Direction != models.GateDirectionEntry && lane.Direction != models.GateDirectionExit {
+			utils.RespondWithError(w, "Direction must be entry or exit", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Create or replace the lane
+		if err := s.Repository.SaveCameraLane(&lane); err != nil {
+			logger.Error().Err(err).Msg("Failed to save camera lane")
+			utils.RespondWithError(w, "Failed to save camera lane", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Camera lane saved successfully",
+			Data:    lane,
+		}, logger)
+	}
+}
+
+func handleIngestAnprEvent(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleIngestAnprEvent").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody anpr.CameraRead
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Turn the read into a park or unpark
+		event := reqBody.Event()
+		err := s.Anpr.Process(&event)
+		if errors.Is(err, anpr.ErrUnknownLane) || errors.Is(err, anpr.ErrMissingPlate) {
+			utils.RespondWithError(w, err.Error(), http.StatusBadRequest, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to process camera event")
+			utils.RespondWithError(w, "Failed to process camera event", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the outcome of the read
+		logger.Info().Str("plate", event.Plate).Str("lane", event.LaneID).Str("status", event.Status).Msg("Camera event processed")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Camera event processed successfully",
+			Data:    event,
+		}, logger)
+	}
+}
+
+func handleGetAnprEvents(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetAnprEvents").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters, the review queue is shown by default
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = models.AnprStatusReview
+		}
+
+		events, err := s.Repository.GetAnprEvents(status)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch camera events")
+			utils.RespondWithError(w, "Failed to fetch camera events", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Camera events fetched successfully",
+			Data:    events,
+		}, logger)
+	}
+}
+
+func handleResolveAnprReview(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleResolveAnprReview").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		eventID, err := strconv.ParseUint(r.URL.Query().Get("event_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid event ID", http.StatusBadRequest, logger)
+			return
+		}
+		attendant := r.URL.Query().Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Confirm or correct the plate, or reject the read when no plate is given
+		event, err := s.Anpr.ResolveReview(uint(eventID), r.URL.Query().Get("plate"), attendant)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Camera event not found", http.StatusNotFound, logger)
+			return
+		}
+		if errors.Is(err, anpr.ErrNotInReview) {
+			utils.RespondWithError(w, "Camera event is not waiting for review", http.StatusBadRequest, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to resolve camera event")
+			utils.RespondWithError(w, "Failed to resolve camera event", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the review decision
+		logger.Info().Uint64("event_id", eventID).Str("attendant", attendant).Str("status", event.Status).Msg("Camera event reviewed")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Camera event reviewed successfully",
+			Data:    event,
+		}, logger)
+	}
+}
@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+)
+
+// BulkSlotReqBody applies one action to the slots of a lot picked by the selector.
+type BulkSlotReqBody struct {
+	repository.SlotSelector
+	ParkingLotID uint   `json:"parking_lot_id"`
+	Action       string `json:"action"`
+	Value        string `json:"value"` // Slot type, zone or label of the set actions
+	DryRun       bool   `json:"dry_run"`
+}
+
+// bulkSlotResponse is the per-slot report of a bulk operation.
+type bulkSlotResponse struct {
+	DryRun  bool                    `json:"dry_run"`
+	Applied bool                    `json:"applied"`
+	Results []models.BulkSlotResult `json:"results"`
+}
+
+func handleBulkUpdateParkingSlots(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleBulkUpdateParkingSlots").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody BulkSlotReqBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		selector := reqBody.SlotSelector
+		if len(selector.RelativeIDs) == 0 && selector.RangeFrom == 0 && selector.RangeTo == 0 && selector.Zone == "" && selector.Label == "" {
+			utils.RespondWithError(w, "Relative IDs, a range, a zone or a label is required", http.StatusBadRequest, logger)
+			return
+		}
+		if selector.RangeFrom > selector.RangeTo {
+			utils.RespondWithError(w, "Range start must not be after range end", http.StatusBadRequest, logger)
+			return
+		}
+
+		switch reqBody.Action {
+		case models.BulkActionMaintenance, models.BulkActionOutOfMaintenance, models.BulkActionSetZone, models.BulkActionSetLabel:
+		case models.BulkActionSetType:
+			if !isSlotType(reqBody.Value) {
+				utils.RespondWithError(w, "Value must be a slot type", http.StatusBadRequest, logger)
+				return
+			}
+		default:
+			utils.RespondWithError(w, "Unknown bulk action", http.StatusBadRequest, logger)
+			return
+		}
+
+		results, err := s.Repository.BulkUpdateParkingSlots(reqBody.ParkingLotID, selector, reqBody.Action, reqBody.Value, reqBody.DryRun)
+		if errors.Is(err, repository.ErrNoSlotsSelected) {
+			utils.RespondWithError(w, "No parking slots match the selection", http.StatusNotFound, logger)
+			return
+		}
+		if errors.Is(err, repository.ErrBulkOperationFailed) {
+			utils.RespondWithJSON(w, http.StatusConflict, utils.CommonResponse{
+				Code:    "error",
+				Message: "Bulk operation failed for some parking slots, nothing was changed",
+				Data:    bulkSlotResponse{DryRun: reqBody.DryRun, Results: results},
+			}, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to apply bulk operation")
+			utils.RespondWithError(w, "Failed to apply bulk operation", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the bulk operation
+		logger.Info().Uint("parking_lot_id", reqBody.ParkingLotID).Str("action", reqBody.Action).Int("slots", len(results)).Bool("dry_run", reqBody.DryRun).Msg("Bulk operation applied successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Bulk operation applied successfully",
+			Data:    bulkSlotResponse{DryRun: reqBody.DryRun, Applied: !reqBody.DryRun, Results: results},
+		}, logger)
+	}
+}
+
+func isSlotType(slotType string) bool {
+	switch slotType {
+	case models.SlotTypeStandard, models.SlotTypeCompact, models.SlotTypeAccessible, models.SlotTypeMotorcycle:
+		return true
+	}
+	return false
+}
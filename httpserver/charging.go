@@ -0,0 +1,206 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+func handleIngestChargerTelemetry(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleIngestChargerTelemetry").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody models.ChargerTelemetry
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+		if reqBody.Timestamp.IsZero() {
+			reqBody.Timestamp = time.Now()
+		}
+
+		// Apply the message to the charging session of the slot
+		session, err := s.Repository.RecordChargerTelemetry(reqBody)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondWithError(w, "Parking slot not found", http.StatusNotFound, logger)
+			return
+		case errors.Is(err, repository.ErrUnknownChargerAction):
+			utils.RespondWithError(w, err.Error(), http.StatusBadRequest, logger)
+			return
+		case errors.Is(err, repository.ErrNoCharger),
+			errors.Is(err, repository.ErrSlotNotOccupied),
+			errors.Is(err, repository.ErrNoChargingSession):
+			utils.RespondWithError(w, err.Error(), http.StatusConflict, logger)
+			return
+		case err != nil:
+			logger.Error().Err(err).Msg("Failed to record charger telemetry")
+			utils.RespondWithError(w, "Failed to record charger telemetry", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Charger telemetry recorded successfully",
+			Data:    session,
+		}, logger)
+	}
+}
+
+func handleGetChargingSessions(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetChargingSessions").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		parkingLotID, err := strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		sessions, err := s.Repository.GetChargingSessions(uint(parkingLotID), query.Get("status"))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch charging sessions")
+			utils.RespondWithError(w, "Failed to fetch charging sessions", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Charging sessions fetched successfully",
+			Data:    sessions,
+		}, logger)
+	}
+}
+
+func handleSetParkingSlotCharger(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleSetParkingSlotCharger").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters, an empty connector removes the charger
+		query := r.URL.Query()
+		parkingSlotID, err := strconv.ParseUint(query.Get("parking_slot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking slot ID", http.StatusBadRequest, logger)
+			return
+		}
+		connector := query.Get("connector")
+		var powerKW float64
+		switch connector {
+		case "":
+		case models.ConnectorType2, models.ConnectorCCS, models.ConnectorCHAdeMO:
+			powerKW, err = strconv.ParseFloat(query.Get("power_kw"), 64)
+			if err != nil || powerKW <= 0 {
+				utils.RespondWithError(w, "Invalid charger power", http.StatusBadRequest, logger)
+				return
+			}
+		default:
+			utils.RespondWithError(w, "Invalid charger connector", http.StatusBadRequest, logger)
+			return
+		}
+
+		parkingSlot, err := s.Repository.SetParkingSlotCharger(uint(parkingSlotID), connector, powerKW)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking slot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to set parking slot charger")
+			utils.RespondWithError(w, "Failed to set parking slot charger", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new charger
+		logger.Info().Uint("parking_slot_id", parkingSlot.ID).Str("connector", parkingSlot.ChargerConnector).Msg("Parking slot charger set successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parking slot charger set successfully",
+			Data:    parkingSlot,
+		}, logger)
+	}
+}
+
+func handleUpdateChargingTariff(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleUpdateChargingTariff").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		parkingLotID, err := strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		energyRate, err := strconv.ParseFloat(query.Get("energy_rate_per_kwh"), 64)
+		if err != nil || energyRate < 0 {
+			utils.RespondWithError(w, "Invalid energy rate", http.StatusBadRequest, logger)
+			return
+		}
+		idleFee, err := strconv.Atoi(query.Get("idle_fee_per_hour"))
+		if err != nil || idleFee < 0 {
+			utils.RespondWithError(w, "Invalid idle fee", http.StatusBadRequest, logger)
+			return
+		}
+		idleGraceMinutes, err := strconv.Atoi(query.Get("idle_grace_minutes"))
+		if err != nil || idleGraceMinutes < 0 {
+			utils.RespondWithError(w, "Invalid idle grace minutes", http.StatusBadRequest, logger)
+			return
+		}
+		billing := query.Get("charging_billing")
+		if billing == "" {
+			billing = models.ChargingBillingEnergyAndTime
+		}
+
+		parkingLot, err := s.Repository.UpdateChargingTariff(uint(parkingLotID), energyRate, idleFee, idleGraceMinutes, billing)
+		if errors.Is(err, repository.ErrUnknownChargingBilling) {
+			utils.RespondWithError(w, err.Error(), http.StatusBadRequest, logger)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to update charging tariff")
+			utils.RespondWithError(w, "Failed to update charging tariff", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new tariff
+		logger.Info().Uint("parking_lot_id", parkingLot.ID).Str("charging_billing", parkingLot.ChargingBilling).Msg("Charging tariff updated successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Charging tariff updated successfully",
+			Data:    parkingLot,
+		}, logger)
+	}
+}
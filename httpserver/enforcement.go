@@ -0,0 +1,119 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+func handleGetEnforcementQueue(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetEnforcementQueue").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		violations, err := s.Repository.GetOverstayQueue(uint(parkingLotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch enforcement queue")
+			utils.RespondWithError(w, "Failed to fetch enforcement queue", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Enforcement queue fetched successfully",
+			Data:    violations,
+		}, logger)
+	}
+}
+
+func handleScanOverstays(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleScanOverstays").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Run a scan now instead of waiting for the next one
+		violations, err := s.Overstays.Scan()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to scan for overstays")
+			utils.RespondWithError(w, "Failed to scan for overstays", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Overstay scan completed successfully",
+			Data:    violations,
+		}, logger)
+	}
+}
+
+func handleUpdateOverstayPolicy(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleUpdateOverstayPolicy").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		parkingLotID, err := strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		maxStayMinutes, err := strconv.Atoi(query.Get("max_stay_minutes"))
+		if err != nil || maxStayMinutes < 0 {
+			utils.RespondWithError(w, "Invalid max stay minutes", http.StatusBadRequest, logger)
+			return
+		}
+		overstayFine, err := strconv.Atoi(query.Get("overstay_fine"))
+		if err != nil || overstayFine < 0 {
+			utils.RespondWithError(w, "Invalid overstay fine", http.StatusBadRequest, logger)
+			return
+		}
+		escalationMinutes, err := strconv.Atoi(query.Get("overstay_escalation_minutes"))
+		if err != nil || escalationMinutes < 1 {
+			utils.RespondWithError(w, "Invalid overstay escalation minutes", http.StatusBadRequest, logger)
+			return
+		}
+
+		parkingLot, err := s.Repository.UpdateOverstayPolicy(uint(parkingLotID), maxStayMinutes, overstayFine, escalationMinutes)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to update overstay policy")
+			utils.RespondWithError(w, "Failed to update overstay policy", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new policy
+		logger.Info().Uint("parking_lot_id", parkingLot.ID).Int("max_stay_minutes", parkingLot.MaxStayMinutes).Msg("Overstay policy updated successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Overstay policy updated successfully",
+			Data:    parkingLot,
+		}, logger)
+	}
+}
@@ -0,0 +1,137 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"parkingManagementSystem/gates"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+)
+
+// gateEventsLimit is the number of recent decisions returned with a gate status.
+const gateEventsLimit = 20
+
+// allowGate opens the gate named by the gate_id query parameter, if any.
+// It must only be called after the park or pay step has committed.
+func allowGate(s *state.State, r *http.Request, event models.GateEvent) *models.GateEvent {
+	event.GateID = r.URL.Query().Get("gate_id")
+	if event.GateID == "" {
+		return nil
+	}
+	return s.Gates.Allow(event)
+}
+
+// denyGate records that the vehicle at the gate named by the gate_id query
+// parameter, if any, was refused.
+func denyGate(s *state.State, r *http.Request, event models.GateEvent, reason string) {
+	event.GateID = r.URL.Query().Get("gate_id")
+	if event.GateID == "" {
+		return
+	}
+	s.Gates.Deny(event, reason)
+}
+
+func handleGetGateStatus(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetGateStatus").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		gateID := r.URL.Query().Get("gate_id")
+		if gateID == "" {
+			utils.RespondWithError(w, "Gate ID is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Fetch the recent decisions for the gate
+		events, err := s.Repository.GetGateEvents(gateID, gateEventsLimit)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch gate events")
+			utils.RespondWithError(w, "Failed to fetch gate events", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Ask the controller for the live state, an unreachable gate is reported as a fault
+		status, err := s.Gates.Status(ctx, gateID)
+		if errors.Is(err, gates.ErrNoGateController) {
+			status = ""
+		} else if err != nil {
+			logger.Warn().Err(err).Str("gate_id", gateID).Msg("Failed to fetch gate status")
+			status = gates.StatusFault
+		}
+
+		// Respond with the gate status and decisions
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Gate status fetched successfully",
+			Data: struct {
+				GateID string             `json:"gate_id"`
+				Status string             `json:"status,omitempty"`
+				Events []models.GateEvent `json:"events"`
+			}{
+				GateID: gateID,
+				Status: status,
+				Events: events,
+			},
+		}, logger)
+	}
+}
+
+func handleOverrideGate(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleOverrideGate").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		gateID := r.URL.Query().Get("gate_id")
+		if gateID == "" {
+			utils.RespondWithError(w, "Gate ID is required", http.StatusBadRequest, logger)
+			return
+		}
+		action := r.URL.Query().Get("action")
+		if action != "open" && action != "close" {
+			utils.RespondWithError(w, "Action must be open or close", http.StatusBadRequest, logger)
+			return
+		}
+		operator := r.URL.Query().Get("operator")
+		if operator == "" {
+			utils.RespondWithError(w, "Operator is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Send the manual command to the gate
+		event, err := s.Gates.Override(ctx, gateID, action == "open", operator, r.URL.Query().Get("reason"))
+		if errors.Is(err, gates.ErrNoGateController) {
+			utils.RespondWithError(w, "No gate controller configured", http.StatusServiceUnavailable, logger)
+			return
+		}
+		if errors.Is(err, gates.ErrGateTimeout) {
+			utils.RespondWithError(w, "Gate did not respond in time", http.StatusGatewayTimeout, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to override gate")
+			utils.RespondWithError(w, "Failed to override gate", http.StatusBadGateway, logger)
+			return
+		}
+
+		// Log the manual override
+		logger.Info().Str("gate_id", gateID).Str("action", action).Str("operator", operator).Msg("Gate overridden successfully")
+
+		// Respond with the recorded decision
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Gate overridden successfully",
+			Data:    event,
+		}, logger)
+	}
+}
@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"sync/atomic"
+	"time"
+)
+
+// readinessTimeout bounds the database checks of a readiness probe.
+const readinessTimeout = 2 * time.Second
+
+// handleLiveness answers as long as the process serves requests.
+func handleLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.With().
+			Str("handler", "handleLiveness").
+			Str("request_id", middleware.GetReqID(r.Context())).
+			Logger()
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Alive",
+		}, logger)
+	}
+}
+
+// handleReadiness answers 503 while the service should get no traffic: the
+// database cannot be reached, migrations are pending, or the server is
+// shutting down.
+func handleReadiness(s *state.State, draining *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.With().
+			Str("handler", "handleReadiness").
+			Str("request_id", middleware.GetReqID(r.Context())).
+			Logger()
+
+		if draining.Load() {
+			utils.RespondWithError(w, "Shutting down", http.StatusServiceUnavailable, logger)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+		if err := s.Repository.CheckReady(ctx); err != nil {
+			logger.Warn().Err(err).Msg("Not ready")
+			utils.RespondWithError(w, "Not ready: "+err.Error(), http.StatusServiceUnavailable, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Ready",
+		}, logger)
+	}
+}
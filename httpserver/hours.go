@@ -0,0 +1,288 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/schedule"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+// maxCalendarSize bounds the size of an uploaded holiday calendar.
+const maxCalendarSize = 1 << 20
+
+// OpeningHoursReqBody replaces the weekly opening hours of a lot.
+type OpeningHoursReqBody struct {
+	ParkingLotID uint                  `json:"parking_lot_id"`
+	Timezone     string                `json:"timezone"`
+	OvernightFee int                   `json:"overnight_fee"`
+	Hours        []models.OpeningHours `json:"hours"`
+}
+
+// openingStatusResponse tells whether a lot is open and when that changes.
+type openingStatusResponse struct {
+	IsOpen       bool                      `json:"is_open"`
+	NextChangeAt *time.Time                `json:"next_change_at,omitempty"` // Next closing when open, next opening when closed
+	Timezone     string                    `json:"timezone"`
+	OvernightFee int                       `json:"overnight_fee"`
+	Hours        []models.OpeningHours     `json:"hours"`
+	Exceptions   []models.LotExceptionDate `json:"exceptions"` // Upcoming exception dates
+}
+
+func handleSetOpeningHours(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleSetOpeningHours").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody OpeningHoursReqBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		if reqBody.Timezone == "" {
+			reqBody.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(reqBody.Timezone); err != nil {
+			utils.RespondWithError(w, "Unknown time zone", http.StatusBadRequest, logger)
+			return
+		}
+		if reqBody.OvernightFee < 0 {
+			utils.RespondWithError(w, "Overnight fee must not be negative", http.StatusBadRequest, logger)
+			return
+		}
+		for _, hours := range reqBody.Hours {
+			if hours.Weekday < 0 || hours.Weekday > 6 {
+				utils.RespondWithError(w, "Weekday must be between 0 (Sunday) and 6", http.StatusBadRequest, logger)
+				return
+			}
+			if !validClocks(hours.Opens, hours.Closes) {
+				utils.RespondWithError(w, "Opening and closing times must be HH:MM", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		err := s.Repository.SetOpeningHours(reqBody.ParkingLotID, reqBody.Timezone, reqBody.OvernightFee, reqBody.Hours)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to set opening hours")
+			utils.RespondWithError(w, "Failed to set opening hours", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new opening hours
+		logger.Info().Uint("parking_lot_id", reqBody.ParkingLotID).Int("periods", len(reqBody.Hours)).Msg("Opening hours set successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Opening hours set successfully",
+			Data:    reqBody.Hours,
+		}, logger)
+	}
+}
+
+func handleGetOpeningStatus(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetOpeningStatus").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		parkingLot, lotSchedule, err := s.Repository.GetLotSchedule(uint(parkingLotID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch lot schedule")
+			utils.RespondWithError(w, "Failed to fetch lot schedule", http.StatusInternalServerError, logger)
+			return
+		}
+		hours, err := s.Repository.GetOpeningHours(uint(parkingLotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch opening hours")
+			utils.RespondWithError(w, "Failed to fetch opening hours", http.StatusInternalServerError, logger)
+			return
+		}
+
+		now := time.Now()
+		location, _ := time.LoadLocation(parkingLot.Timezone)
+		exceptions, err := s.Repository.GetLotExceptionDates(uint(parkingLotID), now.In(location).Format(schedule.DateLayout))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch exception dates")
+			utils.RespondWithError(w, "Failed to fetch exception dates", http.StatusInternalServerError, logger)
+			return
+		}
+
+		status := openingStatusResponse{
+			IsOpen:       lotSchedule.IsOpen(now),
+			Timezone:     parkingLot.Timezone,
+			OvernightFee: parkingLot.OvernightFee,
+			Hours:        hours,
+			Exceptions:   exceptions,
+		}
+		if nextChange, ok := lotSchedule.NextChange(now); ok {
+			status.NextChangeAt = &nextChange
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Opening status fetched successfully",
+			Data:    status,
+		}, logger)
+	}
+}
+
+func handleSaveLotExceptionDate(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleSaveLotExceptionDate").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var exception models.LotExceptionDate
+		if err := json.NewDecoder(r.Body).Decode(&exception); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		if _, err := time.Parse(schedule.DateLayout, exception.Date); err != nil {
+			utils.RespondWithError(w, "Date must be YYYY-MM-DD", http.StatusBadRequest, logger)
+			return
+		}
+		if !exception.Closed && !validClocks(exception.Opens, exception.Closes) {
+			utils.RespondWithError(w, "Special hours must be HH:MM unless the lot is closed", http.StatusBadRequest, logger)
+			return
+		}
+		if _, err := s.Repository.GetParkingLot(exception.ParkingLotID); err != nil {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		exception.ID = 0
+
+		if err := s.Repository.SaveLotExceptionDates([]models.LotExceptionDate{exception}); err != nil {
+			logger.Error().Err(err).Msg("Failed to save exception date")
+			utils.RespondWithError(w, "Failed to save exception date", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Exception date saved successfully",
+			Data:    exception,
+		}, logger)
+	}
+}
+
+func handleDeleteLotExceptionDate(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleDeleteLotExceptionDate").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		exceptionID, err := strconv.ParseUint(r.URL.Query().Get("exception_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid exception ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		err = s.Repository.DeleteLotExceptionDate(uint(exceptionID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Exception date not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to delete exception date")
+			utils.RespondWithError(w, "Failed to delete exception date", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Exception date deleted successfully",
+			Data:    nil,
+		}, logger)
+	}
+}
+
+func handleImportHolidays(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleImportHolidays").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		if _, err := s.Repository.GetParkingLot(uint(parkingLotID)); err != nil {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+
+		// The request body is the iCalendar file
+		holidays, err := schedule.ParseHolidays(http.MaxBytesReader(w, r.Body, maxCalendarSize))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to parse calendar")
+			utils.RespondWithError(w, "Failed to parse calendar: "+err.Error(), http.StatusBadRequest, logger)
+			return
+		}
+		for i := range holidays {
+			holidays[i].ParkingLotID = uint(parkingLotID)
+		}
+
+		if err := s.Repository.SaveLotExceptionDates(holidays); err != nil {
+			logger.Error().Err(err).Msg("Failed to save holidays")
+			utils.RespondWithError(w, "Failed to save holidays", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the import
+		logger.Info().Uint64("parking_lot_id", parkingLotID).Int("dates", len(holidays)).Msg("Holidays imported successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Holidays imported successfully",
+			Data:    holidays,
+		}, logger)
+	}
+}
+
+func validClocks(opens, closes string) bool {
+	if _, err := schedule.ParseClock(opens); err != nil {
+		return false
+	}
+	_, err := schedule.ParseClock(closes)
+	return err == nil
+}
@@ -0,0 +1,209 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+// UpdateParkingLotReqBody changes the fields of a parking lot that are set.
+type UpdateParkingLotReqBody struct {
+	ParkingLotID     uint            `json:"parking_lot_id"`
+	Name             *string         `json:"name"`
+	Location         *string         `json:"location"`
+	Description      *string         `json:"description"`
+	Operator         *string         `json:"operator"`
+	Address          *models.Address `json:"address"`
+	Latitude         *float64        `json:"latitude"`
+	Longitude        *float64        `json:"longitude"`
+	LostTicketPolicy *string         `json:"lost_ticket_policy"`
+	LostTicketFee    *int            `json:"lost_ticket_fee"`
+}
+
+// DecommissionReqBody takes slots of a lot, given by relative IDs, out of service.
+type DecommissionReqBody struct {
+	ParkingLotID uint   `json:"parking_lot_id"`
+	RelativeIDs  []uint `json:"relative_ids"`
+	Drain        bool   `json:"drain"` // Wait for parked cars to leave instead of failing
+}
+
+func handleUpdateParkingLot(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleUpdateParkingLot").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody UpdateParkingLotReqBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		parkingLot, err := s.Repository.GetParkingLot(reqBody.ParkingLotID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch parking lot")
+			utils.RespondWithError(w, "Failed to fetch parking lot", http.StatusInternalServerError, logger)
+			return
+		}
+
+		if reqBody.Name != nil {
+			parkingLot.Name = *reqBody.Name
+		}
+		if reqBody.Location != nil {
+			parkingLot.Location = *reqBody.Location
+		}
+		if reqBody.Description != nil {
+			parkingLot.Description = *reqBody.Description
+		}
+		if reqBody.Operator != nil {
+			parkingLot.Operator = *reqBody.Operator
+		}
+		if reqBody.Address != nil {
+			parkingLot.Address = *reqBody.Address
+		}
+		if reqBody.Latitude != nil || reqBody.Longitude != nil {
+			if !validCoordinates(reqBody.Latitude, reqBody.Longitude) {
+				utils.RespondWithError(w, "Latitude and longitude must be set together and within range", http.StatusBadRequest, logger)
+				return
+			}
+			parkingLot.Latitude = reqBody.Latitude
+			parkingLot.Longitude = reqBody.Longitude
+		}
+		if reqBody.LostTicketPolicy != nil {
+			if *reqBody.LostTicketPolicy != models.LostTicketPolicyFlatFee &&
+				*reqBody.LostTicketPolicy != models.LostTicketPolicyElapsedTime {
+				utils.RespondWithError(w, "Lost ticket policy must be flat_fee or elapsed_time", http.StatusBadRequest, logger)
+				return
+			}
+			parkingLot.LostTicketPolicy = *reqBody.LostTicketPolicy
+		}
+		if reqBody.LostTicketFee != nil {
+			if *reqBody.LostTicketFee < 0 {
+				utils.RespondWithError(w, "Lost ticket fee must not be negative", http.StatusBadRequest, logger)
+				return
+			}
+			parkingLot.LostTicketFee = *reqBody.LostTicketFee
+		}
+
+		if err := s.Repository.UpdateParkingLotDetails(parkingLot); err != nil {
+			logger.Error().Err(err).Msg("Failed to update parking lot")
+			utils.RespondWithError(w, "Failed to update parking lot", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the successful update
+		logger.Info().Uint("parking_lot_id", parkingLot.ID).Msg("Parking lot updated successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parking lot updated successfully",
+			Data:    parkingLot,
+		}, logger)
+	}
+}
+
+func handleAddParkingSlots(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleAddParkingSlots").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		count, err := strconv.Atoi(r.URL.Query().Get("count"))
+		if err != nil || count < 1 {
+			utils.RespondWithError(w, "Number of slots must be greater than 0", http.StatusBadRequest, logger)
+			return
+		}
+
+		parkingSlots, err := s.Repository.AddParkingSlots(uint(parkingLotID), count)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to add parking slots")
+			utils.RespondWithError(w, "Failed to add parking slots", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new slots
+		logger.Info().Uint64("parking_lot_id", parkingLotID).Int("count", count).Msg("Parking slots added successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parking slots added successfully",
+			Data:    parkingSlots,
+		}, logger)
+	}
+}
+
+func handleDecommissionParkingSlots(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleDecommissionParkingSlots").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody DecommissionReqBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+		if len(reqBody.RelativeIDs) == 0 {
+			utils.RespondWithError(w, "At least one relative slot ID is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		results, err := s.Repository.DecommissionParkingSlots(reqBody.ParkingLotID, reqBody.RelativeIDs, reqBody.Drain)
+		if errors.Is(err, repository.ErrUnknownParkingSlot) {
+			utils.RespondWithError(w, "Parking slot not found in the parking lot", http.StatusNotFound, logger)
+			return
+		}
+		if errors.Is(err, repository.ErrBulkOperationFailed) {
+			utils.RespondWithJSON(w, http.StatusConflict, utils.CommonResponse{
+				Code:    "error",
+				Message: "Some parking slots are booked, nothing was decommissioned",
+				Data:    results,
+			}, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to decommission parking slots")
+			utils.RespondWithError(w, "Failed to decommission parking slots", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the decommissioned slots
+		logger.Info().Uint("parking_lot_id", reqBody.ParkingLotID).Int("slots", len(results)).Msg("Parking slots decommissioned successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parking slots decommissioned successfully",
+			Data:    results,
+		}, logger)
+	}
+}
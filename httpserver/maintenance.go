@@ -0,0 +1,192 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+// WorkOrderReqBody plans maintenance of slots given by their relative IDs.
+type WorkOrderReqBody struct {
+	ParkingLotID uint      `json:"parking_lot_id"`
+	RelativeIDs  []uint    `json:"relative_ids"`
+	Reason       string    `json:"reason"`
+	Assignee     string    `json:"assignee"`
+	PlannedStart time.Time `json:"planned_start"`
+	PlannedEnd   time.Time `json:"planned_end"`
+}
+
+func handleCreateMaintenanceWorkOrder(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCreateMaintenanceWorkOrder").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody WorkOrderReqBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		if len(reqBody.RelativeIDs) == 0 {
+			utils.RespondWithError(w, "At least one relative slot ID is required", http.StatusBadRequest, logger)
+			return
+		}
+		if reqBody.Reason == "" {
+			utils.RespondWithError(w, "Reason is required", http.StatusBadRequest, logger)
+			return
+		}
+		if reqBody.PlannedStart.IsZero() || !reqBody.PlannedEnd.After(reqBody.PlannedStart) {
+			utils.RespondWithError(w, "Planned end must be after planned start", http.StatusBadRequest, logger)
+			return
+		}
+
+		order := models.MaintenanceWorkOrder{
+			ParkingLotID: reqBody.ParkingLotID,
+			Reason:       reqBody.Reason,
+			Assignee:     reqBody.Assignee,
+			PlannedStart: reqBody.PlannedStart,
+			PlannedEnd:   reqBody.PlannedEnd,
+		}
+		err := s.Repository.CreateMaintenanceWorkOrder(&order, reqBody.RelativeIDs)
+		if errors.Is(err, repository.ErrUnknownParkingSlot) {
+			utils.RespondWithError(w, "Parking slot not found in the parking lot", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to create maintenance work order")
+			utils.RespondWithError(w, "Failed to create maintenance work order", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// A work order that is already due starts right away
+		if !order.PlannedStart.After(time.Now()) {
+			if _, err := s.Maintenance.Apply(); err != nil {
+				logger.Error().Err(err).Msg("Failed to apply maintenance schedule")
+			}
+		}
+
+		// Log the new work order
+		logger.Info().Uint("work_order_id", order.ID).Uint("parking_lot_id", order.ParkingLotID).Msg("Maintenance work order created successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Maintenance work order created successfully",
+			Data:    order,
+		}, logger)
+	}
+}
+
+func handleGetMaintenanceWorkOrders(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetMaintenanceWorkOrders").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		orders, err := s.Repository.GetMaintenanceWorkOrders(uint(parkingLotID), r.URL.Query().Get("status"))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch maintenance work orders")
+			utils.RespondWithError(w, "Failed to fetch maintenance work orders", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Maintenance work orders fetched successfully",
+			Data:    orders,
+		}, logger)
+	}
+}
+
+// handleFinishMaintenanceWorkOrder completes or cancels a work order, depending on status.
+func handleFinishMaintenanceWorkOrder(s *state.State, status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleFinishMaintenanceWorkOrder").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		workOrderID, err := strconv.ParseUint(r.URL.Query().Get("work_order_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid work order ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		order, err := s.Repository.FinishMaintenanceWorkOrder(uint(workOrderID), status)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Maintenance work order not found", http.StatusNotFound, logger)
+			return
+		}
+		if errors.Is(err, repository.ErrWorkOrderFinished) {
+			utils.RespondWithError(w, "Maintenance work order is already finished", http.StatusConflict, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to finish maintenance work order")
+			utils.RespondWithError(w, "Failed to finish maintenance work order", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the finished work order
+		logger.Info().Uint("work_order_id", order.ID).Str("status", status).Msg("Maintenance work order finished successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Maintenance work order finished successfully",
+			Data:    order,
+		}, logger)
+	}
+}
+
+func handleGetMaintenanceHistory(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetMaintenanceHistory").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingSlotID, err := strconv.ParseUint(r.URL.Query().Get("parking_slot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking slot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		maintenanceEvents, err := s.Repository.GetMaintenanceEvents(uint(parkingSlotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch maintenance history")
+			utils.RespondWithError(w, "Failed to fetch maintenance history", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Maintenance history fetched successfully",
+			Data:    maintenanceEvents,
+		}, logger)
+	}
+}
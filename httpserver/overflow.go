@@ -0,0 +1,85 @@
+package httpserver
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+// lotFullCode is the response code of an entry refused because the lot is full.
+const lotFullCode = "lot_full"
+
+// respondLotFull turns a vehicle away from a full lot with the nearby
+// alternatives that have a slot of the vehicle's slot_type, standard by
+// default. With redirect=auto a slot is held at the best of them.
+func respondLotFull(w http.ResponseWriter, r *http.Request, s *state.State, parkingLotID uint, carID *uint, plate string, logger zerolog.Logger) {
+	hold := r.URL.Query().Get("redirect") == "auto"
+	slotType := r.URL.Query().Get("slot_type")
+	if !isSlotType(slotType) {
+		slotType = models.SlotTypeStandard
+	}
+	outcome, err := s.Overflow.Redirect(parkingLotID, carID, plate, slotType, hold)
+	if err != nil {
+		// The driver is turned away either way, just without alternatives
+		logger.Error().Err(err).Msg("Failed to find overflow alternatives")
+		utils.RespondWithErrorCode(w, lotFullCode, "No available parking slots in the specified parking lot", http.StatusConflict, logger)
+		return
+	}
+
+	message := "No available parking slots in the specified parking lot"
+	if outcome.Hold != nil {
+		message = "No available parking slots in the specified parking lot, a slot is held at a nearby lot"
+	}
+	utils.RespondWithJSON(w, http.StatusConflict, utils.CommonResponse{
+		Code:    lotFullCode,
+		Message: message,
+		Data:    outcome,
+	}, logger)
+}
+
+func handleGetOverflowStats(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetOverflowStats").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
+		if err != nil {
+			utils.RespondWithError(w, "Invalid from date, want YYYY-MM-DD", http.StatusBadRequest, logger)
+			return
+		}
+		to, err := time.Parse("2006-01-02", r.URL.Query().Get("to"))
+		if err != nil || to.Before(from) {
+			utils.RespondWithError(w, "Invalid to date, want YYYY-MM-DD not before from", http.StatusBadRequest, logger)
+			return
+		}
+
+		// The to date is included
+		stats, err := s.Repository.GetOverflowStats(uint(parkingLotID), from, to.AddDate(0, 0, 1))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch overflow stats")
+			utils.RespondWithError(w, "Failed to fetch overflow stats", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Overflow stats fetched successfully",
+			Data:    stats,
+		}, logger)
+	}
+}
@@ -2,13 +2,14 @@ package httpserver
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"math"
 	"net/http"
 	"parkingManagementSystem/models"
 	_ "parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
 	"parkingManagementSystem/state"
 	"parkingManagementSystem/utils"
 	"strconv"
@@ -16,8 +17,26 @@ import (
 )
 
 type ReqBody struct {
-	Location string `json:"location"`
-	Slots    int    `json:"slots"`
+	Name             string         `json:"name"`
+	Location         string         `json:"location"`
+	Description      string         `json:"description"`
+	Operator         string         `json:"operator"`
+	Address          models.Address `json:"address"`
+	Latitude         *float64       `json:"latitude"`
+	Longitude        *float64       `json:"longitude"`
+	Slots            int            `json:"slots"`
+	LostTicketPolicy string         `json:"lost_ticket_policy"`
+	LostTicketFee    *int           `json:"lost_ticket_fee"`
+}
+
+// gateResponse carries the gate decision taken for a request that named a gate.
+type gateResponse struct {
+	Gate *models.GateEvent `json:"gate,omitempty"`
+}
+
+type unparkResponse struct {
+	*models.ParkingCharge
+	Gate *models.GateEvent `json:"gate,omitempty"`
 }
 
 func handleCreateParkingLot(s *state.State) http.HandlerFunc {
@@ -43,31 +62,46 @@ func handleCreateParkingLot(s *state.State) http.HandlerFunc {
 			return
 		}
 
+		if reqBody.LostTicketPolicy != "" &&
+			reqBody.LostTicketPolicy != models.LostTicketPolicyFlatFee &&
+			reqBody.LostTicketPolicy != models.LostTicketPolicyElapsedTime {
+			utils.RespondWithError(w, "Lost ticket policy must be flat_fee or elapsed_time", http.StatusBadRequest, logger)
+			return
+		}
+
+		lostTicketFee := models.DefaultLostTicketFee
+		if reqBody.LostTicketFee != nil {
+			lostTicketFee = *reqBody.LostTicketFee
+		}
+		if lostTicketFee < 0 {
+			utils.RespondWithError(w, "Lost ticket fee must not be negative", http.StatusBadRequest, logger)
+			return
+		}
+
+		if !validCoordinates(reqBody.Latitude, reqBody.Longitude) {
+			utils.RespondWithError(w, "Latitude and longitude must be set together and within range", http.StatusBadRequest, logger)
+			return
+		}
+
 		// Create the parking lot
 		parkingLot := models.ParkingLot{
-			Location: reqBody.Location,
-		}
-		if err := repo.Create(&parkingLot).Error; err != nil {
+			Name:             reqBody.Name,
+			Location:         reqBody.Location,
+			Description:      reqBody.Description,
+			Operator:         reqBody.Operator,
+			Address:          reqBody.Address,
+			Latitude:         reqBody.Latitude,
+			Longitude:        reqBody.Longitude,
+			LostTicketPolicy: reqBody.LostTicketPolicy,
+			LostTicketFee:    lostTicketFee,
+		}
+		// Create the parking lot together with its slots
+		if err := repo.CreateParkingLot(&parkingLot, reqBody.Slots); err != nil {
 			logger.Error().Err(err).Msg("Failed to create parking lot")
 			utils.RespondWithError(w, "Failed to create parking lot", http.StatusInternalServerError, logger)
 			return
 		}
 
-		// Create parking slots with relative IDs
-		for i := 1; i <= reqBody.Slots; i++ {
-			parkingSlot := models.ParkingSlot{
-				ParkingLotID: parkingLot.ID,
-				RelativeID:   uint(i),
-			}
-			if err := repo.Create(&parkingSlot).Error; err != nil {
-				// Rollback the created parking lot if any error occurs while creating parking slots
-				repo.Delete(&parkingLot)
-				logger.Error().Err(err).Msg("Failed to create parking slot")
-				utils.RespondWithError(w, "Failed to create parking slot", http.StatusInternalServerError, logger)
-				return
-			}
-		}
-
 		// Respond with the newly created parking lot
 		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
 			Code:    "success",
@@ -99,18 +133,31 @@ func handleParkCar(s *state.State) http.HandlerFunc {
 		}
 
 		// Check if the car is already parked
+		lotID, cid := uint(parkingLotID), uint(carID)
+		gateEvent := models.GateEvent{Direction: models.GateDirectionEntry, ParkingLotID: &lotID, CarID: &cid}
 		var car models.Car
 		if err := s.Repository.Find(&car, "id = ?", carID).Error; err != nil {
 			utils.RespondWithError(w, "Failed to fetch car details", http.StatusInternalServerError, logger)
 			return
 		}
 		if car.ParkingSlotID != nil {
+			denyGate(s, r, gateEvent, "car is already parked")
 			utils.RespondWithError(w, "Car is already parked", http.StatusBadRequest, logger)
 			return
 		}
 
 		// Park the car using ParkCar function
 		err = s.Repository.ParkCar(uint(parkingLotID), uint(carID))
+		if err != nil {
+			denyGate(s, r, gateEvent, err.Error())
+		}
+		if respondIfEntryRefused(w, err, logger) {
+			return
+		}
+		if errors.Is(err, repository.ErrNoAvailableParkingSlot) {
+			respondLotFull(w, r, s, lotID, &cid, car.Plate, logger)
+			return
+		}
 		if err != nil {
 			utils.RespondWithError(w, "Failed to park the car", http.StatusInternalServerError, logger)
 			return
@@ -119,11 +166,17 @@ func handleParkCar(s *state.State) http.HandlerFunc {
 		// Log the successful parking
 		logger.Info().Str("parking_lot_id", r.URL.Query().Get("parking_lot_id")).Str("car_id", r.URL.Query().Get("car_id")).Msg("Car parked successfully")
 
+		// Open the entry gate now that the car is parked
+		var data interface{}
+		if gate := allowGate(s, r, gateEvent); gate != nil {
+			data = gateResponse{Gate: gate}
+		}
+
 		// Respond with success message
 		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
 			Code:    "success",
 			Message: "Car parked successfully",
-			Data:    nil,
+			Data:    data,
 		}, logger)
 	}
 }
@@ -156,67 +209,33 @@ func handleUnparkCar(s *state.State) http.HandlerFunc {
 			return
 		}
 
-		// Get the parking slot details
-		var parkingSlot models.ParkingSlot
-		if err := s.Repository.Find(&parkingSlot, "id = ?", *car.ParkingSlotID).Error; err != nil {
-			utils.RespondWithError(w, "Failed to fetch parking slot details", http.StatusInternalServerError, logger)
-			return
-		}
-
-		// Calculate parking duration and amount to be paid
-		parkedAt := *parkingSlot.ParkedAt
-		unparkedAt := time.Now()
-		totalParkingTime := int(math.Ceil(unparkedAt.Sub(parkedAt).Hours()))
-		totalAmountToBePaid := totalParkingTime * 10
-
-		// Construct JSON data
-		parkingDetails := struct {
-			TotalParkingTime    int `json:"total_parking_time"`
-			TotalAmountToBePaid int `json:"total_amount_to_be_paid"`
-		}{
-			TotalParkingTime:    totalParkingTime,
-			TotalAmountToBePaid: totalAmountToBePaid,
-		}
-
-		// Update the car model
-		car.ParkingSlotID = nil
-		if err := s.Repository.Save(&car).Error; err != nil {
-			utils.RespondWithError(w, "Failed to update car details", http.StatusInternalServerError, logger)
-			return
-		}
-
-		// Update the parking slot model
-		parkingSlot.IsBooked = false
-		parkingSlot.CarID = nil
-		parkingSlot.ParkedAt = nil
-		if err := s.Repository.Save(&parkingSlot).Error; err != nil {
-			utils.RespondWithError(w, "Failed to update parking slot details", http.StatusInternalServerError, logger)
-			return
-		}
-
-		// Update the parking history model
-		date := time.Now().Truncate(24 * time.Hour)
-		var parkingHistory models.ParkingHistory
-		if err := s.Repository.FirstOrCreate(&parkingHistory, "date = ?", date).Error; err != nil {
-			utils.RespondWithError(w, "Failed to fetch or create parking history", http.StatusInternalServerError, logger)
+		// Unpark the car and charge the parking fee
+		parkingDetails, err := s.Repository.UnparkCar(uint(carID))
+		if errors.Is(err, repository.ErrNotParked) {
+			utils.RespondWithError(w, "Car is already unparked", http.StatusBadRequest, logger)
 			return
 		}
-		parkingHistory.CarsParked += 1
-		parkingHistory.TotalParkingTime += totalParkingTime
-		parkingHistory.TotalRevenueEarned += int64(totalAmountToBePaid)
-		if err := s.Repository.Save(&parkingHistory).Error; err != nil {
-			utils.RespondWithError(w, "Failed to update parking history", http.StatusInternalServerError, logger)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to unpark the car")
+			utils.RespondWithError(w, "Failed to unpark the car", http.StatusInternalServerError, logger)
 			return
 		}
 
 		// Log the successful unparking
 		logger.Info().Str("car_id", r.URL.Query().Get("car_id")).Msg("Car unparked successfully")
 
+		// Open the exit gate now that the stay is paid
+		cid := uint(carID)
+		gate := allowGate(s, r, models.GateEvent{Direction: models.GateDirectionExit, CarID: &cid})
+
 		// Respond with success message and parking details
 		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
 			Code:    "success",
 			Message: "Car unparked successfully",
-			Data:    parkingDetails,
+			Data: unparkResponse{
+				ParkingCharge: parkingDetails,
+				Gate:          gate,
+			},
 		}, logger)
 	}
 }
@@ -256,10 +275,7 @@ func handlePutParkingSlotInMaintenance(s *state.State) http.HandlerFunc {
 		}
 
 		// Put the parking slot in maintenance
-		parkingSlot.IsBooked = true
-		parkingSlot.IsInMaintenance = true
-
-		if err := s.Repository.Save(&parkingSlot).Error; err != nil {
+		if err := s.Repository.SetParkingSlotMaintenance(&parkingSlot, true); err != nil {
 			utils.RespondWithError(w, "Failed to put parking slot in maintenance", http.StatusInternalServerError, logger)
 			return
 		}
@@ -311,10 +327,7 @@ func handlePutParkingSlotOutOfMaintenance(s *state.State) http.HandlerFunc {
 		}
 
 		// Put the parking slot out of maintenance
-		parkingSlot.IsBooked = false
-		parkingSlot.IsInMaintenance = false
-
-		if err := s.Repository.Save(&parkingSlot).Error; err != nil {
+		if err := s.Repository.SetParkingSlotMaintenance(&parkingSlot, false); err != nil {
 			utils.RespondWithError(w, "Failed to put parking slot out of maintenance", http.StatusInternalServerError, logger)
 			return
 		}
@@ -346,9 +359,9 @@ func handleGetParkingLotStatus(s *state.State) http.HandlerFunc {
 			return
 		}
 
-		// Fetch all parking slots of the parking lot
-		var parkingSlots []models.ParkingSlot
-		if err := s.Repository.Find(&parkingSlots, "parking_lot_id = ?", parkingLotID).Error; err != nil {
+		// Fetch the parking slots of the parking lot that are in service
+		parkingSlots, err := s.Repository.GetParkingSlots(uint(parkingLotID))
+		if err != nil {
 			utils.RespondWithError(w, "Failed to fetch parking slots", http.StatusInternalServerError, logger)
 			return
 		}
@@ -358,15 +371,7 @@ func handleGetParkingLotStatus(s *state.State) http.HandlerFunc {
 
 		// Iterate over the parking slots to collect status
 		for _, slot := range parkingSlots {
-			slotStatus := map[string]interface{}{
-				"relative_id":       slot.RelativeID,
-				"is_in_maintenance": slot.IsInMaintenance,
-				"is_booked":         slot.IsBooked,
-			}
-			if slot.IsBooked {
-				slotStatus["carID"] = *slot.CarID
-			}
-			parkingLotStatus = append(parkingLotStatus, slotStatus)
+			parkingLotStatus = append(parkingLotStatus, parkingSlotStatus(slot))
 		}
 
 		// Log the successful fetching of parking slot statuses
@@ -381,6 +386,40 @@ func handleGetParkingLotStatus(s *state.State) http.HandlerFunc {
 	}
 }
 
+// parkingSlotStatus is how a slot is shown in the parking lot status and the live stream.
+func parkingSlotStatus(slot models.ParkingSlot) map[string]interface{} {
+	slotStatus := map[string]interface{}{
+		"parking_slot_id":   slot.ID,
+		"relative_id":       slot.RelativeID,
+		"is_in_maintenance": slot.IsInMaintenance,
+		"is_booked":         slot.IsBooked,
+		"slot_type":         slot.SlotType,
+	}
+	if slot.Zone != "" {
+		slotStatus["zone"] = slot.Zone
+	}
+	if slot.Label != "" {
+		slotStatus["label"] = slot.Label
+	}
+	if slot.ChargerConnector != "" {
+		slotStatus["charger_connector"] = slot.ChargerConnector
+		slotStatus["charger_power_kw"] = slot.ChargerPowerKW
+	}
+	if slot.IsDraining {
+		slotStatus["is_draining"] = true
+	}
+	if slot.DecommissionedAt != nil {
+		slotStatus["is_decommissioned"] = true
+	}
+	if slot.CarID != nil {
+		slotStatus["carID"] = *slot.CarID
+	}
+	if slot.TicketSessionID != nil {
+		slotStatus["ticket_session_id"] = *slot.TicketSessionID
+	}
+	return slotStatus
+}
+
 func handleGetHistoryForDay(s *state.State) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -421,3 +460,68 @@ func handleGetHistoryForDay(s *state.State) http.HandlerFunc {
 		}, logger)
 	}
 }
+
+// handleExportHistory returns the history rows of a range of days, both ends
+// included.
+func handleExportHistory(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleExportHistory").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse the request parameters
+		query := r.URL.Query()
+		from, err := time.Parse("2006-01-02", query.Get("from"))
+		if err != nil {
+			utils.RespondWithError(w, "Invalid from date. Please provide the date in YYYY-MM-DD format", http.StatusBadRequest, logger)
+			return
+		}
+		to, err := time.Parse("2006-01-02", query.Get("to"))
+		if err != nil {
+			utils.RespondWithError(w, "Invalid to date. Please provide the date in YYYY-MM-DD format", http.StatusBadRequest, logger)
+			return
+		}
+		if to.Before(from) {
+			utils.RespondWithError(w, "The to date must not be before the from date", http.StatusBadRequest, logger)
+			return
+		}
+
+		history, err := s.Repository.GetParkingHistory(from, to.AddDate(0, 0, 1))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch history data")
+			utils.RespondWithError(w, "Failed to fetch history data", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "History data fetched successfully",
+			Data:    history,
+		}, logger)
+	}
+}
+
+func handleGetParkingLots(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetParkingLots").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		parkingLots, err := s.Repository.GetParkingLots()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch parking lots")
+			utils.RespondWithError(w, "Failed to fetch parking lots", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parking lots fetched successfully",
+			Data:    parkingLots,
+		}, logger)
+	}
+}
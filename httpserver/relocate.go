@@ -0,0 +1,119 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+func handleRelocateParkedCar(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleRelocateParkedCar").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters, without a target the first available slot is used
+		query := r.URL.Query()
+		parkingSlotID, err := strconv.ParseUint(query.Get("parking_slot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking slot ID", http.StatusBadRequest, logger)
+			return
+		}
+		var toRelativeID uint64
+		if value := query.Get("to_relative_id"); value != "" {
+			toRelativeID, err = strconv.ParseUint(value, 10, 64)
+			if err != nil || toRelativeID == 0 {
+				utils.RespondWithError(w, "Invalid target relative ID", http.StatusBadRequest, logger)
+				return
+			}
+		}
+		attendant := query.Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		move, err := s.Repository.RelocateParkedCar(uint(parkingSlotID), uint(toRelativeID), attendant, query.Get("reason"))
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondWithError(w, "Parking slot not found", http.StatusNotFound, logger)
+			return
+		case errors.Is(err, repository.ErrUnknownParkingSlot):
+			utils.RespondWithError(w, "Target parking slot not found", http.StatusNotFound, logger)
+			return
+		case errors.Is(err, repository.ErrNothingToRelocate),
+			errors.Is(err, repository.ErrTargetSlotUnavailable),
+			errors.Is(err, repository.ErrTargetSlotIncompatible),
+			errors.Is(err, repository.ErrNoAvailableParkingSlot):
+			utils.RespondWithError(w, err.Error(), http.StatusConflict, logger)
+			return
+		case err != nil:
+			logger.Error().Err(err).Msg("Failed to relocate parked car")
+			utils.RespondWithError(w, "Failed to relocate parked car", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the move
+		logger.Info().Uint("from_relative_id", move.FromRelativeID).Uint("to_relative_id", move.ToRelativeID).Str("attendant", attendant).Msg("Parked car relocated successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parked car relocated successfully",
+			Data:    move,
+		}, logger)
+	}
+}
+
+func handleGetSlotMoves(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetSlotMoves").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		parkingLotID, err := strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		var carID, ticketSessionID uint64
+		if value := query.Get("car_id"); value != "" {
+			carID, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, "Invalid car ID", http.StatusBadRequest, logger)
+				return
+			}
+		}
+		if value := query.Get("ticket_session_id"); value != "" {
+			ticketSessionID, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, "Invalid ticket session ID", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		moves, err := s.Repository.GetSlotMoves(uint(parkingLotID), uint(carID), uint(ticketSessionID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch slot moves")
+			utils.RespondWithError(w, "Failed to fetch slot moves", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Slot moves fetched successfully",
+			Data:    moves,
+		}, logger)
+	}
+}
@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+const (
+	// defaultSearchRadius is the search radius in meters when none is given.
+	defaultSearchRadius = 2000
+	// maxSearchRadius bounds the search radius in meters.
+	maxSearchRadius = 50000
+	// defaultSearchLimit is the number of lots returned when no limit is given.
+	defaultSearchLimit = 20
+)
+
+func handleSearchParkingLots(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleSearchParkingLots").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		latitude, err := strconv.ParseFloat(query.Get("lat"), 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid latitude", http.StatusBadRequest, logger)
+			return
+		}
+		longitude, err := strconv.ParseFloat(query.Get("lon"), 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid longitude", http.StatusBadRequest, logger)
+			return
+		}
+		if !validCoordinates(&latitude, &longitude) {
+			utils.RespondWithError(w, "Latitude or longitude out of range", http.StatusBadRequest, logger)
+			return
+		}
+
+		radius := float64(defaultSearchRadius)
+		if value := query.Get("radius_m"); value != "" {
+			radius, err = strconv.ParseFloat(value, 64)
+			if err != nil || radius <= 0 || radius > maxSearchRadius {
+				utils.RespondWithError(w, "Radius must be between 0 and 50000 meters", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		limit := defaultSearchLimit
+		if value := query.Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				utils.RespondWithError(w, "Invalid limit", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		slotType := query.Get("slot_type")
+		if slotType != "" && !isSlotType(slotType) {
+			utils.RespondWithError(w, "Unknown slot type", http.StatusBadRequest, logger)
+			return
+		}
+
+		results, err := s.Search.Nearby(latitude, longitude, radius, slotType, limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to search parking lots")
+			utils.RespondWithError(w, "Failed to search parking lots", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Parking lots fetched successfully",
+			Data:    results,
+		}, logger)
+	}
+}
+
+// validCoordinates reports whether latitude and longitude are either both
+// unset or both set and within range.
+func validCoordinates(latitude, longitude *float64) bool {
+	if latitude == nil || longitude == nil {
+		return latitude == nil && longitude == nil
+	}
+	return *latitude >= -90 && *latitude <= 90 && *longitude >= -180 && *longitude <= 180
+}
@@ -0,0 +1,113 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+// SensorReadingReqBody is the state reported by the ground sensor of a slot.
+type SensorReadingReqBody struct {
+	ParkingSlotID uint      `json:"parking_slot_id"`
+	Occupied      bool      `json:"occupied"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+func handleIngestSensorReading(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleIngestSensorReading").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var reqBody SensorReadingReqBody
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+		if reqBody.Timestamp.IsZero() {
+			reqBody.Timestamp = time.Now()
+		}
+
+		// Store the latest sensor state of the slot
+		sensorState, err := s.Repository.RecordSensorReading(reqBody.ParkingSlotID, reqBody.Occupied, reqBody.Timestamp)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking slot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to record sensor reading")
+			utils.RespondWithError(w, "Failed to record sensor reading", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Sensor reading recorded successfully",
+			Data:    sensorState,
+		}, logger)
+	}
+}
+
+func handleReconcileSensors(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleReconcileSensors").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Run a reconciliation pass now instead of waiting for the next one
+		discrepancies, err := s.Sensors.Reconcile()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to reconcile sensors")
+			utils.RespondWithError(w, "Failed to reconcile sensors", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Sensors reconciled successfully",
+			Data:    discrepancies,
+		}, logger)
+	}
+}
+
+func handleGetSensorDiscrepancies(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetSensorDiscrepancies").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		discrepancies, err := s.Repository.GetOpenSensorDiscrepancies(uint(parkingLotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch sensor discrepancies")
+			utils.RespondWithError(w, "Failed to fetch sensor discrepancies", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Sensor discrepancies fetched successfully",
+			Data:    discrepancies,
+		}, logger)
+	}
+}
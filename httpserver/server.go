@@ -1,41 +1,179 @@
 package httpserver
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httplog"
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"parkingManagementSystem/models"
 	"parkingManagementSystem/state"
+	"sync/atomic"
+	"time"
 )
 
-func Serve(s *state.State) {
+// Serve serves the API until the context is cancelled. It then fails the
+// readiness probe for the shutdown delay, stops accepting connections and
+// waits up to the shutdown timeout for the requests in flight.
+func Serve(ctx context.Context, s *state.State) error {
+	var draining atomic.Bool
+	shutdown := make(chan struct{})
+
 	router := chi.NewRouter()
 	// middlewares
 	router.Use(
 		middleware.RequestID,
-		httplog.RequestLogger(log.Logger),
+		requestLogger,
 	)
 
+	router.Get("/healthz", handleLiveness())
+	router.Get("/readyz", handleReadiness(s, &draining))
+
 	router.Post("/pms/createUser", handleCreateUser(s))
-	router.Post("/pms/createCar", handleCreateCar(s))
+	router.Post("/pms/createCar/{userID}", handleCreateCar(s))
 
 	router.Post("/parkCar", handleParkCar(s))
 	router.Post("/unparkCar", handleUnparkCar(s))
 
+	router.Post("/tickets/entry", handleIssueTicket(s))
+	router.Post("/tickets/exit", handleCloseTicket(s))
+	router.Post("/tickets/scan-exit", handleScanTicketExit(s))
+	router.Get("/tickets/qr", handleGetTicketQRCode(s))
+	router.Get("/tickets/barcode", handleGetTicketBarcode(s))
+	router.Get("/tickets/lost", handleFindLostTicket(s))
+	router.Post("/tickets/lost/close", handleCloseLostTicket(s))
+	router.Get("/tickets/audit", handleGetTicketAuditQueue(s))
+
+	router.Post("/valet/park", handleParkValetCar(s))
+	router.Get("/valet/ticket", handleGetValetTicket(s))
+	router.Post("/valet/request", handleRequestValetCar(s))
+	router.Get("/valet/queue", handleGetValetQueue(s))
+	router.Post("/valet/retrieve", handleStartValetRetrieval(s))
+	router.Post("/valet/ready", handleMarkValetCarReady(s))
+	router.Post("/valet/deliver", handleDeliverValetCar(s))
+
+	router.Get("/gates/status", handleGetGateStatus(s))
+	router.Post("/gates/override", handleOverrideGate(s))
+
+	router.Post("/anpr/lanes", handleCreateCameraLane(s))
+	router.Post("/anpr/events", handleIngestAnprEvent(s))
+	router.Get("/anpr/events", handleGetAnprEvents(s))
+	router.Post("/anpr/review/resolve", handleResolveAnprReview(s))
+
+	router.Post("/sensors/readings", handleIngestSensorReading(s))
+	router.Post("/sensors/reconcile", handleReconcileSensors(s))
+	router.Get("/sensors/discrepancies", handleGetSensorDiscrepancies(s))
+
+	router.Post("/chargers/telemetry", handleIngestChargerTelemetry(s))
+	router.Get("/chargers/sessions", handleGetChargingSessions(s))
+
+	router.Post("/webhooks", handleCreateWebhookSubscription(s))
+	router.Get("/webhooks", handleGetWebhookSubscriptions(s))
+	router.Post("/webhooks/deactivate", handleDeactivateWebhookSubscription(s))
+	router.Get("/webhooks/deliveries", handleGetWebhookDeliveries(s))
+	router.Post("/webhooks/deliveries/redeliver", handleRedeliverWebhook(s))
+
+	router.Post("/sync/events", handleIngestEdgeEvents(s))
+	router.Post("/sync/links", handleCreateEdgeLotLink(s))
+	router.Get("/sync/links", handleGetEdgeLotLinks(s))
+	router.Get("/sync/conflicts", handleGetSyncConflicts(s))
+	router.Get("/sync/status", handleGetSyncStatus(s))
+	router.Post("/sync/run", handleRunSync(s))
+
+	router.Post("/alerts/thresholds", handleCreateCapacityThreshold(s))
+	router.Get("/alerts/thresholds", handleGetCapacityThresholds(s))
+	router.Post("/alerts/thresholds/delete", handleDeleteCapacityThreshold(s))
+	router.Get("/alerts", handleGetCapacityAlerts(s))
+
+	router.Post("/access-list", handleCreateAccessListEntry(s))
+	router.Get("/access-list", handleGetAccessListEntries(s))
+	router.Post("/access-list/delete", handleDeleteAccessListEntry(s))
+	router.Get("/access-list/blocked", handleGetBlockedEntries(s))
+
+	router.Get("/enforcement/queue", handleGetEnforcementQueue(s))
+	router.Post("/enforcement/scan", handleScanOverstays(s))
+	router.Post("/parking-lot/overstay-policy", handleUpdateOverstayPolicy(s))
+
 	router.Post("/createParking", handleCreateParkingLot(s))
+	router.Get("/parking-lots", handleGetParkingLots(s))
 	router.Post("/parking-slots/maintenance", handlePutParkingSlotInMaintenance(s))
 	router.Post("/parking-slots/out-of-maintenance", handlePutParkingSlotOutOfMaintenance(s))
+	router.Post("/parking-slots/bulk", handleBulkUpdateParkingSlots(s))
+	router.Post("/parking-slots/relocate", handleRelocateParkedCar(s))
+	router.Get("/parking-slots/moves", handleGetSlotMoves(s))
+	router.Post("/parking-slots/charger", handleSetParkingSlotCharger(s))
+	router.Post("/parking-lot/charging-tariff", handleUpdateChargingTariff(s))
+	router.Post("/parking-lot/valet", handleUpdateValetSettings(s))
+	router.Post("/maintenance/work-orders", handleCreateMaintenanceWorkOrder(s))
+	router.Get("/maintenance/work-orders", handleGetMaintenanceWorkOrders(s))
+	router.Post("/maintenance/work-orders/complete", handleFinishMaintenanceWorkOrder(s, models.WorkOrderCompleted))
+	router.Post("/maintenance/work-orders/cancel", handleFinishMaintenanceWorkOrder(s, models.WorkOrderCancelled))
+	router.Get("/parking-slots/maintenance-history", handleGetMaintenanceHistory(s))
+	router.Post("/parking-lot/update", handleUpdateParkingLot(s))
+	router.Post("/parking-lot/slots/add", handleAddParkingSlots(s))
+	router.Post("/parking-lot/slots/decommission", handleDecommissionParkingSlots(s))
+	router.Post("/parking-lot/hours", handleSetOpeningHours(s))
+	router.Get("/parking-lot/opening-status", handleGetOpeningStatus(s))
+	router.Post("/parking-lot/exceptions", handleSaveLotExceptionDate(s))
+	router.Post("/parking-lot/exceptions/delete", handleDeleteLotExceptionDate(s))
+	router.Post("/parking-lot/holidays/import", handleImportHolidays(s))
+	router.Get("/parking-lots/search", handleSearchParkingLots(s))
+	router.Get("/overflow/stats", handleGetOverflowStats(s))
 	router.Get("/parking-lot/status", handleGetParkingLotStatus(s))
+	router.Get("/parking-lot/stream", handleStreamParkingLotStatus(s, shutdown))
 	router.Get("/history", handleGetHistoryForDay(s))
+	router.Get("/history/export", handleExportHistory(s))
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", s.Cfg.ApplicationPort),
+		Handler:      router,
+		ReadTimeout:  time.Duration(s.Cfg.HttpReadTimeoutSeconds) * time.Second,
+		WriteTimeout: time.Duration(s.Cfg.HttpWriteTimeoutSeconds) * time.Second,
+		IdleTimeout:  time.Duration(s.Cfg.HttpIdleTimeoutSeconds) * time.Second,
+	}
+	// Streams never finish by themselves
+	server.RegisterOnShutdown(func() { close(shutdown) })
 
 	log.Info().
 		Int("port", s.Cfg.ApplicationPort).
 		Msg("starting http server")
 
-	err := http.ListenAndServe(fmt.Sprintf(":%d", s.Cfg.ApplicationPort), router)
-	if err != nil {
-		log.Fatal().Err(err).Msg("http.ListenAndServe err")
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
 	}
+
+	// Let the orchestrator stop sending traffic before draining
+	draining.Store(true)
+	shutdownDelay := time.Duration(s.Cfg.ShutdownDelaySeconds) * time.Second
+	log.Info().Dur("delay", shutdownDelay).Msg("shutting down http server")
+	time.Sleep(shutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	log.Info().Msg("http server stopped")
+	return nil
+}
+
+// requestLogger logs every request but the health probes, which the
+// orchestrator sends every few seconds.
+func requestLogger(next http.Handler) http.Handler {
+	logged := httplog.RequestLogger(log.Logger)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logged.ServeHTTP(w, r)
+	})
 }
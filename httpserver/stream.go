@@ -0,0 +1,117 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+const (
+	// streamBuffer is how many slot changes a stream may fall behind before it is dropped.
+	streamBuffer = 64
+	// streamHeartbeat keeps idle connections open through proxies.
+	streamHeartbeat = 15 * time.Second
+)
+
+// handleStreamParkingLotStatus pushes the status of a parking lot as
+// Server-Sent Events: a "snapshot" event with every slot, then a
+// "slot_changed" event for each park, unpark or maintenance change. A client
+// that cannot keep up is disconnected and gets a new snapshot on reconnect.
+// Streams end when shutdown is closed, so they do not hold up draining.
+func handleStreamParkingLotStatus(s *state.State, shutdown <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleStreamParkingLotStatus").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			utils.RespondWithError(w, "Streaming is not supported", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// The stream outlives the read and write timeouts of the server
+		controller := http.NewResponseController(w)
+		if err := controller.SetReadDeadline(time.Time{}); err != nil {
+			logger.Warn().Err(err).Msg("Failed to clear the read deadline of the stream")
+		}
+		if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+			logger.Warn().Err(err).Msg("Failed to clear the write deadline of the stream")
+		}
+
+		// Subscribe before taking the snapshot so no change falls in between
+		sub := s.Events.Subscribe(uint(parkingLotID), streamBuffer)
+		defer s.Events.Unsubscribe(sub)
+
+		parkingSlots, err := s.Repository.GetParkingSlots(uint(parkingLotID))
+		if err != nil {
+			utils.RespondWithError(w, "Failed to fetch parking slots", http.StatusInternalServerError, logger)
+			return
+		}
+		snapshot := make([]map[string]interface{}, 0, len(parkingSlots))
+		for _, slot := range parkingSlots {
+			snapshot = append(snapshot, parkingSlotStatus(slot))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		if err := writeEvent(w, "snapshot", snapshot); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		logger.Info().Uint64("parking_lot_id", parkingLotID).Msg("Parking lot status stream opened")
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-shutdown:
+				return
+			case change, ok := <-sub.C:
+				if !ok {
+					logger.Warn().Uint64("parking_lot_id", parkingLotID).Msg("Dropped slow parking lot status stream")
+					return
+				}
+				err = writeEvent(w, "slot_changed", map[string]interface{}{
+					"reason": change.Reason,
+					"slot":   parkingSlotStatus(change.Slot),
+				})
+			case <-heartbeat.C:
+				_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
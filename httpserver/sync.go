@@ -0,0 +1,264 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"parkingManagementSystem/edgesync"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+const (
+	// syncBatchMaxBytes bounds the body of a batch of edge events.
+	syncBatchMaxBytes = 8 << 20
+	// syncConflictsLimit is the number of conflicts returned for review.
+	syncConflictsLimit = 100
+)
+
+// handleIngestEdgeEvents applies a batch of events sent by an edge site. It
+// answers with the events applied even when the batch stopped early, so the
+// site only sends the rest again.
+func handleIngestEdgeEvents(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleIngestEdgeEvents").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		if len(s.Cfg.SyncSiteSecrets) == 0 {
+			utils.RespondWithError(w, "Edge sync is not enabled", http.StatusForbidden, logger)
+			return
+		}
+		// Every site signs with its own secret, so a site cannot send the
+		// events of another
+		siteID := r.Header.Get(edgesync.SiteHeader)
+		secret, ok := s.Cfg.SyncSiteSecrets[siteID]
+		if !ok {
+			utils.RespondWithError(w, "Unknown edge site", http.StatusUnauthorized, logger)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, syncBatchMaxBytes))
+		if err != nil {
+			utils.RespondWithError(w, "Failed to read request body", http.StatusBadRequest, logger)
+			return
+		}
+		if !edgesync.Verify(secret, r.Header.Get(edgesync.TimestampHeader), r.Header.Get(edgesync.SignatureHeader), body, time.Now()) {
+			logger.Warn().Str("site_id", siteID).Msg("Rejected edge batch with an invalid or expired signature")
+			utils.RespondWithError(w, "Invalid or expired signature", http.StatusUnauthorized, logger)
+			return
+		}
+
+		var batch models.SyncBatch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+		if batch.SiteID != siteID {
+			utils.RespondWithError(w, "Site ID is required and must match the "+edgesync.SiteHeader+" header", http.StatusBadRequest, logger)
+			return
+		}
+
+		applied, conflicts, err := s.Repository.ApplyEdgeEvents(batch.SiteID, batch.Events)
+		result := models.SyncResult{Applied: applied, Conflicts: conflicts}
+		if err != nil {
+			logger.Error().Err(err).Str("site_id", batch.SiteID).Msg("Failed to apply edge event")
+			result.Error = err.Error()
+		}
+
+		// Log the sync
+		logger.Info().
+			Str("site_id", batch.SiteID).
+			Int("events", len(batch.Events)).
+			Int("applied", len(applied)).
+			Int("conflicts", len(conflicts)).
+			Msg("Edge events synced")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Edge events synced successfully",
+			Data:    result,
+		}, logger)
+	}
+}
+
+func handleCreateEdgeLotLink(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCreateEdgeLotLink").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var link models.EdgeLotLink
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+		if link.SiteID == "" || link.EdgeLotID == 0 || link.ParkingLotID == 0 {
+			utils.RespondWithError(w, "Site ID, edge parking lot ID and parking lot ID are required", http.StatusBadRequest, logger)
+			return
+		}
+		link.ID = 0
+
+		err := s.Repository.LinkEdgeLot(&link)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if errors.Is(err, repository.ErrEdgeLotLinked) {
+			utils.RespondWithError(w, "Parking lot is already linked", http.StatusConflict, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to link edge parking lot")
+			utils.RespondWithError(w, "Failed to link edge parking lot", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new link
+		logger.Info().
+			Str("site_id", link.SiteID).
+			Uint("edge_parking_lot_id", link.EdgeLotID).
+			Uint("parking_lot_id", link.ParkingLotID).
+			Msg("Edge parking lot linked successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Edge parking lot linked successfully",
+			Data:    link,
+		}, logger)
+	}
+}
+
+func handleGetEdgeLotLinks(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetEdgeLotLinks").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		links, err := s.Repository.GetEdgeLotLinks(r.URL.Query().Get("site_id"))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch edge parking lot links")
+			utils.RespondWithError(w, "Failed to fetch edge parking lot links", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Edge parking lot links fetched successfully",
+			Data:    links,
+		}, logger)
+	}
+}
+
+func handleGetSyncConflicts(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetSyncConflicts").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		var parkingLotID uint64
+		if query.Get("parking_lot_id") != "" {
+			var err error
+			parkingLotID, err = strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		conflicts, err := s.Repository.GetSyncConflicts(query.Get("site_id"), uint(parkingLotID), syncConflictsLimit)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch sync conflicts")
+			utils.RespondWithError(w, "Failed to fetch sync conflicts", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Sync conflicts fetched successfully",
+			Data:    conflicts,
+		}, logger)
+	}
+}
+
+func handleGetSyncStatus(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetSyncStatus").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		if s.Sync == nil {
+			utils.RespondWithError(w, "Not running at an edge site", http.StatusNotFound, logger)
+			return
+		}
+		status, err := s.Sync.Status()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch sync status")
+			utils.RespondWithError(w, "Failed to fetch sync status", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Sync status fetched successfully",
+			Data:    status,
+		}, logger)
+	}
+}
+
+func handleRunSync(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleRunSync").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		if s.Sync == nil {
+			utils.RespondWithError(w, "Not running at an edge site", http.StatusNotFound, logger)
+			return
+		}
+
+		// Sync now instead of waiting for the next attempt
+		synced, err := s.Sync.Sync(ctx)
+		if err != nil {
+			logger.Warn().Err(err).Int("synced", synced).Msg("Failed to sync with the central instance")
+			utils.RespondWithError(w, "Failed to sync with the central instance: "+err.Error(), http.StatusBadGateway, logger)
+			return
+		}
+
+		status, err := s.Sync.Status()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch sync status")
+			utils.RespondWithError(w, "Failed to fetch sync status", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Sync completed successfully",
+			Data:    status,
+		}, logger)
+	}
+}
@@ -0,0 +1,375 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/ticketing"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"strings"
+)
+
+func handleIssueTicket(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleIssueTicket").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Park the vehicle and open a walk-in session
+		lotID := uint(parkingLotID)
+		gateEvent := models.GateEvent{Direction: models.GateDirectionEntry, ParkingLotID: &lotID}
+		session, err := s.Repository.IssueTicket(uint(parkingLotID), r.URL.Query().Get("plate"))
+		if err != nil {
+			denyGate(s, r, gateEvent, err.Error())
+		}
+		if respondIfEntryRefused(w, err, logger) {
+			return
+		}
+		if errors.Is(err, repository.ErrNoAvailableParkingSlot) {
+			respondLotFull(w, r, s, lotID, nil, r.URL.Query().Get("plate"), logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to issue ticket")
+			utils.RespondWithError(w, "Failed to issue ticket", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the successful ticket issue
+		logger.Info().Uint64("parking_lot_id", parkingLotID).Str("ticket_code", session.TicketCode).Msg("Ticket issued successfully")
+
+		// Open the entry gate now that the session is recorded
+		gateEvent.TicketSessionID = &session.ID
+		gate := allowGate(s, r, gateEvent)
+
+		// Respond with the ticket session and the signed token to print on the ticket
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Ticket issued successfully",
+			Data: ticketResponse{
+				TicketSession: session,
+				TicketToken:   s.Tickets.Sign(session.TicketCode),
+				Gate:          gate,
+			},
+		}, logger)
+	}
+}
+
+func handleCloseTicket(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCloseTicket").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		ticketCode := r.URL.Query().Get("ticket_code")
+		if ticketCode == "" {
+			utils.RespondWithError(w, "Ticket code is required", http.StatusBadRequest, logger)
+			return
+		}
+		// The code is not signed, only an attendant may close a ticket from it
+		attendant := r.URL.Query().Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Every use is logged, including the ones that fail
+		logger = logger.With().Str("attendant", attendant).Logger()
+		logger.Info().Str("ticket_code", ticketCode).Msg("Closing ticket from its code")
+
+		closeTicket(w, r, s, ticketCode, attendant, logger)
+	}
+}
+
+func handleScanTicketExit(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleScanTicketExit").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			utils.RespondWithError(w, "Ticket token is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Reject forged or damaged tickets before touching the session
+		ticketCode, err := s.Tickets.Verify(token)
+		if err != nil {
+			denyGate(s, r, models.GateEvent{Direction: models.GateDirectionExit}, "invalid ticket token")
+			// The signature part is left out of the logs
+			ticketCode, _, _ = strings.Cut(strings.TrimSpace(token), ".")
+			logger.Warn().Str("ticket_code", ticketCode).Msg("Rejected ticket token with invalid signature")
+			utils.RespondWithError(w, "Invalid ticket token", http.StatusForbidden, logger)
+			return
+		}
+
+		closeTicket(w, r, s, ticketCode, "", logger)
+	}
+}
+
+func handleGetTicketQRCode(s *state.State) http.HandlerFunc {
+	return handleGetTicketImage(s, "handleGetTicketQRCode", ticketing.QRCode)
+}
+
+func handleGetTicketBarcode(s *state.State) http.HandlerFunc {
+	return handleGetTicketImage(s, "handleGetTicketBarcode", ticketing.Barcode)
+}
+
+// handleGetTicketImage serves the signed token of an open ticket as a
+// scannable image, rendered as PNG unless format=svg is requested.
+func handleGetTicketImage(s *state.State, handlerName string, renderFn func(token, format string) ([]byte, string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", handlerName).
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		ticketCode := r.URL.Query().Get("ticket_code")
+		if ticketCode == "" {
+			utils.RespondWithError(w, "Ticket code is required", http.StatusBadRequest, logger)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != ticketing.FormatPNG && format != ticketing.FormatSVG {
+			utils.RespondWithError(w, "Format must be png or svg", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Only open tickets can be printed
+		session, err := s.Repository.GetTicketSession(ticketCode)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Ticket not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch ticket")
+			utils.RespondWithError(w, "Failed to fetch ticket", http.StatusInternalServerError, logger)
+			return
+		}
+		if session.ExitedAt != nil {
+			utils.RespondWithError(w, "Ticket is already closed", http.StatusBadRequest, logger)
+			return
+		}
+
+		image, contentType, err := renderFn(s.Tickets.Sign(session.TicketCode), format)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to render ticket")
+			utils.RespondWithError(w, "Failed to render ticket", http.StatusInternalServerError, logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(image); err != nil {
+			logger.Error().Err(err).Msg("Failed to write ticket image")
+		}
+	}
+}
+
+func handleFindLostTicket(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleFindLostTicket").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters, either a plate or a parking lot and slot
+		plate := r.URL.Query().Get("plate")
+		var parkingLotID, relativeSlotID uint64
+		if plate == "" {
+			var err error
+			parkingLotID, err = strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, "Either a plate or a parking lot ID and relative slot ID is required", http.StatusBadRequest, logger)
+				return
+			}
+			relativeSlotID, err = strconv.ParseUint(r.URL.Query().Get("relative_slot_id"), 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, "Invalid relative slot ID", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		// Fetch the open sessions matching the search
+		sessions, err := s.Repository.FindOpenTicketSessions(plate, uint(parkingLotID), uint(relativeSlotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch ticket sessions")
+			utils.RespondWithError(w, "Failed to fetch ticket sessions", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Respond with the open sessions
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Open ticket sessions fetched successfully",
+			Data:    sessions,
+		}, logger)
+	}
+}
+
+func handleCloseLostTicket(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCloseLostTicket").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		ticketSessionID, err := strconv.ParseUint(r.URL.Query().Get("ticket_session_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid ticket session ID", http.StatusBadRequest, logger)
+			return
+		}
+		attendant := r.URL.Query().Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Close the session with the lost-ticket charge
+		session, err := s.Repository.CloseLostTicket(uint(ticketSessionID), attendant)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Ticket session not found", http.StatusNotFound, logger)
+			return
+		}
+		if errors.Is(err, repository.ErrTicketAlreadyClosed) {
+			utils.RespondWithError(w, "Ticket is already closed", http.StatusBadRequest, logger)
+			return
+		}
+		if errors.Is(err, repository.ErrValetTicket) {
+			utils.RespondWithError(w, "Valet tickets are closed by delivering the car", http.StatusConflict, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to close lost ticket")
+			utils.RespondWithError(w, "Failed to close lost ticket", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the lost ticket for the audit trail
+		logger.Info().Uint64("ticket_session_id", ticketSessionID).Str("attendant", attendant).Int("amount", session.TotalAmountToBePaid).Msg("Lost ticket closed successfully")
+
+		// Open the exit gate now that the stay is paid
+		gate := allowGate(s, r, models.GateEvent{
+			Direction:       models.GateDirectionExit,
+			ParkingLotID:    &session.ParkingLotID,
+			TicketSessionID: &session.ID,
+			Operator:        attendant,
+		})
+
+		// Respond with the closed session and parking details
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Lost ticket closed successfully",
+			Data: ticketResponse{
+				TicketSession: session,
+				Gate:          gate,
+			},
+		}, logger)
+	}
+}
+
+func handleGetTicketAuditQueue(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetTicketAuditQueue").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Fetch the sessions flagged for audit review
+		sessions, err := s.Repository.GetTicketSessionsForAudit()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch ticket sessions")
+			utils.RespondWithError(w, "Failed to fetch ticket sessions", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Respond with the flagged sessions
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Ticket sessions for audit review fetched successfully",
+			Data:    sessions,
+		}, logger)
+	}
+}
+
+// ticketResponse is a ticket session plus, on entry, the token encoded in the
+// printed QR code and barcode.
+type ticketResponse struct {
+	*models.TicketSession
+	TicketToken string            `json:"ticket_token,omitempty"`
+	Gate        *models.GateEvent `json:"gate,omitempty"`
+}
+
+// closeTicket ends the walk-in session, opens the exit gate and responds
+// with the amount due.
+func closeTicket(w http.ResponseWriter, r *http.Request, s *state.State, ticketCode, attendant string, logger zerolog.Logger) {
+	// Close the session and charge the parking fee
+	session, err := s.Repository.CloseTicket(ticketCode, attendant)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(w, "Ticket not found", http.StatusNotFound, logger)
+		return
+	}
+	if errors.Is(err, repository.ErrTicketAlreadyClosed) {
+		utils.RespondWithError(w, "Ticket is already closed", http.StatusBadRequest, logger)
+		return
+	}
+	if errors.Is(err, repository.ErrValetTicket) {
+		utils.RespondWithError(w, "Valet tickets are closed by delivering the car", http.StatusConflict, logger)
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to close ticket")
+		utils.RespondWithError(w, "Failed to close ticket", http.StatusInternalServerError, logger)
+		return
+	}
+
+	// Log the successful exit
+	logger.Info().Str("ticket_code", session.TicketCode).Msg("Ticket closed successfully")
+
+	// Open the exit gate now that the stay is paid
+	gate := allowGate(s, r, models.GateEvent{
+		Direction:       models.GateDirectionExit,
+		ParkingLotID:    &session.ParkingLotID,
+		TicketSessionID: &session.ID,
+		Operator:        attendant,
+	})
+
+	// Respond with the closed session and parking details
+	utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+		Code:    "success",
+		Message: "Ticket closed successfully",
+		Data: ticketResponse{
+			TicketSession: session,
+			Gate:          gate,
+		},
+	}, logger)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"net/http"
 	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
 	"parkingManagementSystem/state"
 	"parkingManagementSystem/utils"
 	"strconv"
@@ -28,7 +29,7 @@ func handleCreateUser(s *state.State) http.HandlerFunc {
 			return
 		}
 
-		// Create user using PgRepository
+		// Create user using the repository
 		if err := s.Repository.Create(&user).Error; err != nil {
 			logger.Error().Err(err).Msg("Failed to create user")
 			utils.RespondWithError(w, "Failed to create user", http.StatusInternalServerError, logger)
@@ -70,8 +71,9 @@ func handleCreateCar(s *state.State) http.HandlerFunc {
 
 		// Set userID to the car
 		car.UserID = uint(uid)
+		car.Plate = repository.NormalizePlate(car.Plate)
 
-		// Create car using PgRepository
+		// Create car using the repository
 		if err := s.Repository.Create(&car).Error; err != nil {
 			logger.Error().Err(err).Msg("Failed to create car")
 			utils.RespondWithError(w, "Failed to create car", http.StatusInternalServerError, logger)
@@ -0,0 +1,364 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+	"time"
+)
+
+// valetDelivery is a delivered valet ticket with the amount due for the visit.
+type valetDelivery struct {
+	*models.ValetTicket
+	Charge *models.ParkingCharge `json:"charge"`
+}
+
+func handleParkValetCar(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleParkValetCar").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		parkingLotID, err := strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		keyTag := query.Get("key_tag")
+		if keyTag == "" {
+			utils.RespondWithError(w, "Key tag is required", http.StatusBadRequest, logger)
+			return
+		}
+		attendant := query.Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Park the car and open the valet ticket
+		valetTicket, err := s.Repository.ParkValetCar(uint(parkingLotID), query.Get("plate"), keyTag, attendant)
+		if respondIfEntryRefused(w, err, logger) {
+			return
+		}
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		case errors.Is(err, repository.ErrNoAvailableParkingSlot):
+			respondLotFull(w, r, s, uint(parkingLotID), nil, query.Get("plate"), logger)
+			return
+		case errors.Is(err, repository.ErrNotValetLot), errors.Is(err, repository.ErrKeyTagInUse):
+			utils.RespondWithError(w, err.Error(), http.StatusConflict, logger)
+			return
+		case err != nil:
+			logger.Error().Err(err).Msg("Failed to park valet car")
+			utils.RespondWithError(w, "Failed to park valet car", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the car and the attendant who parked it
+		logger.Info().Uint("valet_ticket_id", valetTicket.ID).Str("key_tag", keyTag).Str("attendant", attendant).Msg("Valet car parked successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet car parked successfully",
+			Data:    valetTicket,
+		}, logger)
+	}
+}
+
+func handleGetValetTicket(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetValetTicket").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		claimCode := r.URL.Query().Get("claim_code")
+		if claimCode == "" {
+			utils.RespondWithError(w, "Claim code is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		valetTicket, err := s.Repository.GetValetTicket(claimCode)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Valet ticket not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch valet ticket")
+			utils.RespondWithError(w, "Failed to fetch valet ticket", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet ticket fetched successfully",
+			Data:    valetTicket,
+		}, logger)
+	}
+}
+
+func handleRequestValetCar(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleRequestValetCar").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters, the pickup time defaults to now
+		query := r.URL.Query()
+		claimCode := query.Get("claim_code")
+		if claimCode == "" {
+			utils.RespondWithError(w, "Claim code is required", http.StatusBadRequest, logger)
+			return
+		}
+		pickupAt := time.Now()
+		if value := query.Get("pickup_at"); value != "" {
+			var err error
+			pickupAt, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				utils.RespondWithError(w, "Invalid pickup time", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		valetTicket, err := s.Repository.RequestValetCar(claimCode, pickupAt)
+		if respondIfValetStepFailed(w, err, "Failed to request valet car", logger) {
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet car requested successfully",
+			Data:    valetTicket,
+		}, logger)
+	}
+}
+
+func handleGetValetQueue(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetValetQueue").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		parkingLotID, err := strconv.ParseUint(r.URL.Query().Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		valetTickets, err := s.Repository.GetValetQueue(uint(parkingLotID))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch valet queue")
+			utils.RespondWithError(w, "Failed to fetch valet queue", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet queue fetched successfully",
+			Data:    valetTickets,
+		}, logger)
+	}
+}
+
+func handleStartValetRetrieval(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleStartValetRetrieval").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		valetTicketID, err := strconv.ParseUint(query.Get("valet_ticket_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid valet ticket ID", http.StatusBadRequest, logger)
+			return
+		}
+		attendant := query.Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+		etaMinutes := 0
+		if value := query.Get("eta_minutes"); value != "" {
+			etaMinutes, err = strconv.Atoi(value)
+			if err != nil || etaMinutes < 0 {
+				utils.RespondWithError(w, "Invalid ETA minutes", http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		valetTicket, err := s.Repository.StartValetRetrieval(uint(valetTicketID), attendant, time.Duration(etaMinutes)*time.Minute)
+		if respondIfValetStepFailed(w, err, "Failed to start valet retrieval", logger) {
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet retrieval started successfully",
+			Data:    valetTicket,
+		}, logger)
+	}
+}
+
+func handleMarkValetCarReady(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleMarkValetCarReady").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		valetTicketID, err := strconv.ParseUint(r.URL.Query().Get("valet_ticket_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid valet ticket ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		valetTicket, err := s.Repository.MarkValetCarReady(uint(valetTicketID))
+		if respondIfValetStepFailed(w, err, "Failed to mark valet car ready", logger) {
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet car marked ready successfully",
+			Data:    valetTicket,
+		}, logger)
+	}
+}
+
+func handleDeliverValetCar(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleDeliverValetCar").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		valetTicketID, err := strconv.ParseUint(query.Get("valet_ticket_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid valet ticket ID", http.StatusBadRequest, logger)
+			return
+		}
+		attendant := query.Get("attendant")
+		if attendant == "" {
+			utils.RespondWithError(w, "Attendant is required", http.StatusBadRequest, logger)
+			return
+		}
+
+		// Close the visit and charge it with the valet fee
+		valetTicket, charge, err := s.Repository.DeliverValetCar(uint(valetTicketID), attendant)
+		if errors.Is(err, repository.ErrTicketAlreadyClosed) {
+			utils.RespondWithError(w, "Valet car is already delivered", http.StatusConflict, logger)
+			return
+		}
+		if respondIfValetStepFailed(w, err, "Failed to deliver valet car", logger) {
+			return
+		}
+
+		// Log the delivery
+		logger.Info().Uint("valet_ticket_id", valetTicket.ID).Str("attendant", attendant).Int("amount", charge.TotalAmountToBePaid).Msg("Valet car delivered successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet car delivered successfully",
+			Data:    valetDelivery{ValetTicket: valetTicket, Charge: charge},
+		}, logger)
+	}
+}
+
+func handleUpdateValetSettings(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleUpdateValetSettings").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		query := r.URL.Query()
+		parkingLotID, err := strconv.ParseUint(query.Get("parking_lot_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid parking lot ID", http.StatusBadRequest, logger)
+			return
+		}
+		valetMode, err := strconv.ParseBool(query.Get("valet_mode"))
+		if err != nil {
+			utils.RespondWithError(w, "Invalid valet mode", http.StatusBadRequest, logger)
+			return
+		}
+		valetFee, err := strconv.Atoi(query.Get("valet_fee"))
+		if err != nil || valetFee < 0 {
+			utils.RespondWithError(w, "Invalid valet fee", http.StatusBadRequest, logger)
+			return
+		}
+		retrievalMinutes, err := strconv.Atoi(query.Get("valet_retrieval_minutes"))
+		if err != nil || retrievalMinutes < 0 {
+			utils.RespondWithError(w, "Invalid valet retrieval minutes", http.StatusBadRequest, logger)
+			return
+		}
+
+		parkingLot, err := s.Repository.UpdateValetSettings(uint(parkingLotID), valetMode, valetFee, retrievalMinutes)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Parking lot not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to update valet settings")
+			utils.RespondWithError(w, "Failed to update valet settings", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new settings
+		logger.Info().Uint("parking_lot_id", parkingLot.ID).Bool("valet_mode", parkingLot.ValetMode).Msg("Valet settings updated successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Valet settings updated successfully",
+			Data:    parkingLot,
+		}, logger)
+	}
+}
+
+// respondIfValetStepFailed responds to a failed step of a valet ticket and
+// reports whether it did.
+func respondIfValetStepFailed(w http.ResponseWriter, err error, message string, logger zerolog.Logger) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.RespondWithError(w, "Valet ticket not found", http.StatusNotFound, logger)
+	case errors.Is(err, repository.ErrValetTicketSequence):
+		utils.RespondWithError(w, err.Error(), http.StatusConflict, logger)
+	default:
+		logger.Error().Err(err).Msg(message)
+		utils.RespondWithError(w, message, http.StatusInternalServerError, logger)
+	}
+	return true
+}
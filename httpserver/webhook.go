@@ -0,0 +1,216 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/state"
+	"parkingManagementSystem/utils"
+	"strconv"
+)
+
+// webhookDeliveriesLimit is the number of deliveries returned from the log.
+const webhookDeliveriesLimit = 100
+
+var webhookEventTypes = map[string]bool{
+	models.WebhookEventCarParked:       true,
+	models.WebhookEventCarUnparked:     true,
+	models.WebhookEventSlotMaintenance: true,
+	models.WebhookEventLotFull:         true,
+	models.WebhookEventCarRelocated:    true,
+}
+
+func handleCreateWebhookSubscription(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleCreateWebhookSubscription").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		var sub models.WebhookSubscription
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			logger.Error().Err(err).Msg("Failed to decode request body")
+			utils.RespondWithError(w, "Failed to decode request body", http.StatusBadRequest, logger)
+			return
+		}
+
+		endpoint, err := url.Parse(sub.URL)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			utils.RespondWithError(w, "URL must be an absolute http or https URL", http.StatusBadRequest, logger)
+			return
+		}
+		for _, eventType := range sub.EventTypes {
+			if !webhookEventTypes[eventType] {
+				utils.RespondWithError(w, "Unknown event type "+eventType, http.StatusBadRequest, logger)
+				return
+			}
+		}
+
+		// Generate a signing secret unless the subscriber brought one
+		if sub.Secret == "" {
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				logger.Error().Err(err).Msg("Failed to generate webhook secret")
+				utils.RespondWithError(w, "Failed to create webhook subscription", http.StatusInternalServerError, logger)
+				return
+			}
+			sub.Secret = hex.EncodeToString(secret)
+		}
+		sub.ID = 0
+		sub.IsActive = true
+
+		if err := s.Repository.CreateWebhookSubscription(&sub); err != nil {
+			logger.Error().Err(err).Msg("Failed to create webhook subscription")
+			utils.RespondWithError(w, "Failed to create webhook subscription", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the new subscription
+		logger.Info().Uint("subscription_id", sub.ID).Str("url", sub.URL).Msg("Webhook subscription created successfully")
+
+		// Respond with the subscription, the only time the secret is shown
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Webhook subscription created successfully",
+			Data:    sub,
+		}, logger)
+	}
+}
+
+func handleGetWebhookSubscriptions(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetWebhookSubscriptions").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		subs, err := s.Repository.GetWebhookSubscriptions()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch webhook subscriptions")
+			utils.RespondWithError(w, "Failed to fetch webhook subscriptions", http.StatusInternalServerError, logger)
+			return
+		}
+		for i := range subs {
+			subs[i].Secret = ""
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Webhook subscriptions fetched successfully",
+			Data:    subs,
+		}, logger)
+	}
+}
+
+func handleDeactivateWebhookSubscription(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleDeactivateWebhookSubscription").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		subscriptionID, err := strconv.ParseUint(r.URL.Query().Get("subscription_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid subscription ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		err = s.Repository.DeactivateWebhookSubscription(uint(subscriptionID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Webhook subscription not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to deactivate webhook subscription")
+			utils.RespondWithError(w, "Failed to deactivate webhook subscription", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the deactivation
+		logger.Info().Uint64("subscription_id", subscriptionID).Msg("Webhook subscription deactivated successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Webhook subscription deactivated successfully",
+			Data:    nil,
+		}, logger)
+	}
+}
+
+func handleGetWebhookDeliveries(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleGetWebhookDeliveries").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		subscriptionID, err := strconv.ParseUint(r.URL.Query().Get("subscription_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid subscription ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		deliveries, err := s.Repository.GetWebhookDeliveries(uint(subscriptionID), webhookDeliveriesLimit)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to fetch webhook deliveries")
+			utils.RespondWithError(w, "Failed to fetch webhook deliveries", http.StatusInternalServerError, logger)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Webhook deliveries fetched successfully",
+			Data:    deliveries,
+		}, logger)
+	}
+}
+
+func handleRedeliverWebhook(s *state.State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.With().
+			Str("handler", "handleRedeliverWebhook").
+			Str("request_id", middleware.GetReqID(ctx)).
+			Logger()
+
+		// Parse request parameters
+		deliveryID, err := strconv.ParseUint(r.URL.Query().Get("delivery_id"), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, "Invalid delivery ID", http.StatusBadRequest, logger)
+			return
+		}
+
+		delivery, err := s.Repository.RedeliverWebhook(uint(deliveryID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, "Webhook delivery not found", http.StatusNotFound, logger)
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to queue webhook redelivery")
+			utils.RespondWithError(w, "Failed to queue webhook redelivery", http.StatusInternalServerError, logger)
+			return
+		}
+
+		// Log the redelivery
+		logger.Info().Uint64("delivery_id", deliveryID).Msg("Webhook redelivery queued successfully")
+
+		utils.RespondWithJSON(w, http.StatusOK, utils.CommonResponse{
+			Code:    "success",
+			Message: "Webhook redelivery queued successfully",
+			Data:    delivery,
+		}, logger)
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
+	"os"
+	"os/signal"
 	"parkingManagementSystem/config"
 	"parkingManagementSystem/httpserver"
 	"parkingManagementSystem/state"
+	"syscall"
 )
 
 func main() {
@@ -16,6 +20,34 @@ func main() {
 		log.Fatal().Err(err).Msg("Config parsing failed")
 	}
 
+	// Schema migrations run without starting the service
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
 	appState := state.NewState(cfg)
-	httpserver.Serve(appState)
+
+	// SIGTERM and SIGINT stop the jobs and drain the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Background jobs
+	go appState.Sensors.Run(ctx)
+	go appState.Webhooks.Run(ctx)
+	go appState.Alerts.Run(ctx)
+	go appState.Overstays.Run(ctx)
+	go appState.Maintenance.Run(ctx)
+	if appState.Sync != nil {
+		go appState.Sync.Run(ctx)
+	}
+
+	if err := httpserver.Serve(ctx, appState); err != nil {
+		log.Fatal().Err(err).Msg("http server error")
+	}
+
+	// Wait for the queries still running before exiting
+	if sqlDB, err := appState.Repository.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
 }
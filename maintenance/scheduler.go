@@ -0,0 +1,52 @@
+package maintenance
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"time"
+)
+
+// Scheduler periodically starts and ends the maintenance work orders, and
+// puts slots that were occupied at the start in maintenance once they are free.
+type Scheduler struct {
+	repo     *repository.Repository
+	interval time.Duration
+}
+
+func NewScheduler(repo *repository.Repository, interval time.Duration) *Scheduler {
+	return &Scheduler{repo: repo, interval: interval}
+}
+
+// Run applies the schedule every interval until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Apply(); err != nil {
+				log.Error().Err(err).Msg("maintenance schedule failed")
+			}
+		}
+	}
+}
+
+// Apply runs a single pass and returns the maintenance events it recorded.
+func (s *Scheduler) Apply() ([]models.MaintenanceEvent, error) {
+	maintenanceEvents, err := s.repo.RunMaintenanceSchedule(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, maintenanceEvent := range maintenanceEvents {
+		log.Info().
+			Uint("parking_slot_id", maintenanceEvent.ParkingSlotID).
+			Uint("work_order_id", *maintenanceEvent.WorkOrderID).
+			Str("kind", maintenanceEvent.Kind).
+			Msg("maintenance work order applied")
+	}
+	return maintenanceEvents, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"os"
+	"parkingManagementSystem/config"
+	"parkingManagementSystem/migrations"
+	"parkingManagementSystem/repository"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: parkingManagementSystem migrate up|down|status"
+
+// runMigrate runs the migrate subcommand against the configured database.
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	db, err := repository.NewRepository(cfg.DatabaseUrl)
+	if err != nil {
+		log.Fatal().Err(err).Msg("repository error")
+	}
+	migrator, err := db.SchemaMigrator()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to apply migrations")
+		}
+		fmt.Printf("applied %d migrations\n", applied)
+	case "down":
+		reverted, err := migrator.Down()
+		if errors.Is(err, migrations.ErrNoMigrationApplied) {
+			fmt.Println("no migration to revert")
+			return
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to revert migration")
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read migration status")
+		}
+		printMigrationStatus(statuses)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
+
+func printMigrationStatus(statuses []migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,184 @@
+package migrations
+
+import (
+	"gorm.io/gorm/schema"
+	"parkingManagementSystem/models"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range []string{Postgres, SQLite} {
+		t.Run(dialect, func(t *testing.T) {
+			migrations, err := Load(dialect)
+			if err != nil {
+				t.Fatalf("Load(%s): %v", dialect, err)
+			}
+			if len(migrations) == 0 {
+				t.Fatalf("Load(%s) returned no migrations", dialect)
+			}
+			for i, migration := range migrations {
+				if migration.Version != i+1 {
+					t.Errorf("migration %d_%s: want version %d, versions must have no gaps", migration.Version, migration.Name, i+1)
+				}
+				if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+					t.Errorf("migration %d_%s: empty up or down script", migration.Version, migration.Name)
+				}
+			}
+		})
+	}
+
+	if _, err := Load("mysql"); err == nil {
+		t.Error("Load(mysql) succeeded, want an error for an unknown dialect")
+	}
+}
+
+func TestDialectsHaveTheSameMigrations(t *testing.T) {
+	postgres, err := Load(Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Load(SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("%d PostgreSQL migrations, %d SQLite migrations", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d: %d_%s in PostgreSQL, %d_%s in SQLite",
+				i, postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+func TestDialectsDefineTheSameColumns(t *testing.T) {
+	postgres := columnsAfterUp(t, Postgres)
+	sqlite := columnsAfterUp(t, SQLite)
+	for _, column := range difference(postgres, sqlite) {
+		t.Errorf("%s is only in the PostgreSQL migrations", column)
+	}
+	for _, column := range difference(sqlite, postgres) {
+		t.Errorf("%s is only in the SQLite migrations", column)
+	}
+}
+
+// TestModelsMatchColumns checks that every column GORM reads or writes for a
+// model is created by the migrations.
+func TestModelsMatchColumns(t *testing.T) {
+	columns := columnsAfterUp(t, Postgres)
+	tables := map[string]bool{}
+	for column := range columns {
+		table, _, _ := strings.Cut(column, ".")
+		tables[table] = true
+	}
+
+	stored := []interface{}{
+		&models.User{}, &models.Car{}, &models.ParkingLot{}, &models.ParkingSlot{}, &models.ParkingHistory{},
+		&models.TicketSession{}, &models.GateEvent{}, &models.CameraLane{}, &models.AnprEvent{},
+		&models.SlotSensorState{}, &models.SensorDiscrepancy{}, &models.WebhookSubscription{},
+		&models.OutboxEvent{}, &models.WebhookDelivery{}, &models.CapacityThreshold{}, &models.CapacityAlert{},
+		&models.OverstayViolation{}, &models.AccessListEntry{}, &models.BlockedEntry{},
+		&models.MaintenanceWorkOrder{}, &models.MaintenanceWorkOrderSlot{}, &models.MaintenanceEvent{},
+		&models.OpeningHours{}, &models.LotExceptionDate{}, &models.SlotHold{}, &models.OverflowRedirect{},
+		&models.ChargingSession{}, &models.ValetTicket{}, &models.SlotMove{},
+		&models.EdgeLotLink{}, &models.SyncedEvent{}, &models.SyncConflict{},
+	}
+	cache := &sync.Map{}
+	for _, model := range stored {
+		s, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tables[s.Table] {
+			t.Errorf("model %s: table %s is not created by the migrations", s.Name, s.Table)
+			continue
+		}
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !columns[s.Table+"."+field.DBName] {
+				t.Errorf("model %s: column %s.%s is not created by the migrations", s.Name, s.Table, field.DBName)
+			}
+		}
+	}
+}
+
+var (
+	createTablePattern  = regexp.MustCompile(`(?is)^CREATE TABLE IF NOT EXISTS (\w+) \((.*)\)$`)
+	addColumnPattern    = regexp.MustCompile(`(?i)^ALTER TABLE (\w+) ADD COLUMN (?:IF NOT EXISTS )?(\w+)`)
+	dropColumnPattern   = regexp.MustCompile(`(?i)^ALTER TABLE (\w+) DROP COLUMN (?:IF EXISTS )?(\w+)`)
+	renameColumnPattern = regexp.MustCompile(`(?i)^ALTER TABLE (\w+) RENAME COLUMN (\w+) TO (\w+)`)
+	dropTablePattern    = regexp.MustCompile(`(?i)^DROP TABLE IF EXISTS (\w+)$`)
+)
+
+// columnsAfterUp returns the table.column names created by applying every up
+// script of the dialect in order.
+func columnsAfterUp(t *testing.T, dialect string) map[string]bool {
+	t.Helper()
+	migrations, err := Load(dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := map[string]bool{}
+	for _, migration := range migrations {
+		for _, statement := range statements(migration.Up) {
+			if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+				for _, definition := range strings.Split(match[2], "\n") {
+					name := strings.Fields(definition)
+					if len(name) == 0 || strings.EqualFold(name[0], "PRIMARY") || strings.EqualFold(name[0], "CONSTRAINT") {
+						continue
+					}
+					columns[match[1]+"."+name[0]] = true
+				}
+			} else if match := addColumnPattern.FindStringSubmatch(statement); match != nil {
+				columns[match[1]+"."+match[2]] = true
+			} else if match := dropColumnPattern.FindStringSubmatch(statement); match != nil {
+				delete(columns, match[1]+"."+match[2])
+			} else if match := renameColumnPattern.FindStringSubmatch(statement); match != nil {
+				delete(columns, match[1]+"."+match[2])
+				columns[match[1]+"."+match[3]] = true
+			} else if match := dropTablePattern.FindStringSubmatch(statement); match != nil {
+				for column := range columns {
+					if strings.HasPrefix(column, match[1]+".") {
+						delete(columns, column)
+					}
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// statements splits a script into its statements, without comments.
+func statements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	var result []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			result = append(result, statement)
+		}
+	}
+	return result
+}
+
+func difference(a, b map[string]bool) []string {
+	var only []string
+	for column := range a {
+		if !b[column] {
+			only = append(only, column)
+		}
+	}
+	sort.Strings(only)
+	return only
+}
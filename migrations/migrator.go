@@ -0,0 +1,212 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+// advisoryLockKey identifies the PostgreSQL advisory lock held while
+// migrating, so replicas starting together migrate one after the other.
+const advisoryLockKey = 727_105_046
+
+// createSchemaMigrations creates the table recording the applied versions.
+var createSchemaMigrations = map[string]string{
+	Postgres: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    name text NOT NULL,
+    applied_at timestamptz NOT NULL
+)`,
+	SQLite: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version integer PRIMARY KEY,
+    name text NOT NULL,
+    applied_at datetime NOT NULL
+)`,
+}
+
+// ErrNoMigrationApplied is returned when reverting with nothing applied.
+var ErrNoMigrationApplied = errors.New("no migration is applied")
+
+// SchemaMigration is a row of the schema_migrations table.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator applies and reverts the migrations of a dialect.
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, dialect string) (*Migrator, error) {
+	if _, ok := createSchemaMigrations[dialect]; !ok {
+		return nil, fmt.Errorf("no migrations for dialect %s", dialect)
+	}
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns the number applied.
+func (m *Migrator) Up() (int, error) {
+	applied := 0
+	err := m.locked(func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			skipped := false
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				// Another process may have applied it since the versions were read
+				var count int64
+				if err := tx.Model(&SchemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					skipped = true
+					return nil
+				}
+
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if skipped {
+				continue
+			}
+			log.Info().Int("version", migration.Version).Str("name", migration.Name).Msg("Migration applied")
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest applied migration.
+func (m *Migrator) Down() (*Migration, error) {
+	var reverted *Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		var latest SchemaMigration
+		err := conn.Order("version DESC").First(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoMigrationApplied
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range m.migrations {
+			if m.migrations[i].Version == latest.Version {
+				reverted = &m.migrations[i]
+			}
+		}
+		if reverted == nil {
+			return fmt.Errorf("migration %d_%s is applied but unknown to this build", latest.Version, latest.Name)
+		}
+
+		err = conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(reverted.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&latest).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", reverted.Version, reverted.Name, err)
+		}
+		log.Info().Int("version", reverted.Version).Str("name", reverted.Name).Msg("Migration reverted")
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied. It only
+// reads the database, which has every migration pending until the first one
+// creates the schema_migrations table.
+func (m *Migrator) Status() ([]Status, error) {
+	done := map[int]time.Time{}
+	if m.db.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if done, err = appliedVersions(m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the number of migrations not applied yet, without writing
+// to the database.
+func (m *Migrator) Pending() (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// locked runs fn on a single connection holding the migration lock. On
+// PostgreSQL the lock is an advisory lock taken on the session, so it is
+// released with the connection even if the process dies. SQLite has a single
+// writer, so there each migration's transaction is enough.
+func (m *Migrator) locked(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if m.dialect == Postgres {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockKey).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey)
+		}
+
+		if err := conn.Exec(createSchemaMigrations[m.dialect]).Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func appliedVersions(db *gorm.DB) (map[int]time.Time, error) {
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
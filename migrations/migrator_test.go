@@ -0,0 +1,100 @@
+package migrations
+
+import (
+	"errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "pms.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	db := openSQLite(t)
+	migrator, err := NewMigrator(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := len(migrator.migrations)
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if applied != all {
+		t.Errorf("Up applied %d migrations, want %d", applied, all)
+	}
+	if applied, err := migrator.Up(); err != nil || applied != 0 {
+		t.Errorf("second Up = %d, %v, want 0, nil", applied, err)
+	}
+
+	// Every down script must revert its migration, down to an empty database
+	for i := all; i > 0; i-- {
+		reverted, err := migrator.Down()
+		if err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		if reverted.Version != i {
+			t.Errorf("Down reverted version %d, want %d", reverted.Version, i)
+		}
+	}
+	if _, err := migrator.Down(); !errors.Is(err, ErrNoMigrationApplied) {
+		t.Errorf("Down on an empty database: %v, want ErrNoMigrationApplied", err)
+	}
+	if db.Migrator().HasTable("parking_slots") {
+		t.Error("parking_slots still exists after reverting every migration")
+	}
+
+	if applied, err := migrator.Up(); err != nil || applied != all {
+		t.Errorf("Up after reverting = %d, %v, want %d, nil", applied, err, all)
+	}
+}
+
+func TestPendingIsReadOnly(t *testing.T) {
+	db := openSQLite(t)
+	migrator, err := NewMigrator(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := len(migrator.migrations)
+
+	if pending, err := migrator.Pending(); err != nil || pending != all {
+		t.Errorf("Pending on an empty database = %d, %v, want %d, nil", pending, err, all)
+	}
+	if _, err := migrator.Status(); err != nil {
+		t.Errorf("Status on an empty database: %v", err)
+	}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		t.Error("Pending or Status created schema_migrations")
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if pending, err := migrator.Pending(); err != nil || pending != 0 {
+		t.Errorf("Pending after Up = %d, %v, want 0, nil", pending, err)
+	}
+	if _, err := migrator.Down(); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if pending, err := migrator.Pending(); err != nil || pending != 1 {
+		t.Errorf("Pending after Down = %d, %v, want 1, nil", pending, err)
+	}
+}
+
+func TestNewMigratorUnknownDialect(t *testing.T) {
+	if _, err := NewMigrator(openSQLite(t), "mysql"); err == nil {
+		t.Error("NewMigrator(mysql) succeeded, want an error")
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS parking_histories;
+DROP TABLE IF EXISTS parking_lots;
+DROP TABLE IF EXISTS parking_slots;
+DROP TABLE IF EXISTS cars;
+DROP TABLE IF EXISTS users;
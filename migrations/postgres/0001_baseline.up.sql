@@ -0,0 +1,45 @@
+-- Baseline: the schema of the original release, created by GORM
+-- AutoMigrate before versioned migrations. Tables and indexes are only
+-- created if missing, so databases of that release adopt it as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial,
+    name text,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS cars (
+    id bigserial,
+    user_id bigint,
+    parking_slot_id bigint,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_cars FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS parking_slots (
+    id bigserial,
+    parking_lot_id bigint,
+    relative_id bigint,
+    is_booked boolean DEFAULT false,
+    is_in_maintenance boolean DEFAULT false,
+    car_id bigint,
+    parked_at timestamptz,
+    unparked_at timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS parking_lots (
+    id bigserial,
+    location text,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS parking_histories (
+    date timestamptz,
+    cars_parked bigint,
+    total_parking_time bigint DEFAULT 0,
+    total_revenue_earned bigint DEFAULT 0,
+    PRIMARY KEY (date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_parking_slot_composite ON parking_slots (parking_lot_id, is_booked, relative_id);
@@ -0,0 +1,65 @@
+DROP TABLE IF EXISTS slot_moves;
+DROP TABLE IF EXISTS valet_tickets;
+DROP TABLE IF EXISTS charging_sessions;
+DROP TABLE IF EXISTS overflow_redirects;
+DROP TABLE IF EXISTS slot_holds;
+DROP TABLE IF EXISTS lot_exception_dates;
+DROP TABLE IF EXISTS opening_hours;
+DROP TABLE IF EXISTS maintenance_events;
+DROP TABLE IF EXISTS maintenance_work_order_slots;
+DROP TABLE IF EXISTS maintenance_work_orders;
+DROP TABLE IF EXISTS blocked_entries;
+DROP TABLE IF EXISTS access_list_entries;
+DROP TABLE IF EXISTS overstay_violations;
+DROP TABLE IF EXISTS capacity_alerts;
+DROP TABLE IF EXISTS capacity_thresholds;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS sensor_discrepancies;
+DROP TABLE IF EXISTS slot_sensor_states;
+DROP TABLE IF EXISTS anpr_events;
+DROP TABLE IF EXISTS camera_lanes;
+DROP TABLE IF EXISTS gate_events;
+DROP TABLE IF EXISTS ticket_sessions;
+DROP INDEX IF EXISTS idx_cars_plate;
+DROP INDEX IF EXISTS idx_parking_slots_decommissioned_at;
+DROP INDEX IF EXISTS idx_parking_slots_label;
+DROP INDEX IF EXISTS idx_parking_slots_zone;
+DROP INDEX IF EXISTS idx_parking_lots_operator;
+ALTER TABLE parking_histories DROP COLUMN IF EXISTS valet_revenue;
+ALTER TABLE parking_histories DROP COLUMN IF EXISTS energy_revenue;
+ALTER TABLE parking_histories DROP COLUMN IF EXISTS overstay_fine_revenue;
+ALTER TABLE parking_histories DROP COLUMN IF EXISTS lost_ticket_revenue;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS valet_retrieval_minutes;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS valet_fee;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS valet_mode;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS charging_billing;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS idle_grace_minutes;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS idle_fee_per_hour;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS energy_rate_per_kwh;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS overnight_fee;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS timezone;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS overstay_escalation_minutes;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS overstay_fine;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS max_stay_minutes;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS lost_ticket_fee;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS lost_ticket_policy;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS longitude;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS latitude;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS address_country;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS address_postal_code;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS address_city;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS address_street;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS operator;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS description;
+ALTER TABLE parking_lots DROP COLUMN IF EXISTS name;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS decommissioned_at;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS is_draining;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS ticket_session_id;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS charger_power_kw;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS charger_connector;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS label;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS zone;
+ALTER TABLE parking_slots DROP COLUMN IF EXISTS slot_type;
+ALTER TABLE cars DROP COLUMN IF EXISTS plate;
@@ -0,0 +1,411 @@
+-- Features: the tables and columns added since the original release.
+-- Everything is only added if missing, so databases that AutoMigrate already
+-- brought further adopt it as they are.
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS plate text;
+CREATE INDEX IF NOT EXISTS idx_cars_plate ON cars (plate);
+
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS slot_type text DEFAULT 'standard';
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS zone text;
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS label text;
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS charger_connector text;
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS charger_power_kw decimal;
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS ticket_session_id bigint;
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS is_draining boolean DEFAULT false;
+ALTER TABLE parking_slots ADD COLUMN IF NOT EXISTS decommissioned_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_parking_slots_decommissioned_at ON parking_slots (decommissioned_at);
+CREATE INDEX IF NOT EXISTS idx_parking_slots_label ON parking_slots (label);
+CREATE INDEX IF NOT EXISTS idx_parking_slots_zone ON parking_slots (zone);
+
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS name text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS description text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS operator text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS address_street text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS address_city text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS address_postal_code text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS address_country text;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS latitude decimal;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS longitude decimal;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS lost_ticket_policy text DEFAULT 'flat_fee';
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS lost_ticket_fee bigint DEFAULT 100;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS max_stay_minutes bigint DEFAULT 0;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS overstay_fine bigint DEFAULT 50;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS overstay_escalation_minutes bigint DEFAULT 60;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS timezone text DEFAULT 'UTC';
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS overnight_fee bigint DEFAULT 0;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS energy_rate_per_kwh decimal DEFAULT 0.5;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS idle_fee_per_hour bigint DEFAULT 10;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS idle_grace_minutes bigint DEFAULT 15;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS charging_billing text DEFAULT 'energy_and_time';
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS valet_mode boolean DEFAULT false;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS valet_fee bigint DEFAULT 25;
+ALTER TABLE parking_lots ADD COLUMN IF NOT EXISTS valet_retrieval_minutes bigint DEFAULT 10;
+CREATE INDEX IF NOT EXISTS idx_parking_lots_operator ON parking_lots (operator);
+
+ALTER TABLE parking_histories ADD COLUMN IF NOT EXISTS lost_ticket_revenue bigint DEFAULT 0;
+ALTER TABLE parking_histories ADD COLUMN IF NOT EXISTS overstay_fine_revenue bigint DEFAULT 0;
+ALTER TABLE parking_histories ADD COLUMN IF NOT EXISTS energy_revenue bigint DEFAULT 0;
+ALTER TABLE parking_histories ADD COLUMN IF NOT EXISTS valet_revenue bigint DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS ticket_sessions (
+    id bigserial,
+    ticket_code text NOT NULL,
+    plate text,
+    parking_lot_id bigint,
+    parking_slot_id bigint,
+    relative_slot_id bigint,
+    entered_at timestamptz,
+    exited_at timestamptz,
+    total_parking_time bigint,
+    total_amount_to_be_paid bigint,
+    is_lost_ticket boolean DEFAULT false,
+    handled_by text,
+    needs_audit_review boolean DEFAULT false,
+    is_valet boolean DEFAULT false,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_sessions_plate ON ticket_sessions (plate);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_sessions_ticket_code ON ticket_sessions (ticket_code);
+CREATE INDEX IF NOT EXISTS idx_ticket_sessions_needs_audit_review ON ticket_sessions (needs_audit_review);
+
+CREATE TABLE IF NOT EXISTS gate_events (
+    id bigserial,
+    gate_id text,
+    direction text,
+    decision text,
+    reason text,
+    parking_lot_id bigint,
+    car_id bigint,
+    ticket_session_id bigint,
+    operator text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_gate_events_gate_id ON gate_events (gate_id);
+
+CREATE TABLE IF NOT EXISTS camera_lanes (
+    lane_id text,
+    parking_lot_id bigint,
+    direction text,
+    gate_id text,
+    PRIMARY KEY (lane_id)
+);
+
+CREATE TABLE IF NOT EXISTS anpr_events (
+    id bigserial,
+    plate text,
+    confidence decimal,
+    lane_id text,
+    captured_at timestamptz,
+    direction text,
+    parking_lot_id bigint,
+    status text,
+    reason text,
+    car_id bigint,
+    ticket_session_id bigint,
+    amount_to_be_paid bigint,
+    reviewed_by text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_anpr_events_status ON anpr_events (status);
+CREATE INDEX IF NOT EXISTS idx_anpr_events_lane_id ON anpr_events (lane_id);
+CREATE INDEX IF NOT EXISTS idx_anpr_events_plate ON anpr_events (plate);
+
+CREATE TABLE IF NOT EXISTS slot_sensor_states (
+    parking_slot_id bigint,
+    occupied boolean,
+    changed_at timestamptz,
+    reported_at timestamptz,
+    PRIMARY KEY (parking_slot_id)
+);
+
+CREATE TABLE IF NOT EXISTS sensor_discrepancies (
+    id bigserial,
+    parking_slot_id bigint,
+    parking_lot_id bigint,
+    relative_id bigint,
+    kind text,
+    detected_at timestamptz,
+    resolved_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sensor_discrepancies_parking_lot_id ON sensor_discrepancies (parking_lot_id);
+CREATE INDEX IF NOT EXISTS idx_sensor_discrepancies_parking_slot_id ON sensor_discrepancies (parking_slot_id);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id bigserial,
+    parking_lot_id bigint,
+    url text,
+    secret text,
+    event_types text,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_parking_lot_id ON webhook_subscriptions (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id bigserial,
+    event_type text,
+    parking_lot_id bigint,
+    payload text,
+    created_at timestamptz,
+    dispatched_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dispatched_at ON outbox_events (dispatched_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial,
+    subscription_id bigint,
+    outbox_event_id bigint,
+    event_type text,
+    status text,
+    attempts bigint,
+    last_status_code bigint,
+    last_error text,
+    next_attempt_at timestamptz,
+    delivered_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id),
+    CONSTRAINT fk_webhook_deliveries_outbox_event FOREIGN KEY (outbox_event_id) REFERENCES outbox_events(id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+
+CREATE TABLE IF NOT EXISTS capacity_thresholds (
+    id bigserial,
+    parking_lot_id bigint,
+    percent bigint,
+    hysteresis_percent bigint,
+    is_triggered boolean DEFAULT false,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_capacity_thresholds_parking_lot_id ON capacity_thresholds (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS capacity_alerts (
+    id bigserial,
+    parking_lot_id bigint,
+    threshold_id bigint,
+    threshold_percent bigint,
+    kind text,
+    occupied_slots bigint,
+    available_slots bigint,
+    occupancy_percent decimal,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_capacity_alerts_parking_lot_id ON capacity_alerts (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS overstay_violations (
+    id bigserial,
+    parking_lot_id bigint,
+    parking_slot_id bigint,
+    relative_id bigint,
+    car_id bigint,
+    ticket_session_id bigint,
+    parked_at timestamptz,
+    detected_at timestamptz,
+    level bigint,
+    fine_amount bigint,
+    status text,
+    paid_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_overstay_violations_parking_lot_id ON overstay_violations (parking_lot_id);
+CREATE INDEX IF NOT EXISTS idx_overstay_violations_status ON overstay_violations (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_overstay_visit ON overstay_violations (parking_slot_id,parked_at);
+
+CREATE TABLE IF NOT EXISTS access_list_entries (
+    id bigserial,
+    list_type text,
+    plate text,
+    user_id bigint,
+    parking_lot_id bigint,
+    reason text,
+    expires_at timestamptz,
+    created_by text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_access_list_entries_plate ON access_list_entries (plate);
+CREATE INDEX IF NOT EXISTS idx_access_list_entries_list_type ON access_list_entries (list_type);
+CREATE INDEX IF NOT EXISTS idx_access_list_entries_user_id ON access_list_entries (user_id);
+
+CREATE TABLE IF NOT EXISTS blocked_entries (
+    id bigserial,
+    access_list_entry_id bigint,
+    parking_lot_id bigint,
+    plate text,
+    car_id bigint,
+    user_id bigint,
+    reason text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_blocked_entries_parking_lot_id ON blocked_entries (parking_lot_id);
+CREATE INDEX IF NOT EXISTS idx_blocked_entries_access_list_entry_id ON blocked_entries (access_list_entry_id);
+
+CREATE TABLE IF NOT EXISTS maintenance_work_orders (
+    id bigserial,
+    parking_lot_id bigint,
+    reason text,
+    assignee text,
+    planned_start timestamptz,
+    planned_end timestamptz,
+    status text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_orders_status ON maintenance_work_orders (status);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_orders_parking_lot_id ON maintenance_work_orders (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS maintenance_work_order_slots (
+    id bigserial,
+    work_order_id bigint,
+    parking_slot_id bigint,
+    relative_id bigint,
+    status text,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_maintenance_work_orders_slots FOREIGN KEY (work_order_id) REFERENCES maintenance_work_orders(id)
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_order_slots_parking_slot_id ON maintenance_work_order_slots (parking_slot_id);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_order_slots_work_order_id ON maintenance_work_order_slots (work_order_id);
+
+CREATE TABLE IF NOT EXISTS maintenance_events (
+    id bigserial,
+    parking_slot_id bigint,
+    work_order_id bigint,
+    kind text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_events_parking_slot_id ON maintenance_events (parking_slot_id);
+
+CREATE TABLE IF NOT EXISTS opening_hours (
+    id bigserial,
+    parking_lot_id bigint,
+    weekday bigint,
+    opens text,
+    closes text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_opening_hours_parking_lot_id ON opening_hours (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS lot_exception_dates (
+    id bigserial,
+    parking_lot_id bigint,
+    date text,
+    closed boolean,
+    opens text,
+    closes text,
+    reason text,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lot_exception_date ON lot_exception_dates (parking_lot_id,date);
+
+CREATE TABLE IF NOT EXISTS slot_holds (
+    id bigserial,
+    parking_lot_id bigint,
+    parking_slot_id bigint,
+    relative_id bigint,
+    from_parking_lot_id bigint,
+    car_id bigint,
+    plate text,
+    status text,
+    expires_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_plate ON slot_holds (plate);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_car_id ON slot_holds (car_id);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_parking_slot_id ON slot_holds (parking_slot_id);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_parking_lot_id ON slot_holds (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS overflow_redirects (
+    id bigserial,
+    from_parking_lot_id bigint,
+    to_parking_lot_id bigint,
+    car_id bigint,
+    plate text,
+    alternatives_offered bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_overflow_redirects_created_at ON overflow_redirects (created_at);
+CREATE INDEX IF NOT EXISTS idx_overflow_redirects_from_parking_lot_id ON overflow_redirects (from_parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS charging_sessions (
+    id bigserial,
+    parking_lot_id bigint,
+    parking_slot_id bigint,
+    relative_id bigint,
+    car_id bigint,
+    ticket_session_id bigint,
+    parked_at timestamptz,
+    connector_type text,
+    meter_start_wh bigint,
+    meter_last_wh bigint,
+    prior_segments_wh bigint,
+    energy_kwh decimal,
+    started_at timestamptz,
+    completed_at timestamptz,
+    status text,
+    energy_fee bigint,
+    idle_fee bigint,
+    billed_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_charging_sessions_status ON charging_sessions (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_charging_visit ON charging_sessions (parking_slot_id,parked_at);
+CREATE INDEX IF NOT EXISTS idx_charging_sessions_parking_lot_id ON charging_sessions (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS valet_tickets (
+    id bigserial,
+    ticket_session_id bigint,
+    claim_code text NOT NULL,
+    parking_lot_id bigint,
+    parking_slot_id bigint,
+    relative_slot_id bigint,
+    key_tag text,
+    plate text,
+    parked_by text,
+    parked_at timestamptz,
+    status text,
+    pickup_at timestamptz,
+    requested_at timestamptz,
+    pickup_eta timestamptz,
+    retrieved_by text,
+    ready_at timestamptz,
+    delivered_at timestamptz,
+    delivered_by text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_valet_tickets_status ON valet_tickets (status);
+CREATE INDEX IF NOT EXISTS idx_valet_tickets_key_tag ON valet_tickets (key_tag);
+CREATE INDEX IF NOT EXISTS idx_valet_tickets_parking_lot_id ON valet_tickets (parking_lot_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_valet_tickets_claim_code ON valet_tickets (claim_code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_valet_tickets_ticket_session_id ON valet_tickets (ticket_session_id);
+
+CREATE TABLE IF NOT EXISTS slot_moves (
+    id bigserial,
+    parking_lot_id bigint,
+    car_id bigint,
+    ticket_session_id bigint,
+    parked_at timestamptz,
+    from_slot_id bigint,
+    from_relative_id bigint,
+    to_slot_id bigint,
+    to_relative_id bigint,
+    moved_by text,
+    reason text,
+    moved_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_moved_at ON slot_moves (moved_at);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_ticket_session_id ON slot_moves (ticket_session_id);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_car_id ON slot_moves (car_id);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_parking_lot_id ON slot_moves (parking_lot_id);
@@ -0,0 +1,42 @@
+-- Edge sync: the cursor of the events an edge site sent to the central
+-- instance, and at the central instance the links of edge lots, the edge
+-- events applied and the conflicts they raised.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS synced_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_synced_at ON outbox_events (synced_at);
+
+CREATE TABLE IF NOT EXISTS edge_lot_links (
+    id bigserial,
+    site_id text,
+    edge_lot_id bigint,
+    parking_lot_id bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_lot ON edge_lot_links (site_id,edge_lot_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_lot_links_parking_lot_id ON edge_lot_links (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS synced_events (
+    id bigserial,
+    site_id text,
+    event_id bigint,
+    event_type text,
+    applied_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_synced_event ON synced_events (site_id,event_id);
+
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+    id bigserial,
+    site_id text,
+    parking_lot_id bigint,
+    event_id bigint,
+    event_type text,
+    kind text,
+    relative_id bigint,
+    resolution text,
+    occurred_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_site_id ON sync_conflicts (site_id);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_parking_lot_id ON sync_conflicts (parking_lot_id);
@@ -0,0 +1,2 @@
+-- A key tag identifies the keys of a single car until it is delivered.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_valet_tickets_active_key_tag ON valet_tickets (parking_lot_id, key_tag) WHERE status <> 'delivered';
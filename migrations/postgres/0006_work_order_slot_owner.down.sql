@@ -0,0 +1 @@
+ALTER TABLE maintenance_work_order_slots DROP COLUMN IF EXISTS sets_maintenance;
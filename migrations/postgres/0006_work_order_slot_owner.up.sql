@@ -0,0 +1,5 @@
+-- Whether the work order put the slot in maintenance, only then may it take
+-- the slot out of maintenance again. Slots started before are released by
+-- their work order as they were.
+ALTER TABLE maintenance_work_order_slots ADD COLUMN IF NOT EXISTS sets_maintenance boolean DEFAULT false;
+UPDATE maintenance_work_order_slots SET sets_maintenance = true WHERE status = 'in_maintenance';
@@ -0,0 +1,39 @@
+-- Baseline: the SQLite version of the PostgreSQL baseline schema.
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text
+);
+
+CREATE TABLE IF NOT EXISTS cars (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer,
+    parking_slot_id integer,
+    CONSTRAINT fk_users_cars FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS parking_slots (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    relative_id integer,
+    is_booked numeric DEFAULT false,
+    is_in_maintenance numeric DEFAULT false,
+    car_id integer,
+    parked_at datetime,
+    unparked_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS parking_lots (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    location text
+);
+
+CREATE TABLE IF NOT EXISTS parking_histories (
+    date datetime,
+    cars_parked integer,
+    total_parking_time integer DEFAULT 0,
+    total_revenue_earned integer DEFAULT 0,
+    PRIMARY KEY (date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_parking_slot_composite ON parking_slots (parking_lot_id, is_booked, relative_id);
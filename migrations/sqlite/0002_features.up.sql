@@ -0,0 +1,388 @@
+-- Features: the SQLite version of the PostgreSQL features migration. SQLite
+-- databases were always created by migrations, so the columns are added
+-- without checking for them.
+
+ALTER TABLE cars ADD COLUMN plate text;
+CREATE INDEX IF NOT EXISTS idx_cars_plate ON cars (plate);
+
+ALTER TABLE parking_slots ADD COLUMN slot_type text DEFAULT 'standard';
+ALTER TABLE parking_slots ADD COLUMN zone text;
+ALTER TABLE parking_slots ADD COLUMN label text;
+ALTER TABLE parking_slots ADD COLUMN charger_connector text;
+ALTER TABLE parking_slots ADD COLUMN charger_power_kw real;
+ALTER TABLE parking_slots ADD COLUMN ticket_session_id integer;
+ALTER TABLE parking_slots ADD COLUMN is_draining numeric DEFAULT false;
+ALTER TABLE parking_slots ADD COLUMN decommissioned_at datetime;
+CREATE INDEX IF NOT EXISTS idx_parking_slots_decommissioned_at ON parking_slots (decommissioned_at);
+CREATE INDEX IF NOT EXISTS idx_parking_slots_label ON parking_slots (label);
+CREATE INDEX IF NOT EXISTS idx_parking_slots_zone ON parking_slots (zone);
+
+ALTER TABLE parking_lots ADD COLUMN name text;
+ALTER TABLE parking_lots ADD COLUMN description text;
+ALTER TABLE parking_lots ADD COLUMN operator text;
+ALTER TABLE parking_lots ADD COLUMN address_street text;
+ALTER TABLE parking_lots ADD COLUMN address_city text;
+ALTER TABLE parking_lots ADD COLUMN address_postal_code text;
+ALTER TABLE parking_lots ADD COLUMN address_country text;
+ALTER TABLE parking_lots ADD COLUMN latitude real;
+ALTER TABLE parking_lots ADD COLUMN longitude real;
+ALTER TABLE parking_lots ADD COLUMN lost_ticket_policy text DEFAULT 'flat_fee';
+ALTER TABLE parking_lots ADD COLUMN lost_ticket_fee integer DEFAULT 100;
+ALTER TABLE parking_lots ADD COLUMN max_stay_minutes integer DEFAULT 0;
+ALTER TABLE parking_lots ADD COLUMN overstay_fine integer DEFAULT 50;
+ALTER TABLE parking_lots ADD COLUMN overstay_escalation_minutes integer DEFAULT 60;
+ALTER TABLE parking_lots ADD COLUMN timezone text DEFAULT 'UTC';
+ALTER TABLE parking_lots ADD COLUMN overnight_fee integer DEFAULT 0;
+ALTER TABLE parking_lots ADD COLUMN energy_rate_per_kwh real DEFAULT 0.5;
+ALTER TABLE parking_lots ADD COLUMN idle_fee_per_hour integer DEFAULT 10;
+ALTER TABLE parking_lots ADD COLUMN idle_grace_minutes integer DEFAULT 15;
+ALTER TABLE parking_lots ADD COLUMN charging_billing text DEFAULT 'energy_and_time';
+ALTER TABLE parking_lots ADD COLUMN valet_mode numeric DEFAULT false;
+ALTER TABLE parking_lots ADD COLUMN valet_fee integer DEFAULT 25;
+ALTER TABLE parking_lots ADD COLUMN valet_retrieval_minutes integer DEFAULT 10;
+CREATE INDEX IF NOT EXISTS idx_parking_lots_operator ON parking_lots (operator);
+
+ALTER TABLE parking_histories ADD COLUMN lost_ticket_revenue integer DEFAULT 0;
+ALTER TABLE parking_histories ADD COLUMN overstay_fine_revenue integer DEFAULT 0;
+ALTER TABLE parking_histories ADD COLUMN energy_revenue integer DEFAULT 0;
+ALTER TABLE parking_histories ADD COLUMN valet_revenue integer DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS ticket_sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ticket_code text NOT NULL,
+    plate text,
+    parking_lot_id integer,
+    parking_slot_id integer,
+    relative_slot_id integer,
+    entered_at datetime,
+    exited_at datetime,
+    total_parking_time integer,
+    total_amount_to_be_paid integer,
+    is_lost_ticket numeric DEFAULT false,
+    handled_by text,
+    needs_audit_review numeric DEFAULT false,
+    is_valet numeric DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_sessions_needs_audit_review ON ticket_sessions (needs_audit_review);
+CREATE INDEX IF NOT EXISTS idx_ticket_sessions_plate ON ticket_sessions (plate);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_sessions_ticket_code ON ticket_sessions (ticket_code);
+
+CREATE TABLE IF NOT EXISTS gate_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    gate_id text,
+    direction text,
+    decision text,
+    reason text,
+    parking_lot_id integer,
+    car_id integer,
+    ticket_session_id integer,
+    operator text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_gate_events_gate_id ON gate_events (gate_id);
+
+CREATE TABLE IF NOT EXISTS camera_lanes (
+    lane_id text,
+    parking_lot_id integer,
+    direction text,
+    gate_id text,
+    PRIMARY KEY (lane_id)
+);
+
+CREATE TABLE IF NOT EXISTS anpr_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    plate text,
+    confidence real,
+    lane_id text,
+    captured_at datetime,
+    direction text,
+    parking_lot_id integer,
+    status text,
+    reason text,
+    car_id integer,
+    ticket_session_id integer,
+    amount_to_be_paid integer,
+    reviewed_by text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_anpr_events_status ON anpr_events (status);
+CREATE INDEX IF NOT EXISTS idx_anpr_events_lane_id ON anpr_events (lane_id);
+CREATE INDEX IF NOT EXISTS idx_anpr_events_plate ON anpr_events (plate);
+
+CREATE TABLE IF NOT EXISTS slot_sensor_states (
+    parking_slot_id integer PRIMARY KEY,
+    occupied numeric,
+    changed_at datetime,
+    reported_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS sensor_discrepancies (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_slot_id integer,
+    parking_lot_id integer,
+    relative_id integer,
+    kind text,
+    detected_at datetime,
+    resolved_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_sensor_discrepancies_parking_slot_id ON sensor_discrepancies (parking_slot_id);
+CREATE INDEX IF NOT EXISTS idx_sensor_discrepancies_parking_lot_id ON sensor_discrepancies (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    url text,
+    secret text,
+    event_types text,
+    is_active numeric DEFAULT true,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_parking_lot_id ON webhook_subscriptions (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    event_type text,
+    parking_lot_id integer,
+    payload text,
+    created_at datetime,
+    dispatched_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dispatched_at ON outbox_events (dispatched_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    subscription_id integer,
+    outbox_event_id integer,
+    event_type text,
+    status text,
+    attempts integer,
+    last_status_code integer,
+    last_error text,
+    next_attempt_at datetime,
+    delivered_at datetime,
+    created_at datetime,
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id),
+    CONSTRAINT fk_webhook_deliveries_outbox_event FOREIGN KEY (outbox_event_id) REFERENCES outbox_events(id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS capacity_thresholds (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    percent integer,
+    hysteresis_percent integer,
+    is_triggered numeric DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS idx_capacity_thresholds_parking_lot_id ON capacity_thresholds (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS capacity_alerts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    threshold_id integer,
+    threshold_percent integer,
+    kind text,
+    occupied_slots integer,
+    available_slots integer,
+    occupancy_percent real,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_capacity_alerts_parking_lot_id ON capacity_alerts (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS overstay_violations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    parking_slot_id integer,
+    relative_id integer,
+    car_id integer,
+    ticket_session_id integer,
+    parked_at datetime,
+    detected_at datetime,
+    level integer,
+    fine_amount integer,
+    status text,
+    paid_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_overstay_violations_status ON overstay_violations (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_overstay_visit ON overstay_violations (parking_slot_id,parked_at);
+CREATE INDEX IF NOT EXISTS idx_overstay_violations_parking_lot_id ON overstay_violations (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS access_list_entries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    list_type text,
+    plate text,
+    user_id integer,
+    parking_lot_id integer,
+    reason text,
+    expires_at datetime,
+    created_by text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_access_list_entries_user_id ON access_list_entries (user_id);
+CREATE INDEX IF NOT EXISTS idx_access_list_entries_plate ON access_list_entries (plate);
+CREATE INDEX IF NOT EXISTS idx_access_list_entries_list_type ON access_list_entries (list_type);
+
+CREATE TABLE IF NOT EXISTS blocked_entries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    access_list_entry_id integer,
+    parking_lot_id integer,
+    plate text,
+    car_id integer,
+    user_id integer,
+    reason text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_blocked_entries_parking_lot_id ON blocked_entries (parking_lot_id);
+CREATE INDEX IF NOT EXISTS idx_blocked_entries_access_list_entry_id ON blocked_entries (access_list_entry_id);
+
+CREATE TABLE IF NOT EXISTS maintenance_work_orders (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    reason text,
+    assignee text,
+    planned_start datetime,
+    planned_end datetime,
+    status text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_orders_status ON maintenance_work_orders (status);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_orders_parking_lot_id ON maintenance_work_orders (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS maintenance_work_order_slots (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    work_order_id integer,
+    parking_slot_id integer,
+    relative_id integer,
+    status text,
+    CONSTRAINT fk_maintenance_work_orders_slots FOREIGN KEY (work_order_id) REFERENCES maintenance_work_orders(id)
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_order_slots_parking_slot_id ON maintenance_work_order_slots (parking_slot_id);
+CREATE INDEX IF NOT EXISTS idx_maintenance_work_order_slots_work_order_id ON maintenance_work_order_slots (work_order_id);
+
+CREATE TABLE IF NOT EXISTS maintenance_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_slot_id integer,
+    work_order_id integer,
+    kind text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_events_parking_slot_id ON maintenance_events (parking_slot_id);
+
+CREATE TABLE IF NOT EXISTS opening_hours (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    weekday integer,
+    opens text,
+    closes text
+);
+CREATE INDEX IF NOT EXISTS idx_opening_hours_parking_lot_id ON opening_hours (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS lot_exception_dates (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    date text,
+    closed numeric,
+    opens text,
+    closes text,
+    reason text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lot_exception_date ON lot_exception_dates (parking_lot_id,date);
+
+CREATE TABLE IF NOT EXISTS slot_holds (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    parking_slot_id integer,
+    relative_id integer,
+    from_parking_lot_id integer,
+    car_id integer,
+    plate text,
+    status text,
+    expires_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_plate ON slot_holds (plate);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_car_id ON slot_holds (car_id);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_parking_slot_id ON slot_holds (parking_slot_id);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_parking_lot_id ON slot_holds (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS overflow_redirects (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    from_parking_lot_id integer,
+    to_parking_lot_id integer,
+    car_id integer,
+    plate text,
+    alternatives_offered integer,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_overflow_redirects_created_at ON overflow_redirects (created_at);
+CREATE INDEX IF NOT EXISTS idx_overflow_redirects_from_parking_lot_id ON overflow_redirects (from_parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS charging_sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    parking_slot_id integer,
+    relative_id integer,
+    car_id integer,
+    ticket_session_id integer,
+    parked_at datetime,
+    connector_type text,
+    meter_start_wh integer,
+    meter_last_wh integer,
+    prior_segments_wh integer,
+    energy_kwh real,
+    started_at datetime,
+    completed_at datetime,
+    status text,
+    energy_fee integer,
+    idle_fee integer,
+    billed_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_charging_sessions_status ON charging_sessions (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_charging_visit ON charging_sessions (parking_slot_id,parked_at);
+CREATE INDEX IF NOT EXISTS idx_charging_sessions_parking_lot_id ON charging_sessions (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS valet_tickets (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ticket_session_id integer,
+    claim_code text NOT NULL,
+    parking_lot_id integer,
+    parking_slot_id integer,
+    relative_slot_id integer,
+    key_tag text,
+    plate text,
+    parked_by text,
+    parked_at datetime,
+    status text,
+    pickup_at datetime,
+    requested_at datetime,
+    pickup_eta datetime,
+    retrieved_by text,
+    ready_at datetime,
+    delivered_at datetime,
+    delivered_by text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_valet_tickets_claim_code ON valet_tickets (claim_code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_valet_tickets_ticket_session_id ON valet_tickets (ticket_session_id);
+CREATE INDEX IF NOT EXISTS idx_valet_tickets_status ON valet_tickets (status);
+CREATE INDEX IF NOT EXISTS idx_valet_tickets_key_tag ON valet_tickets (key_tag);
+CREATE INDEX IF NOT EXISTS idx_valet_tickets_parking_lot_id ON valet_tickets (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS slot_moves (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    parking_lot_id integer,
+    car_id integer,
+    ticket_session_id integer,
+    parked_at datetime,
+    from_slot_id integer,
+    from_relative_id integer,
+    to_slot_id integer,
+    to_relative_id integer,
+    moved_by text,
+    reason text,
+    moved_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_moved_at ON slot_moves (moved_at);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_ticket_session_id ON slot_moves (ticket_session_id);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_car_id ON slot_moves (car_id);
+CREATE INDEX IF NOT EXISTS idx_slot_moves_parking_lot_id ON slot_moves (parking_lot_id);
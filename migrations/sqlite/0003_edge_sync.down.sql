@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS sync_conflicts;
+DROP TABLE IF EXISTS synced_events;
+DROP TABLE IF EXISTS edge_lot_links;
+DROP INDEX IF EXISTS idx_outbox_events_synced_at;
+ALTER TABLE outbox_events DROP COLUMN synced_at;
@@ -0,0 +1,39 @@
+-- Edge sync: the cursor of the events an edge site sent to the central
+-- instance, and at the central instance the links of edge lots, the edge
+-- events applied and the conflicts they raised.
+ALTER TABLE outbox_events ADD COLUMN synced_at datetime;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_synced_at ON outbox_events (synced_at);
+
+CREATE TABLE IF NOT EXISTS edge_lot_links (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    site_id text,
+    edge_lot_id integer,
+    parking_lot_id integer,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_lot ON edge_lot_links (site_id,edge_lot_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_lot_links_parking_lot_id ON edge_lot_links (parking_lot_id);
+
+CREATE TABLE IF NOT EXISTS synced_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    site_id text,
+    event_id integer,
+    event_type text,
+    applied_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_synced_event ON synced_events (site_id,event_id);
+
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    site_id text,
+    parking_lot_id integer,
+    event_id integer,
+    event_type text,
+    kind text,
+    relative_id integer,
+    resolution text,
+    occurred_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_site_id ON sync_conflicts (site_id);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_parking_lot_id ON sync_conflicts (parking_lot_id);
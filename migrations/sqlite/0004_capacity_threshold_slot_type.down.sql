@@ -0,0 +1,2 @@
+ALTER TABLE capacity_alerts DROP COLUMN slot_type;
+ALTER TABLE capacity_thresholds DROP COLUMN slot_type;
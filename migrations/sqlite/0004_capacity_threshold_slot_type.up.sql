@@ -0,0 +1,4 @@
+-- Capacity thresholds per slot type: an empty slot type keeps counting every
+-- slot of the lot.
+ALTER TABLE capacity_thresholds ADD COLUMN slot_type text;
+ALTER TABLE capacity_alerts ADD COLUMN slot_type text;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_valet_tickets_active_key_tag;
@@ -0,0 +1 @@
+ALTER TABLE maintenance_work_order_slots DROP COLUMN sets_maintenance;
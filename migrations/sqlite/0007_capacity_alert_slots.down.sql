@@ -0,0 +1 @@
+ALTER TABLE capacity_alerts RENAME COLUMN capacity_slots TO available_slots;
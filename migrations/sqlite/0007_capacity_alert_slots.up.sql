@@ -0,0 +1,3 @@
+-- The alerts store the capacity the occupancy was counted against, the slots
+-- in service and out of maintenance, not the free slots.
+ALTER TABLE capacity_alerts RENAME COLUMN available_slots TO capacity_slots;
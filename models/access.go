@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	AccessListBlock = "block" // Entry is refused
+	AccessListAllow = "allow" // Parking is free of charge
+)
+
+// AccessListEntry blocks or allows a plate or all the cars of a user, either
+// in one parking lot or in every lot when ParkingLotID is not set.
+type AccessListEntry struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ListType     string     `gorm:"index" json:"list_type"` // AccessListBlock or AccessListAllow
+	Plate        string     `gorm:"index" json:"plate,omitempty"`
+	UserID       *uint      `gorm:"index" json:"user_id,omitempty"`
+	ParkingLotID *uint      `json:"parking_lot_id,omitempty"` // Nil applies the entry to every lot
+	Reason       string     `json:"reason"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // Nil never expires
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// BlockedEntry is the audit record of an entry refused by the blocklist.
+type BlockedEntry struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	AccessListEntryID uint      `gorm:"index" json:"access_list_entry_id"`
+	ParkingLotID      uint      `gorm:"index" json:"parking_lot_id"`
+	Plate             string    `json:"plate,omitempty"`
+	CarID             *uint     `json:"car_id,omitempty"`
+	UserID            *uint     `json:"user_id,omitempty"`
+	Reason            string    `json:"reason"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DefaultHysteresisPercent is how far occupancy has to fall below a
+// threshold before it can raise a new alert.
+const DefaultHysteresisPercent = 5
+
+// CapacityThreshold raises an alert when the occupancy of a parking lot, or
+// of its slots of SlotType, reaches Percent. It re-arms once occupancy drops
+// below Percent minus HysteresisPercent, so a lot hovering around the
+// threshold does not flap.
+type CapacityThreshold struct {
+	ID                uint   `gorm:"primaryKey" json:"id"`
+	ParkingLotID      uint   `gorm:"index" json:"parking_lot_id"`
+	SlotType          string `json:"slot_type,omitempty"` // Empty to count every slot type
+	Percent           int    `json:"percent"`
+	HysteresisPercent int    `json:"hysteresis_percent"`
+	IsTriggered       bool   `gorm:"default:false" json:"is_triggered"`
+}
+
+const (
+	CapacityAlertRaised  = "raised"
+	CapacityAlertCleared = "cleared"
+)
+
+// CapacityAlert is an entry of the alert history of a parking lot.
+type CapacityAlert struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ParkingLotID     uint      `gorm:"index" json:"parking_lot_id"`
+	ThresholdID      uint      `json:"threshold_id"`
+	ThresholdPercent int       `json:"threshold_percent"`
+	SlotType         string    `json:"slot_type,omitempty"` // Slot type the occupancy is counted for, empty for all
+	Kind             string    `json:"kind"`
+	OccupiedSlots    int       `json:"occupied_slots"`
+	CapacitySlots    int       `json:"capacity_slots"` // Slots in service and not in maintenance
+	OccupancyPercent float64   `json:"occupancy_percent"`
+	CreatedAt        time.Time `json:"created_at"`
+}
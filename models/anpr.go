@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CameraLane tells which parking lot and direction a plate-recognition camera watches.
+type CameraLane struct {
+	LaneID       string `gorm:"primaryKey" json:"lane_id"`
+	ParkingLotID uint   `json:"parking_lot_id"`
+	Direction    string `json:"direction"`         // GateDirectionEntry or GateDirectionExit
+	GateID       string `json:"gate_id,omitempty"` // Barrier opened for authorized vehicles
+}
+
+const (
+	AnprStatusProcessed     = "processed"      // Turned into a park or an unpark
+	AnprStatusDuplicate     = "duplicate"      // Repeat read of an already handled plate
+	AnprStatusReview        = "review"         // Confidence too low, waiting for an attendant
+	AnprStatusRejected      = "rejected"       // Discarded by an attendant
+	AnprStatusUnmatchedExit = "unmatched_exit" // Exit of a plate with no open session
+	AnprStatusFailed        = "failed"         // The park or unpark was refused
+)
+
+// AnprEvent is a plate read posted by a camera and what was done with it.
+type AnprEvent struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Plate           string    `gorm:"index" json:"plate"`
+	Confidence      float64   `json:"confidence"`
+	LaneID          string    `gorm:"index" json:"lane"`
+	CapturedAt      time.Time `json:"timestamp"`
+	Direction       string    `json:"direction,omitempty"`
+	ParkingLotID    uint      `json:"parking_lot_id,omitempty"`
+	Status          string    `gorm:"index" json:"status,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	CarID           *uint     `json:"car_id,omitempty"`
+	TicketSessionID *uint     `json:"ticket_session_id,omitempty"`
+	AmountToBePaid  int       `json:"amount_to_be_paid,omitempty"`
+	ReviewedBy      string    `json:"reviewed_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+const (
+	BulkActionMaintenance      = "maintenance"        // Put the slots in maintenance
+	BulkActionOutOfMaintenance = "out_of_maintenance" // Put the slots out of maintenance
+	BulkActionSetType          = "set_type"           // Change the slot type
+	BulkActionSetZone          = "set_zone"           // Move the slots to a zone
+	BulkActionSetLabel         = "set_label"          // Tag the slots with a label
+)
+
+const (
+	BulkSlotChanged   = "changed"
+	BulkSlotUnchanged = "unchanged" // Already in the requested state
+	BulkSlotDraining  = "draining"  // Decommissioned once the parked car leaves
+	BulkSlotFailed    = "failed"
+)
+
+// BulkSlotResult reports what a bulk operation did, or would do on a dry run,
+// to one slot.
+type BulkSlotResult struct {
+	ParkingSlotID uint   `json:"parking_slot_id"`
+	RelativeID    uint   `json:"relative_id"`
+	Result        string `json:"result"`
+	Error         string `json:"error,omitempty"`
+}
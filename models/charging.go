@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+const (
+	ConnectorType2   = "type2"
+	ConnectorCCS     = "ccs"
+	ConnectorCHAdeMO = "chademo"
+)
+
+const (
+	// ChargingBillingEnergyAndTime bills energy on top of the parking fee.
+	ChargingBillingEnergyAndTime = "energy_and_time"
+	// ChargingBillingEnergyOnly bills energy instead of the parking fee for visits that charged.
+	ChargingBillingEnergyOnly = "energy_only"
+)
+
+const (
+	ChargerActionStart       = "StartTransaction"
+	ChargerActionMeterValues = "MeterValues"
+	ChargerActionStop        = "StopTransaction"
+)
+
+const (
+	ChargingSessionCharging  = "charging"  // Energy is flowing
+	ChargingSessionCompleted = "completed" // Charging stopped, idle time counts from here
+	ChargingSessionBilled    = "billed"    // Charged at unpark
+)
+
+// ChargingSession is the charging of a vehicle during its visit in a slot
+// with a charger. It belongs to the parking session of the car or walk-in
+// ticket parked in the slot at ParkedAt.
+type ChargingSession struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	ParkingLotID    uint       `gorm:"index" json:"parking_lot_id"`
+	ParkingSlotID   uint       `gorm:"uniqueIndex:idx_charging_visit" json:"parking_slot_id"`
+	RelativeID      uint       `json:"relative_id"`
+	CarID           *uint      `json:"car_id,omitempty"`
+	TicketSessionID *uint      `json:"ticket_session_id,omitempty"`
+	ParkedAt        time.Time  `gorm:"uniqueIndex:idx_charging_visit" json:"parked_at"` // Identifies the visit together with the slot
+	ConnectorType   string     `json:"connector_type"`
+	MeterStartWh    int64      `json:"meter_start_wh"`
+	MeterLastWh     int64      `json:"meter_last_wh"`
+	PriorSegmentsWh int64      `json:"prior_segments_wh,omitempty"` // Energy of earlier charging during the visit, such as before a move
+	EnergyKWh       float64    `gorm:"column:energy_kwh" json:"energy_kwh"`
+	StartedAt       time.Time  `json:"started_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	Status          string     `gorm:"index" json:"status"`
+	EnergyFee       int        `json:"energy_fee"` // Set when billed
+	IdleFee         int        `json:"idle_fee"`   // Set when billed
+	BilledAt        *time.Time `json:"billed_at,omitempty"`
+}
+
+// ChargerTelemetry is a message from a charger, modelled on the OCPP
+// transaction messages. Chargers are identified by their parking slot.
+type ChargerTelemetry struct {
+	ParkingSlotID uint      `json:"parking_slot_id"`
+	Action        string    `json:"action"`   // One of the ChargerAction constants
+	MeterWh       int64     `json:"meter_wh"` // Cumulative energy register of the charger
+	Timestamp     time.Time `json:"timestamp"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	GateDirectionEntry = "entry"
+	GateDirectionExit  = "exit"
+)
+
+const (
+	GateDecisionOpen          = "open"           // Authorized and the gate confirmed the open command
+	GateDecisionDenied        = "denied"         // Not authorized, the gate stays closed
+	GateDecisionTimeout       = "timeout"        // Authorized but the gate did not answer in time
+	GateDecisionFault         = "fault"          // Authorized but the gate reported an error
+	GateDecisionSkipped       = "skipped"        // Authorized but no gate controller is configured
+	GateDecisionOverrideOpen  = "override_open"  // Opened manually by an operator
+	GateDecisionOverrideClose = "override_close" // Closed manually by an operator
+)
+
+// GateEvent records every decision taken for a barrier gate.
+type GateEvent struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	GateID          string    `gorm:"index" json:"gate_id"`
+	Direction       string    `json:"direction,omitempty"`
+	Decision        string    `json:"decision"`
+	Reason          string    `json:"reason,omitempty"`
+	ParkingLotID    *uint     `json:"parking_lot_id,omitempty"`
+	CarID           *uint     `json:"car_id,omitempty"`
+	TicketSessionID *uint     `json:"ticket_session_id,omitempty"`
+	Operator        string    `json:"operator,omitempty"` // Set for manual overrides and exits closed by an attendant
+	CreatedAt       time.Time `json:"created_at"`
+}
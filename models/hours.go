@@ -0,0 +1,24 @@
+package models
+
+// OpeningHours is one weekly opening period of a parking lot, in the lot's
+// time zone. A period that closes at or before its opening time runs past
+// midnight into the next day.
+type OpeningHours struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	ParkingLotID uint   `gorm:"index" json:"parking_lot_id"`
+	Weekday      int    `json:"weekday"` // 0 is Sunday, as in time.Weekday
+	Opens        string `json:"opens"`   // HH:MM
+	Closes       string `json:"closes"`  // HH:MM, 24:00 for midnight
+}
+
+// LotExceptionDate replaces the weekly opening hours of a parking lot on one
+// date, either closing the lot for the day or opening it with special hours.
+type LotExceptionDate struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	ParkingLotID uint   `gorm:"uniqueIndex:idx_lot_exception_date" json:"parking_lot_id"`
+	Date         string `gorm:"uniqueIndex:idx_lot_exception_date" json:"date"` // YYYY-MM-DD in the lot's time zone
+	Closed       bool   `json:"closed"`
+	Opens        string `json:"opens,omitempty"`  // Special hours when not closed
+	Closes       string `json:"closes,omitempty"` // Special hours when not closed
+	Reason       string `json:"reason,omitempty"`
+}
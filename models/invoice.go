@@ -0,0 +1,19 @@
+package models
+
+const (
+	LineItemParking    = "parking"
+	LineItemLostTicket = "lost_ticket"
+	LineItemOvernight  = "overnight"
+	LineItemOverstay   = "overstay"
+	LineItemEnergy     = "energy"
+	LineItemIdle       = "idle"
+	LineItemValet      = "valet"
+)
+
+// LineItem is one component of what a driver owes for a visit.
+type LineItem struct {
+	Kind     string  `json:"kind"` // One of the LineItem constants
+	Quantity float64 `json:"quantity,omitempty"`
+	Unit     string  `json:"unit,omitempty"` // Such as hour or kWh
+	Amount   int     `json:"amount"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+const (
+	WorkOrderScheduled  = "scheduled"   // Waiting for its planned start
+	WorkOrderInProgress = "in_progress" // Between planned start and end
+	WorkOrderCompleted  = "completed"   // Ended at its planned end or by hand
+	WorkOrderCancelled  = "cancelled"   // Called off, its slots are released
+)
+
+const (
+	WorkOrderSlotPending       = "pending"         // Work order not started yet
+	WorkOrderSlotWaitingForCar = "waiting_for_car" // Started while a car was parked in the slot
+	WorkOrderSlotInMaintenance = "in_maintenance"  // Slot put in maintenance by the work order
+	WorkOrderSlotReleased      = "released"        // Work order over, slot given back
+)
+
+// MaintenanceWorkOrder plans maintenance of one or more slots of a parking
+// lot. Its slots are kept out of allocation from shortly before the planned
+// start and are put in maintenance once the work order starts.
+type MaintenanceWorkOrder struct {
+	ID           uint                       `gorm:"primaryKey" json:"id"`
+	ParkingLotID uint                       `gorm:"index" json:"parking_lot_id"`
+	Reason       string                     `json:"reason"`
+	Assignee     string                     `json:"assignee"`
+	PlannedStart time.Time                  `json:"planned_start"`
+	PlannedEnd   time.Time                  `json:"planned_end"`
+	Status       string                     `gorm:"index" json:"status"`
+	CreatedAt    time.Time                  `json:"created_at"`
+	Slots        []MaintenanceWorkOrderSlot `gorm:"foreignKey:WorkOrderID" json:"slots"`
+}
+
+// MaintenanceWorkOrderSlot is a slot covered by a work order and how far the
+// work order got with it.
+type MaintenanceWorkOrderSlot struct {
+	ID              uint   `gorm:"primaryKey" json:"id"`
+	WorkOrderID     uint   `gorm:"index" json:"work_order_id"`
+	ParkingSlotID   uint   `gorm:"index" json:"parking_slot_id"`
+	RelativeID      uint   `json:"relative_id"`
+	Status          string `json:"status"`
+	SetsMaintenance bool   `gorm:"default:false" json:"sets_maintenance"` // The work order put the slot in maintenance and takes it out again
+}
+
+const (
+	MaintenanceEventScheduled = "scheduled" // Slot added to a work order
+	MaintenanceEventWaiting   = "waiting"   // Work order started on an occupied slot
+	MaintenanceEventStarted   = "started"   // Slot put in maintenance
+	MaintenanceEventEnded     = "ended"     // Slot put out of maintenance
+	MaintenanceEventCancelled = "cancelled" // Work order cancelled before the slot went into maintenance
+)
+
+// MaintenanceEvent is an entry in the maintenance history of a slot. Events
+// of the manual maintenance toggle have no work order.
+type MaintenanceEvent struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ParkingSlotID uint      `gorm:"index" json:"parking_slot_id"`
+	WorkOrderID   *uint     `json:"work_order_id,omitempty"`
+	Kind          string    `json:"kind"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	SlotHoldHeld = "held" // Waiting for the redirected vehicle
+	SlotHoldUsed = "used" // The vehicle parked in the held slot
+)
+
+// SlotHold keeps a free slot for a vehicle redirected from a full lot until
+// it arrives or the hold expires.
+type SlotHold struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ParkingLotID     uint      `gorm:"index" json:"parking_lot_id"`
+	ParkingSlotID    uint      `gorm:"index" json:"parking_slot_id"`
+	RelativeID       uint      `json:"relative_id"`
+	FromParkingLotID uint      `json:"from_parking_lot_id"`
+	CarID            *uint     `gorm:"index" json:"car_id,omitempty"`
+	Plate            string    `gorm:"index" json:"plate,omitempty"`
+	Status           string    `json:"status"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// OverflowRedirect records a vehicle turned away by a full lot, for
+// capacity planning.
+type OverflowRedirect struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	FromParkingLotID    uint      `gorm:"index" json:"from_parking_lot_id"`
+	ToParkingLotID      *uint     `json:"to_parking_lot_id,omitempty"` // Lot a slot was held at
+	CarID               *uint     `json:"car_id,omitempty"`
+	Plate               string    `json:"plate,omitempty"`
+	AlternativesOffered int       `json:"alternatives_offered"`
+	CreatedAt           time.Time `gorm:"index" json:"created_at"`
+}
+
+// OverflowStats sums up the overflow redirects of a lot over a period.
+type OverflowStats struct {
+	ParkingLotID        uint           `json:"parking_lot_id"`
+	TurnedAway          int64          `json:"turned_away"`          // Entries refused because the lot was full
+	WithoutAlternatives int64          `json:"without_alternatives"` // Refusals with no alternative to offer
+	AutoRedirected      int64          `json:"auto_redirected"`      // Refusals with a slot held elsewhere
+	ByDestination       map[uint]int64 `json:"by_destination"`       // Held slots by lot
+}
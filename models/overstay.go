@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	OverstayViolationOpen   = "open"   // Fine not paid yet
+	OverstayViolationPaid   = "paid"   // Fine charged at unpark
+	OverstayViolationWaived = "waived" // Vehicle was allowlisted at unpark
+)
+
+// OverstayViolation is raised once a vehicle stays longer than the maximum
+// stay of its lot. The fine escalates while the vehicle stays on.
+type OverstayViolation struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	ParkingLotID    uint       `gorm:"index" json:"parking_lot_id"`
+	ParkingSlotID   uint       `gorm:"uniqueIndex:idx_overstay_visit" json:"parking_slot_id"`
+	RelativeID      uint       `json:"relative_id"`
+	CarID           *uint      `json:"car_id,omitempty"`
+	TicketSessionID *uint      `json:"ticket_session_id,omitempty"`
+	ParkedAt        time.Time  `gorm:"uniqueIndex:idx_overstay_visit" json:"parked_at"` // Identifies the visit together with the slot
+	DetectedAt      time.Time  `json:"detected_at"`
+	Level           int        `json:"level"` // Number of started escalation periods
+	FineAmount      int        `json:"fine_amount"`
+	Status          string     `gorm:"index" json:"status"`
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+}
@@ -3,25 +3,99 @@ package models
 import "time"
 
 type ParkingLot struct {
-	ID       uint          `gorm:"primaryKey" json:"id"`
-	Location string        `json:"location"`
-	Slots    []ParkingSlot `json:"slots"`
+	ID                        uint          `gorm:"primaryKey" json:"id"`
+	Name                      string        `json:"name,omitempty"`
+	Location                  string        `json:"location"`
+	Description               string        `json:"description,omitempty"`
+	Operator                  string        `gorm:"index" json:"operator,omitempty"` // Company running the lot, overflow is redirected within it
+	Address                   Address       `gorm:"embedded;embeddedPrefix:address_" json:"address"`
+	Latitude                  *float64      `json:"latitude,omitempty"`                                                // WGS 84 degrees, nil when the lot is not geolocated
+	Longitude                 *float64      `json:"longitude,omitempty"`                                               // WGS 84 degrees, nil when the lot is not geolocated
+	LostTicketPolicy          string        `gorm:"default:flat_fee" json:"lost_ticket_policy"`                        // One of the LostTicketPolicy constants
+	LostTicketFee             int           `json:"lost_ticket_fee"`                                                   // Charged under the flat fee policy, zero is a valid fee
+	MaxStayMinutes            int           `gorm:"default:0" json:"max_stay_minutes"`                                 // Zero means no limit
+	OverstayFine              int           `gorm:"default:50" json:"overstay_fine"`                                   // Fine per started escalation period
+	OverstayEscalationMinutes int           `gorm:"default:60" json:"overstay_escalation_minutes"`                     // Length of an escalation period
+	Timezone                  string        `gorm:"default:UTC" json:"timezone"`                                       // IANA zone the opening hours are given in
+	OvernightFee              int           `gorm:"default:0" json:"overnight_fee"`                                    // Charged when a stay spans a closure, zero disables
+	EnergyRatePerKWh          float64       `gorm:"column:energy_rate_per_kwh;default:0.5" json:"energy_rate_per_kwh"` // Price of the energy delivered by chargers
+	IdleFeePerHour            int           `gorm:"default:10" json:"idle_fee_per_hour"`                               // Per started hour plugged in after charging completed
+	IdleGraceMinutes          int           `gorm:"default:15" json:"idle_grace_minutes"`                              // Idle time before the idle fee applies
+	ChargingBilling           string        `gorm:"default:energy_and_time" json:"charging_billing"`                   // One of the ChargingBilling constants
+	ValetMode                 bool          `gorm:"default:false" json:"valet_mode"`                                   // Only the valet staff park cars
+	ValetFee                  int           `gorm:"default:25" json:"valet_fee"`                                       // Charged on top of the parking fee for a valet visit
+	ValetRetrievalMinutes     int           `gorm:"default:10" json:"valet_retrieval_minutes"`                         // Expected time to bring a car to the pickup point
+	Slots                     []ParkingSlot `json:"slots"`
 }
 
+// Address is the postal address of a parking lot.
+type Address struct {
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+const (
+	// LostTicketPolicyFlatFee charges the lot's lost-ticket fee regardless of the stay.
+	LostTicketPolicyFlatFee = "flat_fee"
+	// LostTicketPolicyElapsedTime charges the regular fee since the session started.
+	LostTicketPolicyElapsedTime = "elapsed_time"
+)
+
+// DefaultLostTicketFee is the lost-ticket fee of a parking lot created without
+// one. It is applied by the callers rather than as a column default, which
+// would also replace an explicit fee of 0.
+const DefaultLostTicketFee = 100
+
+const (
+	SlotTypeStandard   = "standard"
+	SlotTypeCompact    = "compact"
+	SlotTypeAccessible = "accessible"
+	SlotTypeMotorcycle = "motorcycle"
+)
+
 type ParkingSlot struct {
-	ID              uint       `gorm:"primaryKey" json:"id"`
-	ParkingLotID    uint       `json:"-"`
-	RelativeID      uint       `json:"relative_id"`
-	IsBooked        bool       `gorm:"default:false" json:"is_booked"`
-	IsInMaintenance bool       `gorm:"default:false" json:"is_in_maintenance"`
-	CarID           *uint      `json:"car_id,omitempty"` // Nullable reference to Car
-	ParkedAt        *time.Time `json:"parked_at,omitempty"`
-	UnparkedAt      *time.Time `json:"unparked_at,omitempty"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ParkingLotID     uint       `json:"-"`
+	RelativeID       uint       `json:"relative_id"`
+	IsBooked         bool       `gorm:"default:false" json:"is_booked"`
+	IsInMaintenance  bool       `gorm:"default:false" json:"is_in_maintenance"`
+	SlotType         string     `gorm:"default:standard" json:"slot_type"` // One of the SlotType constants
+	Zone             string     `gorm:"index" json:"zone,omitempty"`       // Area of the lot, such as a level or a row
+	Label            string     `gorm:"index" json:"label,omitempty"`      // Free-form tag set by staff
+	ChargerConnector string     `json:"charger_connector,omitempty"`       // One of the Connector constants, empty without a charger
+	ChargerPowerKW   float64    `json:"charger_power_kw,omitempty"`
+	CarID            *uint      `json:"car_id,omitempty"`            // Nullable reference to Car
+	TicketSessionID  *uint      `json:"ticket_session_id,omitempty"` // Nullable reference to a walk-in TicketSession
+	ParkedAt         *time.Time `json:"parked_at,omitempty"`
+	UnparkedAt       *time.Time `json:"unparked_at,omitempty"`
+	IsDraining       bool       `gorm:"default:false" json:"is_draining,omitempty"` // Decommissioned once the parked car leaves
+	DecommissionedAt *time.Time `gorm:"index" json:"decommissioned_at,omitempty"`   // Kept for history, never allocated again
 }
 
 type ParkingHistory struct {
-	Date               time.Time `gorm:"primaryKey" json:"date"`
-	CarsParked         int       `json:"cars_parked"`
-	TotalParkingTime   int       `json:"total_parking_time" gorm:"default:0"`   // Total parking time in minutes
-	TotalRevenueEarned int64     `json:"total_revenue_earned" gorm:"default:0"` // Total revenue earned
+	Date                time.Time `gorm:"primaryKey" json:"date"`
+	CarsParked          int       `json:"cars_parked"`
+	TotalParkingTime    int       `json:"total_parking_time" gorm:"default:0"`    // Total parking time in minutes
+	TotalRevenueEarned  int64     `json:"total_revenue_earned" gorm:"default:0"`  // Total revenue earned
+	LostTicketRevenue   int64     `json:"lost_ticket_revenue" gorm:"default:0"`   // Part of the revenue charged through the lost-ticket flow
+	OverstayFineRevenue int64     `json:"overstay_fine_revenue" gorm:"default:0"` // Part of the revenue charged as overstay fines
+	EnergyRevenue       int64     `json:"energy_revenue" gorm:"default:0"`        // Part of the revenue charged for charging, idle fees included
+	ValetRevenue        int64     `json:"valet_revenue" gorm:"default:0"`         // Part of the revenue charged as valet fees
+}
+
+// ParkingCharge is what a driver owes when leaving a parking slot.
+type ParkingCharge struct {
+	TotalParkingTime    int        `json:"total_parking_time"`          // Billed parking time in hours
+	TotalAmountToBePaid int        `json:"total_amount_to_be_paid"`     // Amount due
+	LostTicketFee       int        `json:"lost_ticket_fee,omitempty"`   // Part of the amount due charged for a lost ticket
+	OverstayFine        int        `json:"overstay_fine,omitempty"`     // Part of the amount due for unpaid overstay violations
+	OvernightFee        int        `json:"overnight_fee,omitempty"`     // Part of the amount due for staying while the lot was closed
+	EnergyKWh           float64    `json:"energy_kwh,omitempty"`        // Energy delivered by the charger of the slot
+	EnergyFee           int        `json:"energy_fee,omitempty"`        // Part of the amount due for the energy
+	IdleFee             int        `json:"idle_fee,omitempty"`          // Part of the amount due for staying plugged in after charging
+	ValetFee            int        `json:"valet_fee,omitempty"`         // Part of the amount due for the valet service
+	IsFreeOfCharge      bool       `json:"is_free_of_charge,omitempty"` // Waived for an allowlisted vehicle
+	LineItems           []LineItem `json:"line_items,omitempty"`        // The amount due by component
 }
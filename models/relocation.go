@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SlotMove records a parked vehicle moved from one slot to another by an
+// attendant. The visit keeps its original ParkedAt, so it is billed as a
+// single stay when the vehicle leaves.
+type SlotMove struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ParkingLotID    uint      `gorm:"index" json:"parking_lot_id"`
+	CarID           *uint     `gorm:"index" json:"car_id,omitempty"`
+	TicketSessionID *uint     `gorm:"index" json:"ticket_session_id,omitempty"`
+	ParkedAt        time.Time `json:"parked_at"` // Start of the visit, unchanged by the move
+	FromSlotID      uint      `json:"from_slot_id"`
+	FromRelativeID  uint      `json:"from_relative_id"`
+	ToSlotID        uint      `json:"to_slot_id"`
+	ToRelativeID    uint      `json:"to_relative_id"`
+	MovedBy         string    `json:"moved_by"` // Attendant who moved the vehicle
+	Reason          string    `json:"reason,omitempty"`
+	MovedAt         time.Time `gorm:"index" json:"moved_at"`
+}
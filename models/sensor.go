@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// SlotSensorState is the last reading of the ground sensor of a parking slot.
+type SlotSensorState struct {
+	ParkingSlotID uint      `gorm:"primaryKey" json:"parking_slot_id"`
+	Occupied      bool      `json:"occupied"`
+	ChangedAt     time.Time `json:"changed_at"`  // When the sensor last switched between occupied and vacant
+	ReportedAt    time.Time `json:"reported_at"` // When the sensor last reported
+}
+
+const (
+	// SensorDiscrepancyOccupiedUnbooked means a vehicle stands in a slot nobody parked in.
+	SensorDiscrepancyOccupiedUnbooked = "occupied_but_unbooked"
+	// SensorDiscrepancyBookedVacant means a slot is booked but has been empty for a while.
+	SensorDiscrepancyBookedVacant = "booked_but_vacant"
+)
+
+// SensorDiscrepancy is raised when a sensor disagrees with the booking state
+// of its slot, and resolved once they agree again.
+type SensorDiscrepancy struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ParkingSlotID uint       `gorm:"index" json:"parking_slot_id"`
+	ParkingLotID  uint       `gorm:"index" json:"parking_lot_id"`
+	RelativeID    uint       `json:"relative_id"`
+	Kind          string     `json:"kind"`
+	DetectedAt    time.Time  `json:"detected_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
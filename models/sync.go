@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	SyncConflictSlotMissing    = "slot_missing"    // Edge slot does not exist centrally, or was decommissioned
+	SyncConflictSlotOccupied   = "slot_occupied"   // Central slot held another visit, replaced by the edge visit
+	SyncConflictMovedCentrally = "moved_centrally" // Visit was moved to another slot centrally
+	SyncConflictVisitMissing   = "visit_missing"   // Visit is not parked centrally any more
+	SyncConflictLotUnlinked    = "lot_unlinked"    // Edge lot is not linked to a central lot, the event was skipped
+)
+
+// EdgeLotLink ties a parking lot of an edge site to the central lot that
+// mirrors it. Slots are matched by relative ID.
+type EdgeLotLink struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       string    `gorm:"uniqueIndex:idx_edge_lot" json:"site_id"`
+	EdgeLotID    uint      `gorm:"uniqueIndex:idx_edge_lot" json:"edge_parking_lot_id"`
+	ParkingLotID uint      `gorm:"uniqueIndex" json:"parking_lot_id"` // Central lot, operated by the site only
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SyncedEvent records an edge event applied centrally, so a batch sent again
+// after a lost response is applied only once.
+type SyncedEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SiteID    string    `gorm:"uniqueIndex:idx_synced_event" json:"site_id"`
+	EventID   uint      `gorm:"uniqueIndex:idx_synced_event" json:"event_id"` // Outbox event ID at the site
+	EventType string    `json:"event_type"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// SyncConflict is an edge event that did not match the central state. The
+// edge decides where vehicles are, so the event was applied anyway as the
+// resolution says and the conflict is kept for review.
+type SyncConflict struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       string    `gorm:"index" json:"site_id"`
+	ParkingLotID uint      `gorm:"index" json:"parking_lot_id"` // Central lot, zero for an unlinked edge lot
+	EventID      uint      `json:"event_id"`
+	EventType    string    `json:"event_type"`
+	Kind         string    `json:"kind"` // One of the SyncConflict constants
+	RelativeID   uint      `json:"relative_id"`
+	Resolution   string    `json:"resolution"`
+	OccurredAt   time.Time `json:"occurred_at"` // When the event happened at the site
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SyncEvent is an outbox event of an edge site as sent to the central instance.
+type SyncEvent struct {
+	ID        uint            `json:"id"` // Outbox event ID at the site
+	EventType string          `json:"event_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"` // JSON encoded WebhookPayload
+}
+
+// SyncBatch is the body an edge site posts to the central instance, events
+// in the order they happened.
+type SyncBatch struct {
+	SiteID string      `json:"site_id"`
+	Events []SyncEvent `json:"events"`
+}
+
+// SyncResult tells the edge site which events of a batch were applied. The
+// batch stops at the first event that could not be applied.
+type SyncResult struct {
+	Applied   []uint         `json:"applied"`
+	Conflicts []SyncConflict `json:"conflicts,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TicketSession is a walk-in visit identified only by the ticket handed out at entry.
+type TicketSession struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	TicketCode          string     `gorm:"uniqueIndex;not null" json:"ticket_code"`
+	Plate               string     `gorm:"index" json:"plate,omitempty"` // Optional, captured at entry
+	ParkingLotID        uint       `json:"parking_lot_id"`
+	ParkingSlotID       uint       `json:"parking_slot_id"`
+	RelativeSlotID      uint       `json:"relative_slot_id"` // Slot number shown to the driver
+	EnteredAt           time.Time  `json:"entered_at"`
+	ExitedAt            *time.Time `json:"exited_at,omitempty"` // Null while the session is open
+	TotalParkingTime    int        `json:"total_parking_time"`
+	TotalAmountToBePaid int        `json:"total_amount_to_be_paid"`
+	IsLostTicket        bool       `gorm:"default:false" json:"is_lost_ticket"`
+	HandledBy           string     `json:"handled_by,omitempty"` // Attendant who closed a lost ticket or typed in the ticket code
+	NeedsAuditReview    bool       `gorm:"default:false;index" json:"needs_audit_review"`
+	IsValet             bool       `gorm:"default:false" json:"is_valet,omitempty"` // Closed by delivering the car, see ValetTicket
+}
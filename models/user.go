@@ -7,7 +7,8 @@ type User struct {
 }
 
 type Car struct {
-	ID            uint  `gorm:"primaryKey"`
-	UserID        uint  // Foreign key to User.ID
-	ParkingSlotID *uint `json:"parking_slot_id,omitempty"` // Nullable reference to ParkingSlot
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   // Foreign key to User.ID
+	Plate         string `gorm:"index" json:"plate,omitempty"`
+	ParkingSlotID *uint  `json:"parking_slot_id,omitempty"` // Nullable reference to ParkingSlot
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	ValetTicketParked     = "parked"     // Car parked by the staff, keys on the key board
+	ValetTicketRequested  = "requested"  // Driver asked for the car
+	ValetTicketRetrieving = "retrieving" // An attendant is bringing the car
+	ValetTicketReady      = "ready"      // Car waiting at the pickup point
+	ValetTicketDelivered  = "delivered"  // Car handed back and the visit charged
+)
+
+// ValetTicket is a car parked by the valet staff. The driver keeps the claim
+// ticket, whose code is the ticket code of the walk-in session of the visit.
+type ValetTicket struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	TicketSessionID uint       `gorm:"uniqueIndex" json:"ticket_session_id"`
+	ClaimCode       string     `gorm:"uniqueIndex;not null" json:"claim_code"`
+	ParkingLotID    uint       `gorm:"index" json:"parking_lot_id"`
+	ParkingSlotID   uint       `json:"parking_slot_id"`
+	RelativeSlotID  uint       `json:"relative_slot_id"`
+	KeyTag          string     `gorm:"index" json:"key_tag"` // Number of the tag on the keys, unique among the undelivered tickets of the lot
+	Plate           string     `json:"plate,omitempty"`
+	ParkedBy        string     `json:"parked_by"` // Attendant who parked the car
+	ParkedAt        time.Time  `json:"parked_at"`
+	Status          string     `gorm:"index" json:"status"`
+	PickupAt        *time.Time `json:"pickup_at,omitempty"` // When the driver wants the car
+	RequestedAt     *time.Time `json:"requested_at,omitempty"`
+	PickupETA       *time.Time `json:"pickup_eta,omitempty"` // When the car is expected at the pickup point
+	RetrievedBy     string     `json:"retrieved_by,omitempty"`
+	ReadyAt         *time.Time `json:"ready_at,omitempty"`
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
+	DeliveredBy     string     `json:"delivered_by,omitempty"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+const (
+	WebhookEventCarParked       = "car.parked"
+	WebhookEventCarUnparked     = "car.unparked"
+	WebhookEventSlotMaintenance = "slot.maintenance"
+	WebhookEventLotFull         = "lot.full"
+	WebhookEventCarRelocated    = "car.relocated"
+)
+
+// WebhookSubscription sends the lifecycle events of one parking lot, or of
+// every lot when ParkingLotID is nil, to an HTTP endpoint.
+type WebhookSubscription struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ParkingLotID *uint     `gorm:"index" json:"parking_lot_id,omitempty"`
+	URL          string    `json:"url"`
+	Secret       string    `json:"secret,omitempty"`                             // Key of the HMAC signature, only shown on creation
+	EventTypes   []string  `gorm:"serializer:json" json:"event_types,omitempty"` // Empty means every event type
+	IsActive     bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Matches reports whether an event of the lot should be sent to the subscription.
+func (sub WebhookSubscription) Matches(eventType string, parkingLotID uint) bool {
+	if !sub.IsActive || (sub.ParkingLotID != nil && *sub.ParkingLotID != parkingLotID) {
+		return false
+	}
+	if len(sub.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range sub.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the data of a lifecycle event.
+type WebhookPayload struct {
+	EventType    string         `json:"event_type"`
+	ParkingLotID uint           `json:"parking_lot_id"`
+	Slot         *ParkingSlot   `json:"slot,omitempty"`
+	FromSlot     *ParkingSlot   `json:"from_slot,omitempty"` // Slot a relocated vehicle left
+	Charge       *ParkingCharge `json:"charge,omitempty"`
+	ParkedAt     *time.Time     `json:"parked_at,omitempty"` // Start of the visit that ended, on car.unparked
+	OccurredAt   time.Time      `json:"occurred_at"`
+}
+
+// OutboxEvent is a lifecycle event written in the same transaction as the
+// state change, waiting to be fanned out to the matching subscriptions.
+type OutboxEvent struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	EventType    string     `json:"event_type"`
+	ParkingLotID uint       `json:"parking_lot_id"`
+	Payload      string     `gorm:"type:text" json:"payload"` // JSON encoded WebhookPayload
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `gorm:"index" json:"dispatched_at,omitempty"`
+	SyncedAt     *time.Time `gorm:"index" json:"synced_at,omitempty"` // Sent to the central instance, edge mode only
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // Gave up after the last retry
+)
+
+// WebhookDelivery tracks sending one event to one subscription.
+type WebhookDelivery struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint                `gorm:"index" json:"subscription_id"`
+	Subscription   WebhookSubscription `json:"-"`
+	OutboxEventID  uint                `json:"outbox_event_id"`
+	OutboxEvent    OutboxEvent         `json:"-"`
+	EventType      string              `json:"event_type"`
+	Status         string              `gorm:"index" json:"status"`
+	Attempts       int                 `json:"attempts"`
+	LastStatusCode int                 `json:"last_status_code,omitempty"`
+	LastError      string              `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time           `gorm:"index" json:"next_attempt_at"`
+	DeliveredAt    *time.Time          `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
@@ -0,0 +1,104 @@
+package overflow
+
+import (
+	"errors"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/search"
+	"time"
+)
+
+// maxAlternatives is the number of alternative lots offered to a driver.
+const maxAlternatives = 3
+
+// Outcome is what a driver turned away by a full lot is offered.
+type Outcome struct {
+	Alternatives []search.Result  `json:"alternatives"`   // Nearest first
+	Hold         *models.SlotHold `json:"hold,omitempty"` // Slot held at the first alternative that had one
+}
+
+// Redirector finds alternatives for vehicles turned away by a full lot among
+// the nearby open lots of the same operator, and can hold a slot at the best
+// of them. Every redirect is recorded for capacity planning.
+type Redirector struct {
+	repo    *repository.Repository
+	index   *search.Index
+	radius  float64
+	holdFor time.Duration
+}
+
+func NewRedirector(repo *repository.Repository, index *search.Index, radius float64, holdFor time.Duration) *Redirector {
+	return &Redirector{repo: repo, index: index, radius: radius, holdFor: holdFor}
+}
+
+// Redirect ranks the alternatives to the full parking lot that have a free
+// slot of slotType and, with hold set, holds one for the car or plate at the
+// nearest alternative that still has one.
+func (r *Redirector) Redirect(fromParkingLotID uint, carID *uint, plate, slotType string, hold bool) (*Outcome, error) {
+	alternatives, err := r.alternatives(fromParkingLotID, slotType)
+	if err != nil {
+		return nil, err
+	}
+	outcome := &Outcome{Alternatives: alternatives}
+
+	if hold {
+		expiresAt := time.Now().Add(r.holdFor)
+		for _, alternative := range alternatives {
+			slotHold, err := r.repo.HoldParkingSlot(alternative.ParkingLotID, fromParkingLotID, carID, plate, slotType, expiresAt)
+			if errors.Is(err, repository.ErrNoAvailableParkingSlot) {
+				// The cached capacity was stale, try the next one
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			outcome.Hold = slotHold
+			break
+		}
+	}
+
+	redirect := models.OverflowRedirect{
+		FromParkingLotID:    fromParkingLotID,
+		CarID:               carID,
+		Plate:               repository.NormalizePlate(plate),
+		AlternativesOffered: len(alternatives),
+	}
+	if outcome.Hold != nil {
+		redirect.ToParkingLotID = &outcome.Hold.ParkingLotID
+	}
+	if err := r.repo.CreateOverflowRedirect(&redirect); err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
+
+// alternatives returns the nearest open lots of the same operator with a free
+// slot of slotType.
+func (r *Redirector) alternatives(fromParkingLotID uint, slotType string) ([]search.Result, error) {
+	alternatives := make([]search.Result, 0, maxAlternatives)
+	parkingLot, err := r.repo.GetParkingLot(fromParkingLotID)
+	if err != nil {
+		return nil, err
+	}
+	if parkingLot.Latitude == nil || parkingLot.Longitude == nil {
+		return alternatives, nil
+	}
+
+	nearby, err := r.index.Nearby(*parkingLot.Latitude, *parkingLot.Longitude, r.radius, slotType, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range nearby {
+		if result.ParkingLotID == fromParkingLotID || result.Operator != parkingLot.Operator {
+			continue
+		}
+		if !result.IsOpen || result.FreeSlots[slotType] == 0 {
+			continue
+		}
+		alternatives = append(alternatives, result)
+		if len(alternatives) == maxAlternatives {
+			break
+		}
+	}
+	return alternatives, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var ErrEntryBlocked = errors.New("entry is blocked")
+
+// EntryBlockedError is returned by the entry flows for a vehicle on the
+// blocklist. It matches ErrEntryBlocked.
+type EntryBlockedError struct {
+	Entry models.AccessListEntry
+}
+
+func (e *EntryBlockedError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrEntryBlocked, e.Entry.Reason)
+}
+
+func (e *EntryBlockedError) Unwrap() error {
+	return ErrEntryBlocked
+}
+
+func (repo *Repository) CreateAccessListEntry(entry *models.AccessListEntry) error {
+	entry.Plate = NormalizePlate(entry.Plate)
+	return repo.DB.Create(entry).Error
+}
+
+// GetAccessListEntries returns the entries of a list that have not expired,
+// newest first. A non-zero parking lot limits them to the ones applying to it.
+func (repo *Repository) GetAccessListEntries(listType string, parkingLotID uint) ([]models.AccessListEntry, error) {
+	query := repo.DB.Where("list_type = ? AND (expires_at IS NULL OR expires_at > ?)", listType, time.Now())
+	if parkingLotID != 0 {
+		query = query.Where("parking_lot_id IS NULL OR parking_lot_id = ?", parkingLotID)
+	}
+
+	var entries []models.AccessListEntry
+	if err := query.Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (repo *Repository) DeleteAccessListEntry(entryID uint) error {
+	result := repo.DB.Delete(&models.AccessListEntry{}, entryID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetBlockedEntries returns the audit trail of refused entries of a parking lot, newest first.
+func (repo *Repository) GetBlockedEntries(parkingLotID uint, limit int) ([]models.BlockedEntry, error) {
+	var blockedEntries []models.BlockedEntry
+	if err := repo.DB.Where("parking_lot_id = ?", parkingLotID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&blockedEntries).
+		Error; err != nil {
+		return nil, err
+	}
+	return blockedEntries, nil
+}
+
+// checkEntry refuses the entry of a blocklisted plate or user into the
+// parking lot and records the refusal in the audit trail.
+func (repo *Repository) checkEntry(parkingLotID uint, plate string, carID, userID *uint) error {
+	entry, err := findAccessListEntry(repo.DB, models.AccessListBlock, parkingLotID, plate, userID, time.Now())
+	if err != nil || entry == nil {
+		return err
+	}
+
+	if err := repo.DB.Create(&models.BlockedEntry{
+		AccessListEntryID: entry.ID,
+		ParkingLotID:      parkingLotID,
+		Plate:             NormalizePlate(plate),
+		CarID:             carID,
+		UserID:            userID,
+		Reason:            entry.Reason,
+	}).Error; err != nil {
+		return err
+	}
+	return &EntryBlockedError{Entry: *entry}
+}
+
+// findAccessListEntry returns the entry of the list matching the plate or the
+// user in the parking lot at now, or nil if there is none.
+func findAccessListEntry(tx *gorm.DB, listType string, parkingLotID uint, plate string, userID *uint, now time.Time) (*models.AccessListEntry, error) {
+	plate = NormalizePlate(plate)
+	var uid uint
+	if userID != nil {
+		uid = *userID
+	}
+	if plate == "" && uid == 0 {
+		return nil, nil
+	}
+
+	var entry models.AccessListEntry
+	if err := tx.Where("list_type = ? AND (expires_at IS NULL OR expires_at > ?)", listType, now).
+		Where("parking_lot_id IS NULL OR parking_lot_id = ?", parkingLotID).
+		Where("(plate <> '' AND plate = ?) OR user_id = ?", plate, uid).
+		Order("id").
+		First(&entry).
+		Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// allowlistedCharge prices the stay of an allowlisted vehicle: the time is
+// recorded but nothing is owed.
+func allowlistedCharge(parkedAt, unparkedAt time.Time) models.ParkingCharge {
+	charge := CalculateParkingCharge(parkedAt, unparkedAt)
+	charge.TotalAmountToBePaid = 0
+	charge.IsFreeOfCharge = true
+	return charge
+}
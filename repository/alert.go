@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/models"
+)
+
+func (repo *Repository) CreateCapacityThreshold(threshold *models.CapacityThreshold) error {
+	return repo.DB.Create(threshold).Error
+}
+
+func (repo *Repository) GetCapacityThresholds(parkingLotID uint) ([]models.CapacityThreshold, error) {
+	var thresholds []models.CapacityThreshold
+	if err := repo.DB.Where("parking_lot_id = ?", parkingLotID).
+		Order("percent").
+		Find(&thresholds).
+		Error; err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+func (repo *Repository) DeleteCapacityThreshold(thresholdID uint) error {
+	result := repo.DB.Delete(&models.CapacityThreshold{}, thresholdID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetCapacityAlerts returns the alert history of a parking lot, newest first.
+func (repo *Repository) GetCapacityAlerts(parkingLotID uint, limit int) ([]models.CapacityAlert, error) {
+	var alerts []models.CapacityAlert
+	if err := repo.DB.Where("parking_lot_id = ?", parkingLotID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&alerts).
+		Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// EvaluateCapacityThresholds compares the current occupancy of the parking
+// lot with its thresholds, raising or clearing them as needed. A threshold
+// with a slot type only counts the slots of that type. Slots in maintenance
+// do not count towards the capacity. The alerts it recorded are returned.
+func (repo *Repository) EvaluateCapacityThresholds(parkingLotID uint) ([]models.CapacityAlert, error) {
+	var alerts []models.CapacityAlert
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the thresholds so concurrent evaluations do not raise twice
+		var thresholds []models.CapacityThreshold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("parking_lot_id = ?", parkingLotID).
+			Find(&thresholds).
+			Error; err != nil {
+			return err
+		}
+		if len(thresholds) == 0 {
+			return nil
+		}
+
+		// Count the slots per type once, every threshold sums the types it covers
+		var counts []struct {
+			SlotType string
+			IsBooked bool
+			Slots    int
+		}
+		if err := tx.Model(&models.ParkingSlot{}).
+			Scopes(inService).
+			Select("slot_type, is_booked, COUNT(*) AS slots").
+			Where("parking_lot_id = ? AND is_in_maintenance = ?", parkingLotID, false).
+			Group("slot_type, is_booked").
+			Scan(&counts).
+			Error; err != nil {
+			return err
+		}
+
+		for _, threshold := range thresholds {
+			capacitySlots, occupiedSlots := 0, 0
+			for _, count := range counts {
+				if threshold.SlotType != "" && count.SlotType != threshold.SlotType {
+					continue
+				}
+				capacitySlots += count.Slots
+				if count.IsBooked {
+					occupiedSlots += count.Slots
+				}
+			}
+			// Without a slot in service there is no occupancy to alert on
+			if capacitySlots == 0 {
+				continue
+			}
+			occupancy := float64(occupiedSlots) * 100 / float64(capacitySlots)
+
+			kind := ""
+			switch {
+			case !threshold.IsTriggered && occupancy >= float64(threshold.Percent):
+				kind = models.CapacityAlertRaised
+			case threshold.IsTriggered && occupancy < float64(threshold.Percent-threshold.HysteresisPercent):
+				kind = models.CapacityAlertCleared
+			default:
+				continue
+			}
+
+			if err := tx.Model(&threshold).Update("is_triggered", kind == models.CapacityAlertRaised).Error; err != nil {
+				return err
+			}
+			alert := models.CapacityAlert{
+				ParkingLotID:     parkingLotID,
+				ThresholdID:      threshold.ID,
+				ThresholdPercent: threshold.Percent,
+				SlotType:         threshold.SlotType,
+				Kind:             kind,
+				OccupiedSlots:    occupiedSlots,
+				CapacitySlots:    capacitySlots,
+				OccupancyPercent: occupancy,
+			}
+			if err := tx.Create(&alert).Error; err != nil {
+				return err
+			}
+			alerts = append(alerts, alert)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+func (repo *Repository) SaveCameraLane(lane *models.CameraLane) error {
+	return repo.DB.Save(lane).Error
+}
+
+func (repo *Repository) GetCameraLane(laneID string) (*models.CameraLane, error) {
+	var lane models.CameraLane
+	if err := repo.DB.First(&lane, "lane_id = ?", laneID).Error; err != nil {
+		return nil, err
+	}
+	return &lane, nil
+}
+
+// GetCarByPlate returns the registered car with the plate, or nil if there is none.
+func (repo *Repository) GetCarByPlate(plate string) (*models.Car, error) {
+	var car models.Car
+	if err := repo.DB.Where("plate = ?", NormalizePlate(plate)).First(&car).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &car, nil
+}
+
+func (repo *Repository) SaveAnprEvent(event *models.AnprEvent) error {
+	return repo.DB.Save(event).Error
+}
+
+func (repo *Repository) GetAnprEvent(eventID uint) (*models.AnprEvent, error) {
+	var event models.AnprEvent
+	if err := repo.DB.First(&event, eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetAnprEvents returns camera events with the given status, oldest first.
+func (repo *Repository) GetAnprEvents(status string) ([]models.AnprEvent, error) {
+	var events []models.AnprEvent
+	if err := repo.DB.Where("status = ?", status).
+		Order("captured_at").
+		Find(&events).
+		Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// HasRecentAnprRead reports whether the plate was already read and handled on
+// the lane within window of capturedAt. Reads still waiting for review or
+// rejected by an attendant do not count.
+func (repo *Repository) HasRecentAnprRead(plate, laneID string, capturedAt time.Time, window time.Duration) (bool, error) {
+	var count int64
+	if err := repo.DB.Model(&models.AnprEvent{}).
+		Where("plate = ? AND lane_id = ?", plate, laneID).
+		Where("captured_at BETWEEN ? AND ?", capturedAt.Add(-window), capturedAt.Add(window)).
+		Where("status NOT IN ?", []string{models.AnprStatusReview, models.AnprStatusRejected}).
+		Count(&count).
+		Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
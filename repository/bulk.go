@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"sort"
+)
+
+var (
+	ErrNoSlotsSelected     = errors.New("no parking slots match the selection")
+	ErrBulkOperationFailed = errors.New("bulk operation failed for some parking slots")
+	ErrUnknownBulkAction   = errors.New("unknown bulk action")
+
+	// errDryRun rolls back the transaction of a dry run.
+	errDryRun = errors.New("dry run")
+)
+
+// SlotSelector picks slots of a parking lot by relative IDs, an inclusive
+// range of relative IDs, a zone or a label. The criteria that are set are
+// combined.
+type SlotSelector struct {
+	RelativeIDs []uint `json:"relative_ids"`
+	RangeFrom   uint   `json:"range_from"`
+	RangeTo     uint   `json:"range_to"`
+	Zone        string `json:"zone"`
+	Label       string `json:"label"`
+}
+
+// BulkUpdateParkingSlots applies the action to every selected slot of the
+// parking lot. It is all-or-nothing: when the action fails for any slot, or
+// a relative ID names no slot of the lot, nothing is changed and
+// ErrBulkOperationFailed is returned with the results.
+// A dry run reports the results without changing anything.
+func (repo *Repository) BulkUpdateParkingSlots(parkingLotID uint, selector SlotSelector, action, value string, dryRun bool) ([]models.BulkSlotResult, error) {
+	var results []models.BulkSlotResult
+	var changed []models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(inService).
+			Where("parking_lot_id = ?", parkingLotID)
+		if len(selector.RelativeIDs) > 0 {
+			query = query.Where("relative_id IN ?", selector.RelativeIDs)
+		}
+		if selector.RangeFrom > 0 || selector.RangeTo > 0 {
+			query = query.Where("relative_id BETWEEN ? AND ?", selector.RangeFrom, selector.RangeTo)
+		}
+		if selector.Zone != "" {
+			query = query.Where("zone = ?", selector.Zone)
+		}
+		if selector.Label != "" {
+			query = query.Where("label = ?", selector.Label)
+		}
+
+		var parkingSlots []models.ParkingSlot
+		if err := query.Order("relative_id").Find(&parkingSlots).Error; err != nil {
+			return err
+		}
+		unknown, err := unknownRelativeIDs(tx, parkingLotID, selector.RelativeIDs)
+		if err != nil {
+			return err
+		}
+		if len(parkingSlots) == 0 && len(unknown) == 0 {
+			return ErrNoSlotsSelected
+		}
+
+		// Relative IDs the lot does not have fail the operation, as a typo
+		// would otherwise go unnoticed
+		failed := len(unknown) > 0
+		for _, relativeID := range unknown {
+			results = append(results, models.BulkSlotResult{
+				RelativeID: relativeID,
+				Result:     models.BulkSlotFailed,
+				Error:      ErrUnknownParkingSlot.Error(),
+			})
+		}
+		for i := range parkingSlots {
+			parkingSlot := &parkingSlots[i]
+			result := models.BulkSlotResult{
+				ParkingSlotID: parkingSlot.ID,
+				RelativeID:    parkingSlot.RelativeID,
+			}
+			reason, err := applyBulkAction(tx, parkingSlot, action, value)
+			switch {
+			case errors.Is(err, ErrUnknownBulkAction):
+				return err
+			case err != nil:
+				result.Result = models.BulkSlotFailed
+				result.Error = err.Error()
+				failed = true
+			case reason == "":
+				result.Result = models.BulkSlotUnchanged
+			default:
+				result.Result = models.BulkSlotChanged
+				changed = append(changed, *parkingSlot)
+			}
+			results = append(results, result)
+		}
+
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].RelativeID < results[j].RelativeID
+		})
+		if failed {
+			return ErrBulkOperationFailed
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return results, nil
+	}
+	if err != nil {
+		return results, err
+	}
+
+	reason := events.ReasonReconfigure
+	if action == models.BulkActionMaintenance || action == models.BulkActionOutOfMaintenance {
+		reason = events.ReasonMaintenance
+	}
+	for _, parkingSlot := range changed {
+		repo.publishSlotChange(parkingSlot, reason)
+	}
+	return results, nil
+}
+
+// unknownRelativeIDs returns the relative IDs that no slot of the parking lot
+// in service has, in ascending order.
+func unknownRelativeIDs(tx *gorm.DB, parkingLotID uint, relativeIDs []uint) ([]uint, error) {
+	if len(relativeIDs) == 0 {
+		return nil, nil
+	}
+	var known []uint
+	if err := tx.Model(&models.ParkingSlot{}).
+		Scopes(inService).
+		Where("parking_lot_id = ? AND relative_id IN ?", parkingLotID, relativeIDs).
+		Pluck("relative_id", &known).
+		Error; err != nil {
+		return nil, err
+	}
+	exists := make(map[uint]bool, len(known))
+	for _, relativeID := range known {
+		exists[relativeID] = true
+	}
+	var unknown []uint
+	for _, relativeID := range relativeIDs {
+		if !exists[relativeID] {
+			exists[relativeID] = true // Reported once when repeated
+			unknown = append(unknown, relativeID)
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+	return unknown, nil
+}
+
+// applyBulkAction applies the action to one slot. It returns the reason of
+// the slot change, or an empty reason if the slot was already in the
+// requested state.
+func applyBulkAction(tx *gorm.DB, parkingSlot *models.ParkingSlot, action, value string) (string, error) {
+	switch action {
+	case models.BulkActionMaintenance, models.BulkActionOutOfMaintenance:
+		inMaintenance := action == models.BulkActionMaintenance
+		if parkingSlot.IsInMaintenance == inMaintenance {
+			return "", nil
+		}
+		if parkingSlot.IsBooked && !parkingSlot.IsInMaintenance {
+			return "", errors.New("parking slot is booked")
+		}
+		if err := setParkingSlotMaintenance(tx, parkingSlot, inMaintenance); err != nil {
+			return "", err
+		}
+		kind := models.MaintenanceEventEnded
+		if inMaintenance {
+			kind = models.MaintenanceEventStarted
+		}
+		if _, err := recordMaintenanceEvent(tx, parkingSlot.ID, nil, kind); err != nil {
+			return "", err
+		}
+		return events.ReasonMaintenance, nil
+	case models.BulkActionSetType:
+		return updateParkingSlotColumn(tx, parkingSlot, "slot_type", &parkingSlot.SlotType, value)
+	case models.BulkActionSetZone:
+		return updateParkingSlotColumn(tx, parkingSlot, "zone", &parkingSlot.Zone, value)
+	case models.BulkActionSetLabel:
+		return updateParkingSlotColumn(tx, parkingSlot, "label", &parkingSlot.Label, value)
+	default:
+		return "", ErrUnknownBulkAction
+	}
+}
+
+func updateParkingSlotColumn(tx *gorm.DB, parkingSlot *models.ParkingSlot, column string, field *string, value string) (string, error) {
+	if *field == value {
+		return "", nil
+	}
+	*field = value
+	if err := tx.Model(parkingSlot).Update(column, value).Error; err != nil {
+		return "", err
+	}
+	return events.ReasonReconfigure, nil
+}
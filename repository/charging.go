@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var (
+	ErrNoCharger              = errors.New("parking slot has no charger")
+	ErrSlotNotOccupied        = errors.New("parking slot is not occupied")
+	ErrNoChargingSession      = errors.New("no charging session in progress in the parking slot")
+	ErrUnknownChargerAction   = errors.New("unknown charger action")
+	ErrUnknownChargingBilling = errors.New("unknown charging billing")
+)
+
+// RecordChargerTelemetry applies a message of the charger of a slot to the
+// charging session of the visit in the slot. A StartTransaction opens the
+// session, or resumes it when the vehicle charges again during its visit.
+// Charging sessions are looked up by slot, so a vehicle moved to another
+// slot takes its session along, see RelocateParkedCar.
+func (repo *Repository) RecordChargerTelemetry(telemetry models.ChargerTelemetry) (*models.ChargingSession, error) {
+	var session models.ChargingSession
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var parkingSlot models.ParkingSlot
+		if err := tx.First(&parkingSlot, telemetry.ParkingSlotID).Error; err != nil {
+			return err
+		}
+		if parkingSlot.ChargerConnector == "" {
+			return ErrNoCharger
+		}
+		if !parkingSlot.IsBooked || parkingSlot.ParkedAt == nil {
+			return ErrSlotNotOccupied
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&session, "parking_slot_id = ? AND parked_at = ?", parkingSlot.ID, *parkingSlot.ParkedAt).
+			Error
+		notFound := errors.Is(err, gorm.ErrRecordNotFound)
+		if err != nil && !notFound {
+			return err
+		}
+
+		switch telemetry.Action {
+		case models.ChargerActionStart:
+			if notFound {
+				session = models.ChargingSession{
+					ParkingLotID:    parkingSlot.ParkingLotID,
+					ParkingSlotID:   parkingSlot.ID,
+					RelativeID:      parkingSlot.RelativeID,
+					CarID:           parkingSlot.CarID,
+					TicketSessionID: parkingSlot.TicketSessionID,
+					ParkedAt:        *parkingSlot.ParkedAt,
+					ConnectorType:   parkingSlot.ChargerConnector,
+					MeterStartWh:    telemetry.MeterWh,
+					StartedAt:       telemetry.Timestamp,
+				}
+			} else if session.Status == models.ChargingSessionCompleted {
+				// Charging again starts a new segment, possibly on the charger
+				// of another slot the vehicle was moved to
+				session.PriorSegmentsWh += session.MeterLastWh - session.MeterStartWh
+				session.MeterStartWh = telemetry.MeterWh
+				session.MeterLastWh = telemetry.MeterWh
+			}
+			session.Status = models.ChargingSessionCharging
+			session.CompletedAt = nil
+		case models.ChargerActionMeterValues, models.ChargerActionStop:
+			if notFound || session.Status != models.ChargingSessionCharging {
+				return ErrNoChargingSession
+			}
+			if telemetry.Action == models.ChargerActionStop {
+				session.Status = models.ChargingSessionCompleted
+				session.CompletedAt = &telemetry.Timestamp
+			}
+		default:
+			return fmt.Errorf("%w: %q", ErrUnknownChargerAction, telemetry.Action)
+		}
+
+		// The meter register only grows, late or repeated messages change nothing
+		if telemetry.MeterWh > session.MeterLastWh {
+			session.MeterLastWh = telemetry.MeterWh
+		}
+		if session.MeterLastWh < session.MeterStartWh {
+			session.MeterLastWh = session.MeterStartWh
+		}
+		session.EnergyKWh = float64(session.PriorSegmentsWh+session.MeterLastWh-session.MeterStartWh) / 1000
+		return tx.Save(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetChargingSessions returns the charging sessions of a parking lot, latest
+// first, optionally only those in the given status.
+func (repo *Repository) GetChargingSessions(parkingLotID uint, status string) ([]models.ChargingSession, error) {
+	query := repo.DB.Where("parking_lot_id = ?", parkingLotID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var sessions []models.ChargingSession
+	if err := query.Order("started_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// SetParkingSlotCharger installs a charger in the slot, or removes it when
+// the connector is empty.
+func (repo *Repository) SetParkingSlotCharger(parkingSlotID uint, connector string, powerKW float64) (*models.ParkingSlot, error) {
+	var parkingSlot models.ParkingSlot
+	if err := repo.DB.First(&parkingSlot, parkingSlotID).Error; err != nil {
+		return nil, err
+	}
+	if connector == "" {
+		powerKW = 0
+	}
+	parkingSlot.ChargerConnector = connector
+	parkingSlot.ChargerPowerKW = powerKW
+	if err := repo.DB.Model(&parkingSlot).
+		Select("charger_connector", "charger_power_kw").
+		Updates(&parkingSlot).
+		Error; err != nil {
+		return nil, err
+	}
+	return &parkingSlot, nil
+}
+
+// UpdateChargingTariff sets how a parking lot bills the energy delivered by
+// its chargers and the time vehicles stay plugged in after charging.
+func (repo *Repository) UpdateChargingTariff(parkingLotID uint, energyRatePerKWh float64, idleFeePerHour, idleGraceMinutes int, billing string) (*models.ParkingLot, error) {
+	if billing != models.ChargingBillingEnergyAndTime && billing != models.ChargingBillingEnergyOnly {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownChargingBilling, billing)
+	}
+
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return nil, err
+	}
+	parkingLot.EnergyRatePerKWh = energyRatePerKWh
+	parkingLot.IdleFeePerHour = idleFeePerHour
+	parkingLot.IdleGraceMinutes = idleGraceMinutes
+	parkingLot.ChargingBilling = billing
+	if err := repo.DB.Model(&parkingLot).
+		Select("energy_rate_per_kwh", "idle_fee_per_hour", "idle_grace_minutes", "charging_billing").
+		Updates(&parkingLot).
+		Error; err != nil {
+		return nil, err
+	}
+	return &parkingLot, nil
+}
+
+// settleChargingSession bills the charging session of the visit ending in the
+// slot into the charge. Energy is billed per kWh and every started hour the
+// vehicle stayed plugged in past the grace period after charging completed
+// costs the idle fee. Under energy-only billing the parking fee is dropped
+// for a visit that charged, while a lost-ticket fee and a valet fee are kept.
+// Nothing is billed for a visit that is free of charge.
+func settleChargingSession(tx *gorm.DB, parkingSlot *models.ParkingSlot, unparkedAt time.Time, charge *models.ParkingCharge) (*models.ChargingSession, error) {
+	var session models.ChargingSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&session, "parking_slot_id = ? AND parked_at = ?", parkingSlot.ID, *parkingSlot.ParkedAt).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var parkingLot models.ParkingLot
+	if err := tx.First(&parkingLot, parkingSlot.ParkingLotID).Error; err != nil {
+		return nil, err
+	}
+
+	// A vehicle leaving while charging stops the charge
+	if session.CompletedAt == nil {
+		session.CompletedAt = &unparkedAt
+	}
+
+	session.EnergyFee = 0
+	session.IdleFee = 0
+	if !charge.IsFreeOfCharge {
+		session.EnergyFee = int(math.Round(session.EnergyKWh * parkingLot.EnergyRatePerKWh))
+		idle := unparkedAt.Sub(*session.CompletedAt) - time.Duration(parkingLot.IdleGraceMinutes)*time.Minute
+		if idle > 0 {
+			session.IdleFee = int(math.Ceil(idle.Hours())) * parkingLot.IdleFeePerHour
+		}
+		if parkingLot.ChargingBilling == models.ChargingBillingEnergyOnly && charge.LostTicketFee == 0 {
+			charge.TotalAmountToBePaid = charge.ValetFee
+		}
+	}
+	session.Status = models.ChargingSessionBilled
+	session.BilledAt = &unparkedAt
+	if err := tx.Save(&session).Error; err != nil {
+		return nil, err
+	}
+
+	charge.EnergyKWh = session.EnergyKWh
+	charge.EnergyFee = session.EnergyFee
+	charge.IdleFee = session.IdleFee
+	charge.TotalAmountToBePaid += session.EnergyFee + session.IdleFee
+	return &session, nil
+}
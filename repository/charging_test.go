@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"gorm.io/gorm/logger"
+	"parkingManagementSystem/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestRepository returns a repository on a migrated SQLite database that
+// is removed with the test.
+func openTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	repo, err := NewRepository(sqliteScheme + filepath.Join(t.TempDir(), "pms.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.DB.Logger = logger.Default.LogMode(logger.Silent)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestSettleChargingSession(t *testing.T) {
+	repo := openTestRepository(t)
+	parkedAt := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	unparkedAt := parkedAt.Add(3 * time.Hour)
+	completedBefore := func(d time.Duration) *time.Time {
+		completedAt := unparkedAt.Add(-d)
+		return &completedAt
+	}
+
+	// The lots charge 0.4 per kWh and 10 per started idle hour after 15
+	// minutes of grace. Every visit parked 3 hours for 30 and drew 12.5 kWh.
+	tests := []struct {
+		name          string
+		billing       string
+		noSession     bool
+		completedAt   *time.Time
+		charge        models.ParkingCharge
+		wantTotal     int
+		wantEnergyFee int
+		wantIdleFee   int
+	}{
+		{name: "visit without charging", noSession: true, charge: models.ParkingCharge{TotalAmountToBePaid: 30}, wantTotal: 30},
+		{name: "still charging at unpark", charge: models.ParkingCharge{TotalAmountToBePaid: 30}, wantTotal: 35, wantEnergyFee: 5},
+		{name: "left within the grace period", completedAt: completedBefore(10 * time.Minute), charge: models.ParkingCharge{TotalAmountToBePaid: 30}, wantTotal: 35, wantEnergyFee: 5},
+		{name: "one started idle hour", completedAt: completedBefore(time.Hour), charge: models.ParkingCharge{TotalAmountToBePaid: 30}, wantTotal: 45, wantEnergyFee: 5, wantIdleFee: 10},
+		{name: "two started idle hours", completedAt: completedBefore(2 * time.Hour), charge: models.ParkingCharge{TotalAmountToBePaid: 30}, wantTotal: 55, wantEnergyFee: 5, wantIdleFee: 20},
+		{name: "energy only drops the parking fee", billing: models.ChargingBillingEnergyOnly, charge: models.ParkingCharge{TotalAmountToBePaid: 30}, wantTotal: 5, wantEnergyFee: 5},
+		{name: "energy only keeps the valet fee", billing: models.ChargingBillingEnergyOnly, charge: models.ParkingCharge{TotalAmountToBePaid: 55, ValetFee: 25}, wantTotal: 30, wantEnergyFee: 5},
+		{name: "energy only keeps a lost ticket fee", billing: models.ChargingBillingEnergyOnly, charge: models.ParkingCharge{TotalAmountToBePaid: 100, LostTicketFee: 100}, wantTotal: 105, wantEnergyFee: 5},
+		{name: "free of charge", completedAt: completedBefore(2 * time.Hour), charge: models.ParkingCharge{IsFreeOfCharge: true}, wantTotal: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			billing := tt.billing
+			if billing == "" {
+				billing = models.ChargingBillingEnergyAndTime
+			}
+			parkingLot := models.ParkingLot{
+				Location:         tt.name,
+				EnergyRatePerKWh: 0.4,
+				IdleFeePerHour:   10,
+				IdleGraceMinutes: 15,
+				ChargingBilling:  billing,
+			}
+			if err := repo.DB.Create(&parkingLot).Error; err != nil {
+				t.Fatal(err)
+			}
+			parkingSlot := models.ParkingSlot{ParkingLotID: parkingLot.ID, RelativeID: 1, IsBooked: true, ParkedAt: &parkedAt}
+			if err := repo.DB.Create(&parkingSlot).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !tt.noSession {
+				status := models.ChargingSessionCharging
+				if tt.completedAt != nil {
+					status = models.ChargingSessionCompleted
+				}
+				if err := repo.DB.Create(&models.ChargingSession{
+					ParkingLotID:  parkingLot.ID,
+					ParkingSlotID: parkingSlot.ID,
+					RelativeID:    parkingSlot.RelativeID,
+					ParkedAt:      parkedAt,
+					EnergyKWh:     12.5,
+					StartedAt:     parkedAt,
+					CompletedAt:   tt.completedAt,
+					Status:        status,
+				}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			charge := tt.charge
+			session, err := settleChargingSession(repo.DB, &parkingSlot, unparkedAt, &charge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if charge.TotalAmountToBePaid != tt.wantTotal || charge.EnergyFee != tt.wantEnergyFee || charge.IdleFee != tt.wantIdleFee {
+				t.Errorf("charge total %d, energy fee %d, idle fee %d, want %d, %d, %d",
+					charge.TotalAmountToBePaid, charge.EnergyFee, charge.IdleFee, tt.wantTotal, tt.wantEnergyFee, tt.wantIdleFee)
+			}
+			if tt.noSession {
+				if session != nil {
+					t.Errorf("settled session %d of a visit without charging", session.ID)
+				}
+				return
+			}
+
+			var stored models.ChargingSession
+			if err := repo.DB.First(&stored, session.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != models.ChargingSessionBilled || stored.BilledAt == nil || stored.CompletedAt == nil {
+				t.Errorf("stored session status %s, billed at %v, completed at %v, want billed with both times", stored.Status, stored.BilledAt, stored.CompletedAt)
+			}
+			if stored.EnergyFee != tt.wantEnergyFee || stored.IdleFee != tt.wantIdleFee {
+				t.Errorf("stored session energy fee %d, idle fee %d, want %d, %d", stored.EnergyFee, stored.IdleFee, tt.wantEnergyFee, tt.wantIdleFee)
+			}
+			if charge.EnergyKWh != 12.5 {
+				t.Errorf("charge energy %g kWh, want 12.5", charge.EnergyKWh)
+			}
+		})
+	}
+}
@@ -0,0 +1,588 @@
+// Package conformance checks that a storage backend behaves the way the
+// parking system relies on: slots are allocated in order and never twice,
+// visits are charged once and background scans are idempotent. The suite
+// runs against a live database through the repository, so the same checks
+// cover every dialect, see the tests and cmd/conformance.
+package conformance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+type check struct {
+	name string
+	run  func(repo *repository.Repository) error
+}
+
+var checks = []check{
+	{"migrations applied", checkMigrations},
+	{"park and unpark", checkParkAndUnpark},
+	{"lowest free slot first", checkAllocationOrder},
+	{"concurrent entries", checkConcurrentEntries},
+	{"ticket closes once", checkTicketClosesOnce},
+	{"valet key tag used once", checkValetKeyTag},
+	{"slot in maintenance skipped", checkMaintenanceSkipped},
+	{"capacity thresholds per slot type", checkCapacityThresholdsBySlotType},
+	{"bulk update rejects unknown slots", checkBulkUnknownSlots},
+	{"overstay scan idempotent", checkOverstayScan},
+	{"exception date upsert", checkExceptionDateUpsert},
+	{"history revenue", checkHistoryRevenue},
+	{"unlinked edge lot skipped", checkUnlinkedEdgeLot},
+}
+
+// Run runs every check against the migrated database of the repository.
+// Each check creates the lots, users and cars it needs, so the suite can
+// run again on the same database.
+func Run(repo *repository.Repository) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		start := time.Now()
+		err := c.run(repo)
+		results = append(results, Result{Name: c.name, Err: err, Duration: time.Since(start)})
+	}
+	return results
+}
+
+func checkMigrations(repo *repository.Repository) error {
+	migrator, err := repo.SchemaMigrator()
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations pending", pending)
+	}
+	return nil
+}
+
+func checkParkAndUnpark(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 2)
+	if err != nil {
+		return err
+	}
+	car, err := createCar(repo)
+	if err != nil {
+		return err
+	}
+	if err := repo.ParkCar(parkingLot.ID, car.ID); err != nil {
+		return err
+	}
+	parkingSlot, err := slotOfCar(repo, car.ID)
+	if err != nil {
+		return err
+	}
+	if err := backdateParking(repo, parkingSlot.ID, 90*time.Minute); err != nil {
+		return err
+	}
+
+	charge, err := repo.UnparkCar(car.ID)
+	if err != nil {
+		return err
+	}
+	if charge.TotalParkingTime != 2 || charge.TotalAmountToBePaid != 2*repository.HourlyParkingRate {
+		return fmt.Errorf("charged %d for %d hours, want %d for 2 hours",
+			charge.TotalAmountToBePaid, charge.TotalParkingTime, 2*repository.HourlyParkingRate)
+	}
+
+	if err := repo.DB.First(parkingSlot, parkingSlot.ID).Error; err != nil {
+		return err
+	}
+	if parkingSlot.IsBooked || parkingSlot.CarID != nil {
+		return fmt.Errorf("slot %d still booked after unpark", parkingSlot.RelativeID)
+	}
+	return nil
+}
+
+func checkAllocationOrder(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 3)
+	if err != nil {
+		return err
+	}
+	for want := uint(1); want <= 3; want++ {
+		car, err := createCar(repo)
+		if err != nil {
+			return err
+		}
+		if err := repo.ParkCar(parkingLot.ID, car.ID); err != nil {
+			return err
+		}
+		parkingSlot, err := slotOfCar(repo, car.ID)
+		if err != nil {
+			return err
+		}
+		if parkingSlot.RelativeID != want {
+			return fmt.Errorf("car parked in slot %d, want %d", parkingSlot.RelativeID, want)
+		}
+	}
+	return nil
+}
+
+// checkConcurrentEntries lets more vehicles than there are slots enter at
+// once. Every slot must be given out exactly once.
+func checkConcurrentEntries(repo *repository.Repository) error {
+	const slots, entries = 5, 20
+	parkingLot, err := createParkingLot(repo, slots)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, entries)
+	sessions := make([]*models.TicketSession, entries)
+	for i := 0; i < entries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sessions[i], errs[i] = repo.IssueTicket(parkingLot.ID, "")
+		}(i)
+	}
+	wg.Wait()
+
+	taken := map[uint]bool{}
+	for i, err := range errs {
+		if errors.Is(err, repository.ErrNoAvailableParkingSlot) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if taken[sessions[i].ParkingSlotID] {
+			return fmt.Errorf("slot %d given out twice", sessions[i].RelativeSlotID)
+		}
+		taken[sessions[i].ParkingSlotID] = true
+	}
+	if len(taken) != slots {
+		return fmt.Errorf("%d of %d entries got a slot, want %d", len(taken), entries, slots)
+	}
+	return nil
+}
+
+// checkTicketClosesOnce closes every ticket from two exits at once: exactly
+// one of them may charge the visit, the other must find it closed.
+func checkTicketClosesOnce(repo *repository.Repository) error {
+	const tickets = 5
+	parkingLot, err := createParkingLot(repo, tickets)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < tickets; i++ {
+		session, err := repo.IssueTicket(parkingLot.ID, "")
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs := make([]error, 2)
+		for exit := range errs {
+			wg.Add(1)
+			go func(exit int) {
+				defer wg.Done()
+				<-start
+				_, errs[exit] = repo.CloseTicket(session.TicketCode, "")
+			}(exit)
+		}
+		close(start)
+		wg.Wait()
+
+		closed := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				closed++
+			case !errors.Is(err, repository.ErrTicketAlreadyClosed):
+				return fmt.Errorf("concurrent close returned %v, want nil or %v", err, repository.ErrTicketAlreadyClosed)
+			}
+		}
+		if closed != 1 {
+			return fmt.Errorf("ticket %s closed %d times by two concurrent exits, want once", session.TicketCode, closed)
+		}
+	}
+	return nil
+}
+
+// checkValetKeyTag checks in two cars with the same key tag at once: only
+// one may get it, and it is free again once that car is delivered.
+func checkValetKeyTag(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 2)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.UpdateValetSettings(parkingLot.ID, true, 25, 10); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, 2)
+	valetTickets := make([]*models.ValetTicket, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			valetTickets[i], errs[i] = repo.ParkValetCar(parkingLot.ID, fmt.Sprintf("VALET%d%d", parkingLot.ID, i), "K1", "conformance")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var parked *models.ValetTicket
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if parked != nil {
+				return fmt.Errorf("key tag K1 given to two cars")
+			}
+			parked = valetTickets[i]
+		case !errors.Is(err, repository.ErrKeyTagInUse):
+			return fmt.Errorf("concurrent check-in returned %v, want nil or %v", err, repository.ErrKeyTagInUse)
+		}
+	}
+	if parked == nil {
+		return fmt.Errorf("neither check-in got key tag K1")
+	}
+
+	if _, _, err := repo.DeliverValetCar(parked.ID, "conformance"); err != nil {
+		return err
+	}
+	if _, err := repo.ParkValetCar(parkingLot.ID, fmt.Sprintf("VALET%d2", parkingLot.ID), "K1", "conformance"); err != nil {
+		return fmt.Errorf("check-in with the key tag of a delivered car: %w", err)
+	}
+	return nil
+}
+
+func checkMaintenanceSkipped(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 2)
+	if err != nil {
+		return err
+	}
+	var firstSlot models.ParkingSlot
+	if err := repo.DB.Where("parking_lot_id = ? AND relative_id = ?", parkingLot.ID, 1).
+		First(&firstSlot).
+		Error; err != nil {
+		return err
+	}
+	if err := repo.SetParkingSlotMaintenance(&firstSlot, true); err != nil {
+		return err
+	}
+
+	session, err := repo.IssueTicket(parkingLot.ID, "")
+	if err != nil {
+		return err
+	}
+	if session.RelativeSlotID != 2 {
+		return fmt.Errorf("ticket issued for slot %d, want 2", session.RelativeSlotID)
+	}
+	if _, err := repo.IssueTicket(parkingLot.ID, ""); !errors.Is(err, repository.ErrNoAvailableParkingSlot) {
+		return fmt.Errorf("entry to a full lot returned %v, want %v", err, repository.ErrNoAvailableParkingSlot)
+	}
+	return nil
+}
+
+func checkCapacityThresholdsBySlotType(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 2)
+	if err != nil {
+		return err
+	}
+	if err := repo.DB.Model(&models.ParkingSlot{}).
+		Where("parking_lot_id = ? AND relative_id = ?", parkingLot.ID, 2).
+		Update("slot_type", models.SlotTypeAccessible).
+		Error; err != nil {
+		return err
+	}
+	// Slot 1, the standard one, is taken: the lot is half full. The lot has no
+	// compact slot, so its compact threshold has nothing to count.
+	thresholds := map[string]*models.CapacityThreshold{
+		"all":        {ParkingLotID: parkingLot.ID, Percent: 50, HysteresisPercent: 5},
+		"standard":   {ParkingLotID: parkingLot.ID, SlotType: models.SlotTypeStandard, Percent: 100, HysteresisPercent: 5},
+		"accessible": {ParkingLotID: parkingLot.ID, SlotType: models.SlotTypeAccessible, Percent: 50, HysteresisPercent: 5},
+		"compact":    {ParkingLotID: parkingLot.ID, SlotType: models.SlotTypeCompact, Percent: 50, HysteresisPercent: 5},
+	}
+	for _, threshold := range thresholds {
+		if err := repo.CreateCapacityThreshold(threshold); err != nil {
+			return err
+		}
+	}
+	if _, err := repo.IssueTicket(parkingLot.ID, ""); err != nil {
+		return err
+	}
+
+	alerts, err := repo.EvaluateCapacityThresholds(parkingLot.ID)
+	if err != nil {
+		return err
+	}
+	raised := map[uint]models.CapacityAlert{}
+	for _, alert := range alerts {
+		raised[alert.ThresholdID] = alert
+	}
+	if len(raised) != 2 {
+		return fmt.Errorf("%d thresholds raised, want the lot and its standard slots", len(raised))
+	}
+	if alert, ok := raised[thresholds["standard"].ID]; !ok || alert.OccupiedSlots != 1 || alert.CapacitySlots != 1 {
+		return fmt.Errorf("standard slots alert %+v, want 1 of 1 slots occupied", alert)
+	}
+	if alert, ok := raised[thresholds["all"].ID]; !ok || alert.OccupiedSlots != 1 || alert.CapacitySlots != 2 {
+		return fmt.Errorf("lot alert %+v, want 1 of 2 slots occupied", alert)
+	}
+	return nil
+}
+
+func checkBulkUnknownSlots(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 2)
+	if err != nil {
+		return err
+	}
+	selector := repository.SlotSelector{RelativeIDs: []uint{1, 7}}
+	results, err := repo.BulkUpdateParkingSlots(parkingLot.ID, selector, models.BulkActionMaintenance, "", false)
+	if !errors.Is(err, repository.ErrBulkOperationFailed) {
+		return fmt.Errorf("bulk update with an unknown slot returned %v, want %v", err, repository.ErrBulkOperationFailed)
+	}
+	if len(results) != 2 || results[1].RelativeID != 7 || results[1].Result != models.BulkSlotFailed {
+		return fmt.Errorf("results %+v, want slot 7 reported as failed", results)
+	}
+
+	// Nothing may have changed for the known slot either
+	var inMaintenance int64
+	if err := repo.DB.Model(&models.ParkingSlot{}).
+		Where("parking_lot_id = ? AND is_in_maintenance = ?", parkingLot.ID, true).
+		Count(&inMaintenance).
+		Error; err != nil {
+		return err
+	}
+	if inMaintenance != 0 {
+		return fmt.Errorf("%d slots put in maintenance by a failed bulk update", inMaintenance)
+	}
+	return nil
+}
+
+func checkOverstayScan(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 1)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.UpdateOverstayPolicy(parkingLot.ID, 60, 50, 120); err != nil {
+		return err
+	}
+	session, err := repo.IssueTicket(parkingLot.ID, "")
+	if err != nil {
+		return err
+	}
+	if err := backdateParking(repo, session.ParkingSlotID, 90*time.Minute); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := repo.ScanOverstays(now); err != nil {
+			return err
+		}
+	}
+	var violations int64
+	if err := repo.DB.Model(&models.OverstayViolation{}).
+		Where("parking_slot_id = ?", session.ParkingSlotID).
+		Count(&violations).
+		Error; err != nil {
+		return err
+	}
+	if violations != 1 {
+		return fmt.Errorf("%d violations after two scans, want 1", violations)
+	}
+	return nil
+}
+
+func checkExceptionDateUpsert(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 1)
+	if err != nil {
+		return err
+	}
+	const date = "2030-12-25"
+	if err := repo.SaveLotExceptionDates([]models.LotExceptionDate{
+		{ParkingLotID: parkingLot.ID, Date: date, Closed: true, Reason: "Christmas"},
+	}); err != nil {
+		return err
+	}
+	if err := repo.SaveLotExceptionDates([]models.LotExceptionDate{
+		{ParkingLotID: parkingLot.ID, Date: date, Opens: "10:00", Closes: "14:00", Reason: "Short hours"},
+	}); err != nil {
+		return err
+	}
+
+	exceptions, err := repo.GetLotExceptionDates(parkingLot.ID, date)
+	if err != nil {
+		return err
+	}
+	if len(exceptions) != 1 {
+		return fmt.Errorf("%d exception dates stored, want 1", len(exceptions))
+	}
+	if exceptions[0].Closed || exceptions[0].Opens != "10:00" {
+		return fmt.Errorf("exception date not replaced: %+v", exceptions[0])
+	}
+	return nil
+}
+
+func checkHistoryRevenue(repo *repository.Repository) error {
+	before, err := todaysHistory(repo)
+	if err != nil {
+		return err
+	}
+
+	parkingLot, err := createParkingLot(repo, 1)
+	if err != nil {
+		return err
+	}
+	session, err := repo.IssueTicket(parkingLot.ID, "")
+	if err != nil {
+		return err
+	}
+	if err := backdateParking(repo, session.ParkingSlotID, 30*time.Minute); err != nil {
+		return err
+	}
+	if _, err := repo.CloseTicket(session.TicketCode, ""); err != nil {
+		return err
+	}
+
+	after, err := todaysHistory(repo)
+	if err != nil {
+		return err
+	}
+	if after.CarsParked-before.CarsParked != 1 {
+		return fmt.Errorf("cars parked grew by %d, want 1", after.CarsParked-before.CarsParked)
+	}
+	if revenue := after.TotalRevenueEarned - before.TotalRevenueEarned; revenue != repository.HourlyParkingRate {
+		return fmt.Errorf("revenue grew by %d, want %d", revenue, repository.HourlyParkingRate)
+	}
+	return nil
+}
+
+// checkUnlinkedEdgeLot sends an entry into an unlinked edge lot ahead of one
+// into a linked lot: the first is skipped as a conflict, the second applied.
+func checkUnlinkedEdgeLot(repo *repository.Repository) error {
+	parkingLot, err := createParkingLot(repo, 1)
+	if err != nil {
+		return err
+	}
+	siteID := fmt.Sprintf("conformance-%d", parkingLot.ID)
+	if err := repo.LinkEdgeLot(&models.EdgeLotLink{SiteID: siteID, EdgeLotID: 1, ParkingLotID: parkingLot.ID}); err != nil {
+		return err
+	}
+
+	parkedAt := time.Now()
+	var syncEvents []models.SyncEvent
+	for i, edgeLotID := range []uint{2, 1} {
+		data, err := json.Marshal(models.WebhookPayload{
+			EventType:    models.WebhookEventCarParked,
+			ParkingLotID: edgeLotID,
+			Slot:         &models.ParkingSlot{RelativeID: 1, IsBooked: true, ParkedAt: &parkedAt},
+			OccurredAt:   parkedAt,
+		})
+		if err != nil {
+			return err
+		}
+		syncEvents = append(syncEvents, models.SyncEvent{ID: uint(i + 1), EventType: models.WebhookEventCarParked, CreatedAt: parkedAt, Data: data})
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		applied, conflicts, err := repo.ApplyEdgeEvents(siteID, syncEvents)
+		if err != nil {
+			return err
+		}
+		if len(applied) != 2 {
+			return fmt.Errorf("attempt %d: %d events applied, want both", attempt+1, len(applied))
+		}
+		// Sent again, the events are acknowledged without a new conflict
+		wantConflicts := 1 - attempt
+		if len(conflicts) != wantConflicts {
+			return fmt.Errorf("attempt %d: %d conflicts, want %d", attempt+1, len(conflicts), wantConflicts)
+		}
+		if wantConflicts == 1 && conflicts[0].Kind != models.SyncConflictLotUnlinked {
+			return fmt.Errorf("conflict %s, want %s", conflicts[0].Kind, models.SyncConflictLotUnlinked)
+		}
+	}
+
+	parkingSlots, err := repo.GetParkingSlots(parkingLot.ID)
+	if err != nil {
+		return err
+	}
+	if len(parkingSlots) != 1 || !parkingSlots[0].IsBooked {
+		return fmt.Errorf("entry into the linked lot was not applied")
+	}
+	return nil
+}
+
+func createParkingLot(repo *repository.Repository, slots int) (*models.ParkingLot, error) {
+	parkingLot := models.ParkingLot{Name: "Conformance", Location: "conformance", LostTicketFee: models.DefaultLostTicketFee}
+	if err := repo.DB.Create(&parkingLot).Error; err != nil {
+		return nil, err
+	}
+	for i := 1; i <= slots; i++ {
+		if err := repo.DB.Create(&models.ParkingSlot{
+			ParkingLotID: parkingLot.ID,
+			RelativeID:   uint(i),
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &parkingLot, nil
+}
+
+func createCar(repo *repository.Repository) (*models.Car, error) {
+	user := models.User{Name: "Conformance"}
+	if err := repo.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	car := models.Car{UserID: user.ID, Plate: fmt.Sprintf("CONF%d", user.ID)}
+	if err := repo.DB.Create(&car).Error; err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+func slotOfCar(repo *repository.Repository, carID uint) (*models.ParkingSlot, error) {
+	var parkingSlot models.ParkingSlot
+	if err := repo.DB.Where("car_id = ?", carID).First(&parkingSlot).Error; err != nil {
+		return nil, err
+	}
+	return &parkingSlot, nil
+}
+
+// backdateParking moves the start of the visit in the slot into the past,
+// so it is charged for a known duration.
+func backdateParking(repo *repository.Repository, parkingSlotID uint, ago time.Duration) error {
+	parkedAt := time.Now().Add(-ago)
+	if err := repo.DB.Model(&models.ParkingSlot{}).
+		Where("id = ?", parkingSlotID).
+		Update("parked_at", parkedAt).
+		Error; err != nil {
+		return err
+	}
+	return repo.DB.Model(&models.TicketSession{}).
+		Where("parking_slot_id = ? AND exited_at IS NULL", parkingSlotID).
+		Update("entered_at", parkedAt).
+		Error
+}
+
+func todaysHistory(repo *repository.Repository) (models.ParkingHistory, error) {
+	var parkingHistory models.ParkingHistory
+	err := repo.DB.Where("date = ?", time.Now().Truncate(24*time.Hour)).
+		Limit(1).
+		Find(&parkingHistory).
+		Error
+	return parkingHistory, err
+}
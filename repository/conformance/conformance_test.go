@@ -0,0 +1,44 @@
+package conformance
+
+import (
+	"gorm.io/gorm/logger"
+	"os"
+	"parkingManagementSystem/repository"
+	"path/filepath"
+	"testing"
+)
+
+// postgresURLVariable names the scratch PostgreSQL database the suite also
+// runs against, it is skipped when unset.
+const postgresURLVariable = "PMS_TEST_POSTGRES_URL"
+
+func TestSQLite(t *testing.T) {
+	runChecks(t, "sqlite://"+filepath.Join(t.TempDir(), "pms-conformance.db"))
+}
+
+func TestPostgres(t *testing.T) {
+	databaseUrl := os.Getenv(postgresURLVariable)
+	if databaseUrl == "" {
+		t.Skipf("%s is not set", postgresURLVariable)
+	}
+	runChecks(t, databaseUrl)
+}
+
+func runChecks(t *testing.T, databaseUrl string) {
+	repo, err := repository.NewRepository(databaseUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Expected misses and refused entries are not worth a log line
+	repo.DB.Logger = logger.Default.LogMode(logger.Silent)
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.run(repo); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
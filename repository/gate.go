@@ -0,0 +1,20 @@
+package repository
+
+import "parkingManagementSystem/models"
+
+func (repo *Repository) CreateGateEvent(event *models.GateEvent) error {
+	return repo.DB.Create(event).Error
+}
+
+// GetGateEvents returns the latest decisions taken for a gate, newest first.
+func (repo *Repository) GetGateEvents(gateID string, limit int) ([]models.GateEvent, error) {
+	var events []models.GateEvent
+	if err := repo.DB.Where("gate_id = ?", gateID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).
+		Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// recordParkingHistory adds a finished visit to the history row of the day it ended.
+func recordParkingHistory(tx *gorm.DB, unparkedAt time.Time, charge models.ParkingCharge) error {
+	date := unparkedAt.Truncate(24 * time.Hour)
+	var parkingHistory models.ParkingHistory
+	// The date is passed as a struct so a new row is created with it
+	if err := tx.FirstOrCreate(&parkingHistory, models.ParkingHistory{Date: date}).Error; err != nil {
+		return err
+	}
+	parkingHistory.CarsParked += 1
+	parkingHistory.TotalParkingTime += charge.TotalParkingTime
+	parkingHistory.TotalRevenueEarned += int64(charge.TotalAmountToBePaid)
+	parkingHistory.LostTicketRevenue += int64(charge.LostTicketFee)
+	parkingHistory.OverstayFineRevenue += int64(charge.OverstayFine)
+	parkingHistory.EnergyRevenue += int64(charge.EnergyFee + charge.IdleFee)
+	parkingHistory.ValetRevenue += int64(charge.ValetFee)
+	return tx.Save(&parkingHistory).Error
+}
+
+// GetParkingHistory returns the history rows of the days from the first date
+// up to, but not including, the second one.
+func (repo *Repository) GetParkingHistory(from, to time.Time) ([]models.ParkingHistory, error) {
+	var history []models.ParkingHistory
+	if err := repo.DB.Where("date >= ? AND date < ?", from, to).
+		Order("date").
+		Find(&history).
+		Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/schedule"
+	"time"
+)
+
+var ErrLotClosed = errors.New("parking lot is closed")
+
+// GetLotSchedule returns the parking lot with the schedule built from its
+// opening hours and exception dates.
+func (repo *Repository) GetLotSchedule(parkingLotID uint) (*models.ParkingLot, *schedule.Schedule, error) {
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return nil, nil, err
+	}
+	lotSchedule, err := loadSchedule(repo.DB, parkingLot)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &parkingLot, lotSchedule, nil
+}
+
+func (repo *Repository) GetOpeningHours(parkingLotID uint) ([]models.OpeningHours, error) {
+	var hours []models.OpeningHours
+	if err := repo.DB.Where("parking_lot_id = ?", parkingLotID).
+		Order("weekday, opens").
+		Find(&hours).
+		Error; err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// SetOpeningHours replaces the weekly opening hours of a parking lot, and
+// sets the time zone they are given in and the overnight fee.
+func (repo *Repository) SetOpeningHours(parkingLotID uint, timezone string, overnightFee int, hours []models.OpeningHours) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ParkingLot{}).
+			Where("id = ?", parkingLotID).
+			Updates(map[string]interface{}{"timezone": timezone, "overnight_fee": overnightFee})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("parking_lot_id = ?", parkingLotID).Delete(&models.OpeningHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		for i := range hours {
+			hours[i].ID = 0
+			hours[i].ParkingLotID = parkingLotID
+		}
+		return tx.Create(&hours).Error
+	})
+}
+
+// GetLotExceptionDates returns the exception dates of a parking lot from the given date on.
+func (repo *Repository) GetLotExceptionDates(parkingLotID uint, from string) ([]models.LotExceptionDate, error) {
+	var exceptions []models.LotExceptionDate
+	if err := repo.DB.Where("parking_lot_id = ? AND date >= ?", parkingLotID, from).
+		Order("date").
+		Find(&exceptions).
+		Error; err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
+
+// SaveLotExceptionDates stores exception dates, replacing the ones the
+// parking lot already has on the same dates.
+func (repo *Repository) SaveLotExceptionDates(exceptions []models.LotExceptionDate) error {
+	if len(exceptions) == 0 {
+		return nil
+	}
+	return repo.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "parking_lot_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"closed", "opens", "closes", "reason"}),
+	}).Create(&exceptions).Error
+}
+
+func (repo *Repository) DeleteLotExceptionDate(exceptionID uint) error {
+	result := repo.DB.Delete(&models.LotExceptionDate{}, exceptionID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// checkOpen refuses entries into a parking lot outside its opening hours.
+func (repo *Repository) checkOpen(parkingLotID uint, now time.Time) error {
+	_, lotSchedule, err := repo.GetLotSchedule(parkingLotID)
+	if err != nil {
+		return err
+	}
+	if !lotSchedule.IsOpen(now) {
+		return ErrLotClosed
+	}
+	return nil
+}
+
+// overnightFee returns the overnight fee of the slot's lot when the stay
+// spanned a time the lot was closed.
+func overnightFee(tx *gorm.DB, parkingLotID uint, parkedAt, unparkedAt time.Time) (int, error) {
+	var parkingLot models.ParkingLot
+	if err := tx.First(&parkingLot, parkingLotID).Error; err != nil {
+		return 0, err
+	}
+	if parkingLot.OvernightFee <= 0 {
+		return 0, nil
+	}
+
+	lotSchedule, err := loadSchedule(tx, parkingLot)
+	if err != nil {
+		return 0, err
+	}
+	if !lotSchedule.ClosedDuring(parkedAt, unparkedAt) {
+		return 0, nil
+	}
+	return parkingLot.OvernightFee, nil
+}
+
+// GetLotSchedules returns the schedules of the parking lots by lot ID, with
+// their opening hours and exception dates loaded in one query each.
+func (repo *Repository) GetLotSchedules(parkingLots []models.ParkingLot) (map[uint]*schedule.Schedule, error) {
+	parkingLotIDs := make([]uint, 0, len(parkingLots))
+	for _, parkingLot := range parkingLots {
+		parkingLotIDs = append(parkingLotIDs, parkingLot.ID)
+	}
+	var hours []models.OpeningHours
+	if err := repo.DB.Where("parking_lot_id IN ?", parkingLotIDs).Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	var exceptions []models.LotExceptionDate
+	if err := repo.DB.Where("parking_lot_id IN ?", parkingLotIDs).Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+
+	hoursByLot := make(map[uint][]models.OpeningHours)
+	for _, h := range hours {
+		hoursByLot[h.ParkingLotID] = append(hoursByLot[h.ParkingLotID], h)
+	}
+	exceptionsByLot := make(map[uint][]models.LotExceptionDate)
+	for _, exception := range exceptions {
+		exceptionsByLot[exception.ParkingLotID] = append(exceptionsByLot[exception.ParkingLotID], exception)
+	}
+
+	schedules := make(map[uint]*schedule.Schedule, len(parkingLots))
+	for _, parkingLot := range parkingLots {
+		lotSchedule, err := schedule.New(parkingLot.Timezone, hoursByLot[parkingLot.ID], exceptionsByLot[parkingLot.ID])
+		if err != nil {
+			return nil, err
+		}
+		schedules[parkingLot.ID] = lotSchedule
+	}
+	return schedules, nil
+}
+
+func loadSchedule(tx *gorm.DB, parkingLot models.ParkingLot) (*schedule.Schedule, error) {
+	var hours []models.OpeningHours
+	if err := tx.Where("parking_lot_id = ?", parkingLot.ID).Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	var exceptions []models.LotExceptionDate
+	if err := tx.Where("parking_lot_id = ?", parkingLot.ID).Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	return schedule.New(parkingLot.Timezone, hours, exceptions)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// FindOpenTicketSessions returns the open walk-in sessions matching a plate,
+// in the given parking lot unless it is zero, or the one parked in the given
+// slot of a parking lot when no plate is set.
+func (repo *Repository) FindOpenTicketSessions(plate string, parkingLotID uint, relativeSlotID uint) ([]models.TicketSession, error) {
+	query := repo.DB.Where("exited_at IS NULL")
+	if plate != "" {
+		query = query.Where("plate = ?", NormalizePlate(plate))
+		if parkingLotID != 0 {
+			query = query.Where("parking_lot_id = ?", parkingLotID)
+		}
+	} else {
+		query = query.Where("parking_lot_id = ? AND relative_slot_id = ?", parkingLotID, relativeSlotID)
+	}
+
+	var sessions []models.TicketSession
+	if err := query.Order("entered_at").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// CloseLostTicket closes an open walk-in session whose ticket was lost. The
+// charge follows the lost-ticket policy of the session's parking lot, and the
+// session is marked with the attendant and flagged for audit review.
+func (repo *Repository) CloseLostTicket(ticketSessionID uint, attendant string) (*models.TicketSession, error) {
+	var session models.TicketSession
+	if err := repo.DB.First(&session, ticketSessionID).Error; err != nil {
+		return nil, err
+	}
+	if session.ExitedAt != nil {
+		return nil, ErrTicketAlreadyClosed
+	}
+	if session.IsValet {
+		return nil, ErrValetTicket
+	}
+
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, session.ParkingLotID).Error; err != nil {
+		return nil, err
+	}
+
+	_, err := repo.closeTicketSession(&session, lostTicketCharge(parkingLot), map[string]interface{}{
+		"is_lost_ticket":     true,
+		"handled_by":         attendant,
+		"needs_audit_review": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session.IsLostTicket = true
+	session.HandledBy = attendant
+	session.NeedsAuditReview = true
+	return &session, nil
+}
+
+// GetTicketSessionsForAudit returns the sessions flagged for audit review, newest first.
+func (repo *Repository) GetTicketSessionsForAudit() ([]models.TicketSession, error) {
+	var sessions []models.TicketSession
+	if err := repo.DB.Where("needs_audit_review = ?", true).
+		Order("exited_at DESC").
+		Find(&sessions).
+		Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// lostTicketCharge prices a lost ticket by the lot policy. The whole amount
+// is reported as lost-ticket revenue in the history.
+func lostTicketCharge(parkingLot models.ParkingLot) chargeFunc {
+	return func(parkedAt, unparkedAt time.Time) models.ParkingCharge {
+		charge := CalculateParkingCharge(parkedAt, unparkedAt)
+		if parkingLot.LostTicketPolicy != models.LostTicketPolicyElapsedTime {
+			charge.TotalAmountToBePaid = parkingLot.LostTicketFee
+		}
+		charge.LostTicketFee = charge.TotalAmountToBePaid
+		return charge
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// inService limits a parking slot query to the slots that are not decommissioned.
+func inService(db *gorm.DB) *gorm.DB {
+	return db.Where("decommissioned_at IS NULL")
+}
+
+func (repo *Repository) GetParkingLot(parkingLotID uint) (*models.ParkingLot, error) {
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return nil, err
+	}
+	return &parkingLot, nil
+}
+
+// GetParkingLots returns every parking lot, by ID.
+func (repo *Repository) GetParkingLots() ([]models.ParkingLot, error) {
+	var parkingLots []models.ParkingLot
+	if err := repo.DB.Order("id").Find(&parkingLots).Error; err != nil {
+		return nil, err
+	}
+	return parkingLots, nil
+}
+
+// CreateParkingLot creates a parking lot with the given number of slots,
+// numbered from 1.
+func (repo *Repository) CreateParkingLot(parkingLot *models.ParkingLot, slots int) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(parkingLot).Error; err != nil {
+			return err
+		}
+		parkingSlots := make([]models.ParkingSlot, 0, slots)
+		for i := 1; i <= slots; i++ {
+			parkingSlots = append(parkingSlots, models.ParkingSlot{
+				ParkingLotID: parkingLot.ID,
+				RelativeID:   uint(i),
+			})
+		}
+		return tx.Create(&parkingSlots).Error
+	})
+}
+
+// GetParkingSlots returns the slots of a parking lot that are in service, by relative ID.
+func (repo *Repository) GetParkingSlots(parkingLotID uint) ([]models.ParkingSlot, error) {
+	var parkingSlots []models.ParkingSlot
+	if err := repo.DB.Scopes(inService).
+		Where("parking_lot_id = ?", parkingLotID).
+		Order("relative_id").
+		Find(&parkingSlots).
+		Error; err != nil {
+		return nil, err
+	}
+	return parkingSlots, nil
+}
+
+// GetParkingSlot returns a parking slot by ID.
+func (repo *Repository) GetParkingSlot(parkingSlotID uint) (*models.ParkingSlot, error) {
+	var parkingSlot models.ParkingSlot
+	if err := repo.DB.First(&parkingSlot, parkingSlotID).Error; err != nil {
+		return nil, err
+	}
+	return &parkingSlot, nil
+}
+
+// UpdateParkingLotDetails stores the descriptive fields and the lost-ticket
+// policy of a parking lot.
+func (repo *Repository) UpdateParkingLotDetails(parkingLot *models.ParkingLot) error {
+	return repo.DB.Model(parkingLot).
+		Select("name", "location", "description", "operator", "address_street", "address_city", "address_postal_code", "address_country",
+			"latitude", "longitude", "lost_ticket_policy", "lost_ticket_fee").
+		Updates(parkingLot).
+		Error
+}
+
+// AddParkingSlots adds slots to a parking lot. They are numbered after the
+// highest relative ID ever used in the lot, so existing numbers never change.
+func (repo *Repository) AddParkingSlots(parkingLotID uint, count int) ([]models.ParkingSlot, error) {
+	parkingSlots := make([]models.ParkingSlot, 0, count)
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the lot so concurrent calls do not hand out the same numbers
+		var parkingLot models.ParkingLot
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&parkingLot, parkingLotID).
+			Error; err != nil {
+			return err
+		}
+
+		var highestRelativeID uint
+		if err := tx.Model(&models.ParkingSlot{}).
+			Where("parking_lot_id = ?", parkingLotID).
+			Select("COALESCE(MAX(relative_id), 0)").
+			Scan(&highestRelativeID).
+			Error; err != nil {
+			return err
+		}
+
+		for i := 1; i <= count; i++ {
+			parkingSlots = append(parkingSlots, models.ParkingSlot{
+				ParkingLotID: parkingLotID,
+				RelativeID:   highestRelativeID + uint(i),
+			})
+		}
+		return tx.Create(&parkingSlots).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, parkingSlot := range parkingSlots {
+		repo.publishSlotChange(parkingSlot, events.ReasonReconfigure)
+	}
+	return parkingSlots, nil
+}
+
+// DecommissionParkingSlots takes the slots with the given relative IDs out of
+// service. Empty slots are decommissioned right away. Occupied slots fail the
+// whole call unless drain is set, in which case they are no longer allocated
+// and are decommissioned when the parked car leaves. Decommissioned slots are
+// kept so past sessions still point to them.
+func (repo *Repository) DecommissionParkingSlots(parkingLotID uint, relativeIDs []uint, drain bool) ([]models.BulkSlotResult, error) {
+	wanted := make(map[uint]bool, len(relativeIDs))
+	for _, relativeID := range relativeIDs {
+		wanted[relativeID] = true
+	}
+
+	var results []models.BulkSlotResult
+	var changed []models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var parkingSlots []models.ParkingSlot
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("parking_lot_id = ? AND relative_id IN ?", parkingLotID, relativeIDs).
+			Order("relative_id").
+			Find(&parkingSlots).
+			Error; err != nil {
+			return err
+		}
+		if len(parkingSlots) != len(wanted) {
+			return ErrUnknownParkingSlot
+		}
+
+		failed := false
+		now := time.Now()
+		for i := range parkingSlots {
+			parkingSlot := &parkingSlots[i]
+			result := models.BulkSlotResult{
+				ParkingSlotID: parkingSlot.ID,
+				RelativeID:    parkingSlot.RelativeID,
+			}
+			switch {
+			case parkingSlot.DecommissionedAt != nil || parkingSlot.IsDraining:
+				result.Result = models.BulkSlotUnchanged
+			case parkingSlot.IsBooked && !parkingSlot.IsInMaintenance && !drain:
+				result.Result = models.BulkSlotFailed
+				result.Error = "parking slot is booked"
+				failed = true
+			case parkingSlot.IsBooked && !parkingSlot.IsInMaintenance:
+				parkingSlot.IsDraining = true
+				result.Result = models.BulkSlotDraining
+			default:
+				parkingSlot.IsBooked = false
+				parkingSlot.IsInMaintenance = false
+				parkingSlot.DecommissionedAt = &now
+				result.Result = models.BulkSlotChanged
+			}
+			results = append(results, result)
+
+			if result.Result == models.BulkSlotChanged || result.Result == models.BulkSlotDraining {
+				if err := tx.Save(parkingSlot).Error; err != nil {
+					return err
+				}
+				changed = append(changed, *parkingSlot)
+			}
+		}
+
+		if failed {
+			return ErrBulkOperationFailed
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrBulkOperationFailed) {
+			return results, err
+		}
+		return nil, err
+	}
+
+	for _, parkingSlot := range changed {
+		repo.publishSlotChange(parkingSlot, events.ReasonReconfigure)
+	}
+	return results, nil
+}
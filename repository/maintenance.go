@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var (
+	ErrUnknownParkingSlot = errors.New("parking slot not found in the parking lot")
+	ErrWorkOrderFinished  = errors.New("maintenance work order is already finished")
+)
+
+// CreateMaintenanceWorkOrder schedules a work order for the slots of its
+// parking lot with the given relative IDs.
+func (repo *Repository) CreateMaintenanceWorkOrder(order *models.MaintenanceWorkOrder, relativeIDs []uint) error {
+	wanted := make(map[uint]bool, len(relativeIDs))
+	for _, relativeID := range relativeIDs {
+		wanted[relativeID] = true
+	}
+
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		var parkingSlots []models.ParkingSlot
+		if err := tx.Scopes(inService).
+			Where("parking_lot_id = ? AND relative_id IN ?", order.ParkingLotID, relativeIDs).
+			Order("relative_id").
+			Find(&parkingSlots).
+			Error; err != nil {
+			return err
+		}
+		if len(parkingSlots) != len(wanted) {
+			return ErrUnknownParkingSlot
+		}
+
+		order.Status = models.WorkOrderScheduled
+		order.Slots = make([]models.MaintenanceWorkOrderSlot, 0, len(parkingSlots))
+		for _, parkingSlot := range parkingSlots {
+			order.Slots = append(order.Slots, models.MaintenanceWorkOrderSlot{
+				ParkingSlotID: parkingSlot.ID,
+				RelativeID:    parkingSlot.RelativeID,
+				Status:        models.WorkOrderSlotPending,
+			})
+		}
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+
+		for _, orderSlot := range order.Slots {
+			if _, err := recordMaintenanceEvent(tx, orderSlot.ParkingSlotID, &order.ID, models.MaintenanceEventScheduled); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (repo *Repository) GetMaintenanceWorkOrder(workOrderID uint) (*models.MaintenanceWorkOrder, error) {
+	var order models.MaintenanceWorkOrder
+	if err := repo.DB.Preload("Slots").First(&order, workOrderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetMaintenanceWorkOrders returns the work orders of a parking lot by planned
+// start, optionally only the ones with the given status.
+func (repo *Repository) GetMaintenanceWorkOrders(parkingLotID uint, status string) ([]models.MaintenanceWorkOrder, error) {
+	query := repo.DB.Preload("Slots").Where("parking_lot_id = ?", parkingLotID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.MaintenanceWorkOrder
+	if err := query.Order("planned_start").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetMaintenanceEvents returns the maintenance history of a slot, oldest first.
+func (repo *Repository) GetMaintenanceEvents(parkingSlotID uint) ([]models.MaintenanceEvent, error) {
+	var maintenanceEvents []models.MaintenanceEvent
+	if err := repo.DB.Where("parking_slot_id = ?", parkingSlotID).
+		Order("created_at, id").
+		Find(&maintenanceEvents).
+		Error; err != nil {
+		return nil, err
+	}
+	return maintenanceEvents, nil
+}
+
+// RunMaintenanceSchedule starts the work orders that are due, puts their free
+// slots in maintenance and ends the ones past their planned end. Slots with a
+// car parked wait until it leaves. It returns the maintenance events recorded.
+func (repo *Repository) RunMaintenanceSchedule(now time.Time) ([]models.MaintenanceEvent, error) {
+	var orders []models.MaintenanceWorkOrder
+	if err := repo.DB.Where("status IN ? AND planned_start <= ?",
+		[]string{models.WorkOrderScheduled, models.WorkOrderInProgress}, now).
+		Find(&orders).
+		Error; err != nil {
+		return nil, err
+	}
+
+	var recorded []models.MaintenanceEvent
+	for _, order := range orders {
+		var orderEvents []models.MaintenanceEvent
+		var err error
+		if order.PlannedEnd.After(now) {
+			orderEvents, err = repo.startMaintenanceWorkOrder(order.ID)
+		} else {
+			_, orderEvents, err = repo.finishMaintenanceWorkOrder(order.ID, models.WorkOrderCompleted)
+		}
+		if err != nil {
+			return recorded, err
+		}
+		recorded = append(recorded, orderEvents...)
+	}
+	return recorded, nil
+}
+
+// FinishMaintenanceWorkOrder completes or cancels a work order ahead of its
+// planned end and gives its slots back.
+func (repo *Repository) FinishMaintenanceWorkOrder(workOrderID uint, status string) (*models.MaintenanceWorkOrder, error) {
+	order, _, err := repo.finishMaintenanceWorkOrder(workOrderID, status)
+	return order, err
+}
+
+// startMaintenanceWorkOrder puts the slots of a due work order in maintenance,
+// skipping the ones a car is still parked in.
+func (repo *Repository) startMaintenanceWorkOrder(workOrderID uint) ([]models.MaintenanceEvent, error) {
+	var recorded []models.MaintenanceEvent
+	var changed []models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.MaintenanceWorkOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Slots").
+			First(&order, workOrderID).
+			Error; err != nil {
+			return err
+		}
+		if order.Status != models.WorkOrderScheduled && order.Status != models.WorkOrderInProgress {
+			return nil
+		}
+		if err := tx.Model(&order).Update("status", models.WorkOrderInProgress).Error; err != nil {
+			return err
+		}
+
+		for _, orderSlot := range order.Slots {
+			if orderSlot.Status != models.WorkOrderSlotPending && orderSlot.Status != models.WorkOrderSlotWaitingForCar {
+				continue
+			}
+
+			var parkingSlot models.ParkingSlot
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&parkingSlot, orderSlot.ParkingSlotID).
+				Error; err != nil {
+				return err
+			}
+
+			kind := models.MaintenanceEventStarted
+			status := models.WorkOrderSlotInMaintenance
+			setsMaintenance := false
+			switch {
+			case parkingSlot.DecommissionedAt != nil:
+				kind = models.MaintenanceEventCancelled
+				status = models.WorkOrderSlotReleased
+			case parkingSlot.IsBooked && !parkingSlot.IsInMaintenance:
+				if orderSlot.Status == models.WorkOrderSlotWaitingForCar {
+					continue
+				}
+				kind = models.MaintenanceEventWaiting
+				status = models.WorkOrderSlotWaitingForCar
+			case !parkingSlot.IsInMaintenance:
+				if err := setParkingSlotMaintenance(tx, &parkingSlot, true); err != nil {
+					return err
+				}
+				setsMaintenance = true
+				changed = append(changed, parkingSlot)
+			}
+
+			if err := tx.Model(&orderSlot).Updates(map[string]interface{}{
+				"status":           status,
+				"sets_maintenance": setsMaintenance,
+			}).Error; err != nil {
+				return err
+			}
+			maintenanceEvent, err := recordMaintenanceEvent(tx, parkingSlot.ID, &order.ID, kind)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, *maintenanceEvent)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, parkingSlot := range changed {
+		repo.publishSlotChange(parkingSlot, events.ReasonMaintenance)
+	}
+	return recorded, nil
+}
+
+// finishMaintenanceWorkOrder ends a work order with the given status and puts
+// the slots it had put in maintenance back in service. A slot another work
+// order still keeps in maintenance is handed over to that order instead, and
+// a slot that was in maintenance before the work order started stays so.
+func (repo *Repository) finishMaintenanceWorkOrder(workOrderID uint, status string) (*models.MaintenanceWorkOrder, []models.MaintenanceEvent, error) {
+	var order models.MaintenanceWorkOrder
+	var recorded []models.MaintenanceEvent
+	var changed []models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Slots").
+			First(&order, workOrderID).
+			Error; err != nil {
+			return err
+		}
+		if order.Status == models.WorkOrderCompleted || order.Status == models.WorkOrderCancelled {
+			return ErrWorkOrderFinished
+		}
+
+		for i := range order.Slots {
+			orderSlot := &order.Slots[i]
+			if orderSlot.Status == models.WorkOrderSlotReleased {
+				continue
+			}
+
+			kind := models.MaintenanceEventCancelled
+			if orderSlot.Status == models.WorkOrderSlotInMaintenance {
+				var parkingSlot models.ParkingSlot
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					First(&parkingSlot, orderSlot.ParkingSlotID).
+					Error; err != nil {
+					return err
+				}
+				if orderSlot.SetsMaintenance && parkingSlot.IsInMaintenance {
+					handedOver, err := handOverSlotMaintenance(tx, orderSlot)
+					if err != nil {
+						return err
+					}
+					if !handedOver {
+						if err := setParkingSlotMaintenance(tx, &parkingSlot, false); err != nil {
+							return err
+						}
+						changed = append(changed, parkingSlot)
+					}
+				}
+				kind = models.MaintenanceEventEnded
+			}
+
+			orderSlot.Status = models.WorkOrderSlotReleased
+			if err := tx.Model(orderSlot).Update("status", orderSlot.Status).Error; err != nil {
+				return err
+			}
+			maintenanceEvent, err := recordMaintenanceEvent(tx, orderSlot.ParkingSlotID, &order.ID, kind)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, *maintenanceEvent)
+		}
+
+		order.Status = status
+		return tx.Model(&order).Update("status", status).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, parkingSlot := range changed {
+		repo.publishSlotChange(parkingSlot, events.ReasonMaintenance)
+	}
+	return &order, recorded, nil
+}
+
+// handOverSlotMaintenance makes another work order that keeps the slot in
+// maintenance responsible for taking it out again. It reports false when no
+// other work order covers the slot.
+func handOverSlotMaintenance(tx *gorm.DB, orderSlot *models.MaintenanceWorkOrderSlot) (bool, error) {
+	var other models.MaintenanceWorkOrderSlot
+	err := tx.Where("parking_slot_id = ? AND work_order_id <> ? AND status = ?",
+		orderSlot.ParkingSlotID, orderSlot.WorkOrderID, models.WorkOrderSlotInMaintenance).
+		Order("id").
+		First(&other).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Model(&other).Update("sets_maintenance", true).Error
+}
+
+// plannedMaintenanceSlots selects the slots of the work orders that are in
+// progress or start within lead of now, which must not be allocated.
+func plannedMaintenanceSlots(tx *gorm.DB, now time.Time, lead time.Duration) *gorm.DB {
+	return tx.Model(&models.MaintenanceWorkOrderSlot{}).
+		Select("maintenance_work_order_slots.parking_slot_id").
+		Joins("JOIN maintenance_work_orders ON maintenance_work_orders.id = maintenance_work_order_slots.work_order_id").
+		Where("maintenance_work_orders.status IN ?", []string{models.WorkOrderScheduled, models.WorkOrderInProgress}).
+		Where("maintenance_work_orders.planned_start < ? AND maintenance_work_orders.planned_end > ?", now.Add(lead), now)
+}
+
+func recordMaintenanceEvent(tx *gorm.DB, parkingSlotID uint, workOrderID *uint, kind string) (*models.MaintenanceEvent, error) {
+	maintenanceEvent := models.MaintenanceEvent{
+		ParkingSlotID: parkingSlotID,
+		WorkOrderID:   workOrderID,
+		Kind:          kind,
+	}
+	if err := tx.Create(&maintenanceEvent).Error; err != nil {
+		return nil, err
+	}
+	return &maintenanceEvent, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"parkingManagementSystem/models"
+	"testing"
+	"time"
+)
+
+func TestFinishMaintenanceWorkOrderKeepsOtherMaintenance(t *testing.T) {
+	repo := openTestRepository(t)
+	start := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+
+	// Every case works on slot 1 of a fresh lot and finishes the work orders
+	// in the given order, checking the slot after each one.
+	tests := []struct {
+		name          string
+		inMaintenance bool
+		orders        int
+		finish        []int
+		want          []bool
+	}{
+		{name: "single work order", orders: 1, finish: []int{0}, want: []bool{false}},
+		{name: "slot in maintenance before the work order", inMaintenance: true, orders: 1, finish: []int{0}, want: []bool{true}},
+		{name: "overlapping work orders, first one ends first", orders: 2, finish: []int{0, 1}, want: []bool{true, false}},
+		{name: "overlapping work orders, last one ends first", orders: 2, finish: []int{1, 0}, want: []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parkingLot := models.ParkingLot{Location: tt.name}
+			if err := repo.CreateParkingLot(&parkingLot, 1); err != nil {
+				t.Fatal(err)
+			}
+			var parkingSlot models.ParkingSlot
+			if err := repo.DB.Where("parking_lot_id = ?", parkingLot.ID).First(&parkingSlot).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.inMaintenance {
+				if err := setParkingSlotMaintenance(repo.DB, &parkingSlot, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			orderIDs := make([]uint, 0, tt.orders)
+			for i := 0; i < tt.orders; i++ {
+				order := models.MaintenanceWorkOrder{
+					ParkingLotID: parkingLot.ID,
+					PlannedStart: start,
+					PlannedEnd:   start.Add(4 * time.Hour),
+				}
+				if err := repo.CreateMaintenanceWorkOrder(&order, []uint{1}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := repo.startMaintenanceWorkOrder(order.ID); err != nil {
+					t.Fatal(err)
+				}
+				orderIDs = append(orderIDs, order.ID)
+			}
+
+			for i, index := range tt.finish {
+				if _, err := repo.FinishMaintenanceWorkOrder(orderIDs[index], models.WorkOrderCompleted); err != nil {
+					t.Fatal(err)
+				}
+				if err := repo.DB.First(&parkingSlot, parkingSlot.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				if parkingSlot.IsInMaintenance != tt.want[i] {
+					t.Errorf("after finishing work order %d the slot is in maintenance: %v, want %v",
+						index+1, parkingSlot.IsInMaintenance, tt.want[i])
+				}
+			}
+		})
+	}
+}
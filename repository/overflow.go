@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// HoldParkingSlot keeps the first available slot of slotType of a parking lot
+// for a vehicle redirected from another lot until the hold expires.
+func (repo *Repository) HoldParkingSlot(parkingLotID, fromParkingLotID uint, carID *uint, plate, slotType string, expiresAt time.Time) (*models.SlotHold, error) {
+	var hold *models.SlotHold
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		parkingSlot, err := repo.firstAvailableParkingSlotOfType(tx, parkingLotID, slotType)
+		if err != nil {
+			return err
+		}
+		if parkingSlot == nil {
+			return ErrNoAvailableParkingSlot
+		}
+
+		hold = &models.SlotHold{
+			ParkingLotID:     parkingLotID,
+			ParkingSlotID:    parkingSlot.ID,
+			RelativeID:       parkingSlot.RelativeID,
+			FromParkingLotID: fromParkingLotID,
+			CarID:            carID,
+			Plate:            NormalizePlate(plate),
+			Status:           models.SlotHoldHeld,
+			ExpiresAt:        expiresAt,
+		}
+		return tx.Create(hold).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (repo *Repository) CreateOverflowRedirect(redirect *models.OverflowRedirect) error {
+	return repo.DB.Create(redirect).Error
+}
+
+// GetOverflowStats sums up the vehicles a parking lot turned away between from and to.
+func (repo *Repository) GetOverflowStats(parkingLotID uint, from, to time.Time) (*models.OverflowStats, error) {
+	stats := models.OverflowStats{
+		ParkingLotID:  parkingLotID,
+		ByDestination: make(map[uint]int64),
+	}
+	period := repo.DB.Model(&models.OverflowRedirect{}).
+		Where("from_parking_lot_id = ? AND created_at >= ? AND created_at < ?", parkingLotID, from, to)
+
+	if err := period.Session(&gorm.Session{}).Count(&stats.TurnedAway).Error; err != nil {
+		return nil, err
+	}
+	if err := period.Session(&gorm.Session{}).
+		Where("alternatives_offered = ?", 0).
+		Count(&stats.WithoutAlternatives).
+		Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ToParkingLotID uint
+		Count          int64
+	}
+	if err := period.Session(&gorm.Session{}).
+		Select("to_parking_lot_id, COUNT(*) AS count").
+		Where("to_parking_lot_id IS NOT NULL").
+		Group("to_parking_lot_id").
+		Scan(&rows).
+		Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		stats.ByDestination[row.ToParkingLotID] = row.Count
+		stats.AutoRedirected += row.Count
+	}
+	return &stats, nil
+}
+
+// allocateParkingSlot returns the slot held in the parking lot for the car or
+// the plate, or else the first available slot, or nil when there is none. A
+// held slot is marked used.
+func (repo *Repository) allocateParkingSlot(tx *gorm.DB, parkingLotID uint, carID *uint, plate string) (*models.ParkingSlot, error) {
+	plate = NormalizePlate(plate)
+	var uid uint
+	if carID != nil {
+		uid = *carID
+	}
+	if plate == "" && uid == 0 {
+		return repo.firstAvailableParkingSlot(tx, parkingLotID)
+	}
+
+	var hold models.SlotHold
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("parking_lot_id = ? AND status = ? AND expires_at > ?", parkingLotID, models.SlotHoldHeld, time.Now()).
+		Where("car_id = ? OR (plate <> '' AND plate = ?)", uid, plate).
+		Order("created_at").
+		First(&hold).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repo.firstAvailableParkingSlot(tx, parkingLotID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var parkingSlot models.ParkingSlot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&parkingSlot, hold.ParkingSlotID).
+		Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&hold).Update("status", models.SlotHoldUsed).Error; err != nil {
+		return nil, err
+	}
+	if parkingSlot.IsBooked || parkingSlot.DecommissionedAt != nil {
+		// The slot was taken out of service since it was held
+		return repo.firstAvailableParkingSlot(tx, parkingLotID)
+	}
+	return &parkingSlot, nil
+}
+
+// heldSlots selects the slots held for redirected vehicles at now, which
+// must not be allocated to anyone else.
+func heldSlots(tx *gorm.DB, now time.Time) *gorm.DB {
+	return tx.Model(&models.SlotHold{}).
+		Select("parking_slot_id").
+		Where("status = ? AND expires_at > ?", models.SlotHoldHeld, now)
+}
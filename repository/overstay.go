@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// ScanOverstays raises or escalates a violation for every vehicle parked
+// longer than the maximum stay of its lot. The violations that were raised
+// or escalated are returned.
+func (repo *Repository) ScanOverstays(now time.Time) ([]models.OverstayViolation, error) {
+	var parkingLots []models.ParkingLot
+	// Edge sites enforce the lots they operate themselves
+	if err := repo.DB.Where("max_stay_minutes > 0").
+		Where("id NOT IN (?)", edgeOperatedLots(repo.DB)).
+		Find(&parkingLots).
+		Error; err != nil {
+		return nil, err
+	}
+
+	var changed []models.OverstayViolation
+	for _, parkingLot := range parkingLots {
+		maxStay := time.Duration(parkingLot.MaxStayMinutes) * time.Minute
+		var parkingSlots []models.ParkingSlot
+		if err := repo.DB.Where("parking_lot_id = ? AND is_booked = ? AND is_in_maintenance = ? AND parked_at < ?",
+			parkingLot.ID, true, false, now.Add(-maxStay)).
+			Find(&parkingSlots).
+			Error; err != nil {
+			return nil, err
+		}
+
+		for _, parkingSlot := range parkingSlots {
+			violation, escalated, err := upsertOverstayViolation(repo.DB, parkingLot, parkingSlot, now)
+			if err != nil {
+				return nil, err
+			}
+			if escalated {
+				changed = append(changed, *violation)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// GetOverstayQueue returns the open violations of a parking lot for the
+// attendants, oldest visit first.
+func (repo *Repository) GetOverstayQueue(parkingLotID uint) ([]models.OverstayViolation, error) {
+	var violations []models.OverstayViolation
+	if err := repo.DB.Where("parking_lot_id = ? AND status = ?", parkingLotID, models.OverstayViolationOpen).
+		Order("parked_at").
+		Find(&violations).
+		Error; err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+// UpdateOverstayPolicy sets the maximum stay and the fines of a parking lot.
+func (repo *Repository) UpdateOverstayPolicy(parkingLotID uint, maxStayMinutes, overstayFine, escalationMinutes int) (*models.ParkingLot, error) {
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return nil, err
+	}
+	parkingLot.MaxStayMinutes = maxStayMinutes
+	parkingLot.OverstayFine = overstayFine
+	parkingLot.OverstayEscalationMinutes = escalationMinutes
+	if err := repo.DB.Model(&parkingLot).
+		Select("max_stay_minutes", "overstay_fine", "overstay_escalation_minutes").
+		Updates(&parkingLot).
+		Error; err != nil {
+		return nil, err
+	}
+	return &parkingLot, nil
+}
+
+// settleOverstayViolations brings the violation of the visit ending in the
+// slot up to date, marks it paid and returns the fine to add to the charge.
+// The fines of a visit that is free of charge are waived instead.
+func settleOverstayViolations(tx *gorm.DB, parkingSlot *models.ParkingSlot, unparkedAt time.Time, waive bool) (int, error) {
+	var parkingLot models.ParkingLot
+	if err := tx.First(&parkingLot, parkingSlot.ParkingLotID).Error; err != nil {
+		return 0, err
+	}
+	if _, _, err := upsertOverstayViolation(tx, parkingLot, *parkingSlot, unparkedAt); err != nil {
+		return 0, err
+	}
+
+	var violations []models.OverstayViolation
+	if err := tx.Where("parking_slot_id = ? AND parked_at = ? AND status = ?",
+		parkingSlot.ID, *parkingSlot.ParkedAt, models.OverstayViolationOpen).
+		Find(&violations).
+		Error; err != nil {
+		return 0, err
+	}
+
+	status := models.OverstayViolationPaid
+	if waive {
+		status = models.OverstayViolationWaived
+	}
+
+	fines := 0
+	for _, violation := range violations {
+		if !waive {
+			fines += violation.FineAmount
+		}
+		if err := tx.Model(&violation).Updates(map[string]interface{}{
+			"status":  status,
+			"paid_at": unparkedAt,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return fines, nil
+}
+
+// upsertOverstayViolation raises the violation of the visit in the slot, or
+// escalates it to the level reached at now. It reports whether the violation
+// was raised or escalated; nothing is stored while the stay is within the limit.
+func upsertOverstayViolation(tx *gorm.DB, parkingLot models.ParkingLot, parkingSlot models.ParkingSlot, now time.Time) (*models.OverstayViolation, bool, error) {
+	if parkingLot.MaxStayMinutes <= 0 || parkingSlot.ParkedAt == nil || parkingSlot.IsInMaintenance {
+		return nil, false, nil
+	}
+	overstay := now.Sub(*parkingSlot.ParkedAt) - time.Duration(parkingLot.MaxStayMinutes)*time.Minute
+	if overstay <= 0 {
+		return nil, false, nil
+	}
+
+	period := time.Duration(parkingLot.OverstayEscalationMinutes) * time.Minute
+	level := 1
+	if period > 0 {
+		level += int(overstay / period)
+	}
+
+	violation := models.OverstayViolation{
+		ParkingLotID:    parkingLot.ID,
+		ParkingSlotID:   parkingSlot.ID,
+		RelativeID:      parkingSlot.RelativeID,
+		CarID:           parkingSlot.CarID,
+		TicketSessionID: parkingSlot.TicketSessionID,
+		ParkedAt:        *parkingSlot.ParkedAt,
+		DetectedAt:      now,
+		Level:           level,
+		FineAmount:      level * parkingLot.OverstayFine,
+		Status:          models.OverstayViolationOpen,
+	}
+
+	// Raise the violation once per visit and only ever escalate an open one
+	result := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "parking_slot_id"}, {Name: "parked_at"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"level":       level,
+			"fine_amount": violation.FineAmount,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "overstay_violations.status = ? AND overstay_violations.level < ?", Vars: []interface{}{models.OverstayViolationOpen, level}},
+		}},
+	}).Create(&violation)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return &violation, result.RowsAffected > 0, nil
+}
@@ -1,40 +1,329 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"parkingManagementSystem/events"
 	"parkingManagementSystem/models"
 	"time"
 )
 
-func (repo *PgRepository) ParkCar(parkingLotID uint, carID uint) error {
-	// Get the first available parking slot
-	parkingSlot, err := repo.GetFirstAvailableParkingSlot(parkingLotID)
+var (
+	ErrNoAvailableParkingSlot = errors.New("no available parking slots in the specified parking lot")
+	// ErrNotParked is returned when the vehicle left, or was moved, while its
+	// exit was being processed.
+	ErrNotParked = errors.New("vehicle is not parked in the slot")
+)
+
+// HourlyParkingRate is the amount charged for every started hour of parking.
+const HourlyParkingRate = 10
+
+func (repo *Repository) ParkCar(parkingLotID uint, carID uint) error {
+	// Refuse blocklisted cars before allocating anything
+	var car models.Car
+	if err := repo.DB.First(&car, carID).Error; err != nil {
+		return err
+	}
+	if err := repo.checkEntry(parkingLotID, car.Plate, &car.ID, &car.UserID); err != nil {
+		return err
+	}
+	if err := repo.checkOpen(parkingLotID, time.Now()); err != nil {
+		return err
+	}
+	if err := repo.checkSelfService(parkingLotID); err != nil {
+		return err
+	}
+	if err := repo.checkEdgeOperated(parkingLotID); err != nil {
+		return err
+	}
+
+	var parkingSlot *models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		// Get the slot held for the car, or else the first available one
+		var err error
+		parkingSlot, err = repo.allocateParkingSlot(tx, parkingLotID, &carID, car.Plate)
+		if err != nil {
+			return err
+		}
+
+		// Check if parking slot is available
+		if parkingSlot == nil {
+			return ErrNoAvailableParkingSlot
+		}
+
+		// Update car data with parking slot ID
+		if err := tx.Model(&models.Car{}).
+			Where("id = ?", carID).
+			Update("parking_slot_id", parkingSlot.ID).
+			Error; err != nil {
+			return err
+		}
+
+		// Update parking slot data
+		parkingSlot.CarID = &carID
+		return occupyParkingSlot(tx, parkingSlot, time.Now())
+	})
 	if err != nil {
 		return err
 	}
 
-	// Check if parking slot is available
-	if parkingSlot == nil {
-		return fmt.Errorf("no available parking slots in the specified parking lot")
+	repo.publishSlotChange(*parkingSlot, events.ReasonPark)
+	return nil
+}
+
+// occupyParkingSlot books the slot for the vehicle that just entered and
+// queues the park, and the lot filling up, for webhook subscribers.
+func occupyParkingSlot(tx *gorm.DB, parkingSlot *models.ParkingSlot, parkedAt time.Time) error {
+	parkingSlot.IsBooked = true
+	parkingSlot.ParkedAt = &parkedAt
+	parkingSlot.UnparkedAt = nil // Set unparked_at to null
+	if err := tx.Save(parkingSlot).Error; err != nil {
+		return err
+	}
+
+	if err := enqueueOutboxEvent(tx, models.WebhookPayload{
+		EventType:    models.WebhookEventCarParked,
+		ParkingLotID: parkingSlot.ParkingLotID,
+		Slot:         parkingSlot,
+		OccurredAt:   parkedAt,
+	}); err != nil {
+		return err
 	}
 
-	// Update car data with parking slot ID
-	if err := repo.DB.Model(&models.Car{}).
-		Where("id = ?", carID).
-		Update("parking_slot_id", parkingSlot.ID).
+	var freeSlots int64
+	if err := tx.Model(&models.ParkingSlot{}).
+		Scopes(inService).
+		Where("parking_lot_id = ? AND is_booked = ?", parkingSlot.ParkingLotID, false).
+		Count(&freeSlots).
 		Error; err != nil {
 		return err
 	}
+	if freeSlots > 0 {
+		return nil
+	}
+	return enqueueOutboxEvent(tx, models.WebhookPayload{
+		EventType:    models.WebhookEventLotFull,
+		ParkingLotID: parkingSlot.ParkingLotID,
+		OccurredAt:   parkedAt,
+	})
+}
 
-	// Update parking slot data
-	currentTime := time.Now()
-	parkingSlot.CarID = &carID
-	parkingSlot.IsBooked = true
-	parkingSlot.ParkedAt = &currentTime
-	parkingSlot.UnparkedAt = nil // Set unparked_at to null
-	if err := repo.DB.Save(parkingSlot).Error; err != nil {
+// UnparkCar releases the slot held by the car, charges the parking fee and
+// records the visit in the daily history.
+func (repo *Repository) UnparkCar(carID uint) (*models.ParkingCharge, error) {
+	var car models.Car
+	if err := repo.DB.First(&car, carID).Error; err != nil {
+		return nil, err
+	}
+	if car.ParkingSlotID == nil {
+		return nil, fmt.Errorf("car %d is not parked", carID)
+	}
+
+	var charge *models.ParkingCharge
+	var parkingSlot models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the slot, then the car, in the order relocations lock them
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&parkingSlot, *car.ParkingSlotID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&car, carID).Error; err != nil {
+			return err
+		}
+		// Another exit or a move may have been committed since the car was read
+		if car.ParkingSlotID == nil || *car.ParkingSlotID != parkingSlot.ID ||
+			parkingSlot.CarID == nil || *parkingSlot.CarID != car.ID {
+			return ErrNotParked
+		}
+
+		// Update the car model
+		car.ParkingSlotID = nil
+		if err := tx.Save(&car).Error; err != nil {
+			return err
+		}
+
+		// Allowlisted cars park free of charge
+		calculateCharge := CalculateParkingCharge
+		entry, err := findAccessListEntry(tx, models.AccessListAllow, parkingSlot.ParkingLotID, car.Plate, &car.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			calculateCharge = allowlistedCharge
+		}
+
+		charge, err = releaseParkingSlot(tx, &parkingSlot, calculateCharge)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	repo.publishSlotChange(parkingSlot, events.ReasonUnpark)
+	return charge, nil
+}
+
+// SetParkingSlotMaintenance puts the slot in or out of maintenance. A slot in
+// maintenance is booked so it is never allocated.
+func (repo *Repository) SetParkingSlotMaintenance(parkingSlot *models.ParkingSlot, inMaintenance bool) error {
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setParkingSlotMaintenance(tx, parkingSlot, inMaintenance); err != nil {
+			return err
+		}
+
+		// Manual toggles belong to no work order
+		kind := models.MaintenanceEventEnded
+		if inMaintenance {
+			kind = models.MaintenanceEventStarted
+		}
+		_, err := recordMaintenanceEvent(tx, parkingSlot.ID, nil, kind)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	repo.publishSlotChange(*parkingSlot, events.ReasonMaintenance)
 	return nil
 }
+
+func setParkingSlotMaintenance(tx *gorm.DB, parkingSlot *models.ParkingSlot, inMaintenance bool) error {
+	parkingSlot.IsBooked = inMaintenance
+	parkingSlot.IsInMaintenance = inMaintenance
+	if err := tx.Save(parkingSlot).Error; err != nil {
+		return err
+	}
+	return enqueueOutboxEvent(tx, models.WebhookPayload{
+		EventType:    models.WebhookEventSlotMaintenance,
+		ParkingLotID: parkingSlot.ParkingLotID,
+		Slot:         parkingSlot,
+		OccurredAt:   time.Now(),
+	})
+}
+
+// publishSlotChange tells the live status subscribers of the slot's lot about
+// a change. It must only be called once the change has been committed.
+func (repo *Repository) publishSlotChange(parkingSlot models.ParkingSlot, reason string) {
+	repo.Events.Publish(events.SlotChange{
+		ParkingLotID: parkingSlot.ParkingLotID,
+		Reason:       reason,
+		Slot:         parkingSlot,
+	})
+}
+
+// chargeFunc prices a stay from the time a vehicle parked until it left.
+type chargeFunc func(parkedAt, unparkedAt time.Time) models.ParkingCharge
+
+// releaseParkingSlot frees a booked slot, calculates what is owed for the
+// time it was held and adds the visit to the parking history.
+func releaseParkingSlot(tx *gorm.DB, parkingSlot *models.ParkingSlot, calculateCharge chargeFunc) (*models.ParkingCharge, error) {
+	if !parkingSlot.IsBooked || parkingSlot.ParkedAt == nil {
+		return nil, ErrNotParked
+	}
+	unparkedAt := time.Now()
+	charge := calculateCharge(*parkingSlot.ParkedAt, unparkedAt)
+
+	// Energy and idle time of a charging vehicle, may replace the parking fee
+	if _, err := settleChargingSession(tx, parkingSlot, unparkedAt, &charge); err != nil {
+		return nil, err
+	}
+	parkingAmount := charge.TotalAmountToBePaid - charge.EnergyFee - charge.IdleFee - charge.ValetFee
+
+	// Exits are always allowed, staying while the lot was closed may cost extra
+	if !charge.IsFreeOfCharge {
+		fee, err := overnightFee(tx, parkingSlot.ParkingLotID, *parkingSlot.ParkedAt, unparkedAt)
+		if err != nil {
+			return nil, err
+		}
+		charge.OvernightFee = fee
+		charge.TotalAmountToBePaid += fee
+	}
+
+	// Unpaid overstay fines are due with the parking fee
+	fines, err := settleOverstayViolations(tx, parkingSlot, unparkedAt, charge.IsFreeOfCharge)
+	if err != nil {
+		return nil, err
+	}
+	charge.OverstayFine = fines
+	charge.TotalAmountToBePaid += fines
+	charge.LineItems = chargeLineItems(charge, parkingAmount)
+
+	parkedAt := *parkingSlot.ParkedAt
+	parkingSlot.IsBooked = false
+	parkingSlot.CarID = nil
+	parkingSlot.TicketSessionID = nil
+	parkingSlot.ParkedAt = nil
+	parkingSlot.UnparkedAt = &unparkedAt
+	if parkingSlot.IsDraining {
+		parkingSlot.IsDraining = false
+		parkingSlot.DecommissionedAt = &unparkedAt
+	}
+	if err := tx.Save(parkingSlot).Error; err != nil {
+		return nil, err
+	}
+
+	if err := enqueueOutboxEvent(tx, models.WebhookPayload{
+		EventType:    models.WebhookEventCarUnparked,
+		ParkingLotID: parkingSlot.ParkingLotID,
+		Slot:         parkingSlot,
+		Charge:       &charge,
+		ParkedAt:     &parkedAt,
+		OccurredAt:   unparkedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := recordParkingHistory(tx, unparkedAt, charge); err != nil {
+		return nil, err
+	}
+
+	return &charge, nil
+}
+
+// CalculateParkingCharge bills every started hour between parkedAt and
+// unparkedAt at HourlyParkingRate.
+func CalculateParkingCharge(parkedAt, unparkedAt time.Time) models.ParkingCharge {
+	totalParkingTime := int(math.Ceil(unparkedAt.Sub(parkedAt).Hours()))
+	return models.ParkingCharge{
+		TotalParkingTime:    totalParkingTime,
+		TotalAmountToBePaid: totalParkingTime * HourlyParkingRate,
+	}
+}
+
+// chargeLineItems breaks the amount due down by component, the parking fee
+// first. Components that are not part of the charge are left out.
+func chargeLineItems(charge models.ParkingCharge, parkingAmount int) []models.LineItem {
+	parkingKind := models.LineItemParking
+	if charge.LostTicketFee > 0 {
+		parkingKind = models.LineItemLostTicket
+	}
+	lineItems := []models.LineItem{{
+		Kind:     parkingKind,
+		Quantity: float64(charge.TotalParkingTime),
+		Unit:     "hour",
+		Amount:   parkingAmount,
+	}}
+	if charge.OvernightFee > 0 {
+		lineItems = append(lineItems, models.LineItem{Kind: models.LineItemOvernight, Amount: charge.OvernightFee})
+	}
+	if charge.OverstayFine > 0 {
+		lineItems = append(lineItems, models.LineItem{Kind: models.LineItemOverstay, Amount: charge.OverstayFine})
+	}
+	if charge.EnergyKWh > 0 {
+		lineItems = append(lineItems, models.LineItem{
+			Kind:     models.LineItemEnergy,
+			Quantity: charge.EnergyKWh,
+			Unit:     "kWh",
+			Amount:   charge.EnergyFee,
+		})
+	}
+	if charge.IdleFee > 0 {
+		lineItems = append(lineItems, models.LineItem{Kind: models.LineItemIdle, Amount: charge.IdleFee})
+	}
+	if charge.ValetFee > 0 {
+		lineItems = append(lineItems, models.LineItem{Kind: models.LineItemValet, Amount: charge.ValetFee})
+	}
+	return lineItems
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"parkingManagementSystem/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCalculateParkingCharge(t *testing.T) {
+	parkedAt := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		stay      time.Duration
+		wantHours int
+	}{
+		{name: "left at once", stay: 0, wantHours: 0},
+		{name: "one second", stay: time.Second, wantHours: 1},
+		{name: "exactly one hour", stay: time.Hour, wantHours: 1},
+		{name: "one hour and a minute", stay: time.Hour + time.Minute, wantHours: 2},
+		{name: "a full day", stay: 24 * time.Hour, wantHours: 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charge := CalculateParkingCharge(parkedAt, parkedAt.Add(tt.stay))
+			if charge.TotalParkingTime != tt.wantHours {
+				t.Errorf("TotalParkingTime = %d, want %d", charge.TotalParkingTime, tt.wantHours)
+			}
+			if want := tt.wantHours * HourlyParkingRate; charge.TotalAmountToBePaid != want {
+				t.Errorf("TotalAmountToBePaid = %d, want %d", charge.TotalAmountToBePaid, want)
+			}
+		})
+	}
+}
+
+func TestChargeLineItems(t *testing.T) {
+	tests := []struct {
+		name          string
+		charge        models.ParkingCharge
+		parkingAmount int
+		want          []models.LineItem
+	}{
+		{
+			name:          "parking only",
+			charge:        models.ParkingCharge{TotalParkingTime: 3},
+			parkingAmount: 30,
+			want:          []models.LineItem{{Kind: models.LineItemParking, Quantity: 3, Unit: "hour", Amount: 30}},
+		},
+		{
+			name:          "waived parking fee is still listed",
+			charge:        models.ParkingCharge{TotalParkingTime: 2, EnergyKWh: 12.5, EnergyFee: 5},
+			parkingAmount: 0,
+			want: []models.LineItem{
+				{Kind: models.LineItemParking, Quantity: 2, Unit: "hour", Amount: 0},
+				{Kind: models.LineItemEnergy, Quantity: 12.5, Unit: "kWh", Amount: 5},
+			},
+		},
+		{
+			name:          "lost ticket replaces the parking fee",
+			charge:        models.ParkingCharge{TotalParkingTime: 5, LostTicketFee: 100},
+			parkingAmount: 100,
+			want:          []models.LineItem{{Kind: models.LineItemLostTicket, Quantity: 5, Unit: "hour", Amount: 100}},
+		},
+		{
+			name: "every component in order",
+			charge: models.ParkingCharge{
+				TotalParkingTime: 4,
+				OvernightFee:     15,
+				OverstayFine:     50,
+				EnergyKWh:        20,
+				EnergyFee:        8,
+				IdleFee:          10,
+				ValetFee:         25,
+			},
+			parkingAmount: 40,
+			want: []models.LineItem{
+				{Kind: models.LineItemParking, Quantity: 4, Unit: "hour", Amount: 40},
+				{Kind: models.LineItemOvernight, Amount: 15},
+				{Kind: models.LineItemOverstay, Amount: 50},
+				{Kind: models.LineItemEnergy, Quantity: 20, Unit: "kWh", Amount: 8},
+				{Kind: models.LineItemIdle, Amount: 10},
+				{Kind: models.LineItemValet, Amount: 25},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chargeLineItems(tt.charge, tt.parkingAmount); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chargeLineItems = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var (
+	ErrNothingToRelocate      = errors.New("no vehicle is parked in the parking slot")
+	ErrTargetSlotUnavailable  = errors.New("target parking slot is not available")
+	ErrTargetSlotIncompatible = errors.New("target parking slot does not fit the vehicle")
+)
+
+// RelocateParkedCar moves the vehicle parked in a slot to the slot with the
+// given relative ID in the same lot, or to the first available slot when it
+// is zero. The visit keeps its ParkedAt, its overstay violations and its
+// charging session, so it is billed as one stay. A charge in progress ends,
+// as the vehicle is unplugged to be moved.
+func (repo *Repository) RelocateParkedCar(parkingSlotID uint, toRelativeID uint, attendant, reason string) (*models.SlotMove, error) {
+	var move models.SlotMove
+	var fromSlot, toSlot models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fromSlot, parkingSlotID).Error; err != nil {
+			return err
+		}
+		// A visit mirrored from an edge site has neither a car nor a ticket session
+		if !fromSlot.IsBooked || fromSlot.ParkedAt == nil {
+			return ErrNothingToRelocate
+		}
+
+		target, err := repo.relocationTarget(tx, fromSlot, toRelativeID)
+		if err != nil {
+			return err
+		}
+		toSlot = *target
+
+		now := time.Now()
+		parkedAt := *fromSlot.ParkedAt
+		move = models.SlotMove{
+			ParkingLotID:    fromSlot.ParkingLotID,
+			CarID:           fromSlot.CarID,
+			TicketSessionID: fromSlot.TicketSessionID,
+			ParkedAt:        parkedAt,
+			FromSlotID:      fromSlot.ID,
+			FromRelativeID:  fromSlot.RelativeID,
+			ToSlotID:        toSlot.ID,
+			ToRelativeID:    toSlot.RelativeID,
+			MovedBy:         attendant,
+			Reason:          reason,
+			MovedAt:         now,
+		}
+
+		// Book the new slot for the visit as it was booked in the old one
+		toSlot.IsBooked = true
+		toSlot.CarID = fromSlot.CarID
+		toSlot.TicketSessionID = fromSlot.TicketSessionID
+		toSlot.ParkedAt = &parkedAt
+		toSlot.UnparkedAt = nil
+		if err := tx.Save(&toSlot).Error; err != nil {
+			return err
+		}
+
+		fromSlot.IsBooked = false
+		fromSlot.CarID = nil
+		fromSlot.TicketSessionID = nil
+		fromSlot.ParkedAt = nil
+		fromSlot.UnparkedAt = &now
+		if fromSlot.IsDraining {
+			fromSlot.IsDraining = false
+			fromSlot.DecommissionedAt = &now
+		}
+		if err := tx.Save(&fromSlot).Error; err != nil {
+			return err
+		}
+
+		if err := moveVisitRecords(tx, move, now); err != nil {
+			return err
+		}
+		if err := tx.Create(&move).Error; err != nil {
+			return err
+		}
+
+		return enqueueOutboxEvent(tx, models.WebhookPayload{
+			EventType:    models.WebhookEventCarRelocated,
+			ParkingLotID: toSlot.ParkingLotID,
+			Slot:         &toSlot,
+			FromSlot:     &fromSlot,
+			OccurredAt:   now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo.publishSlotChange(fromSlot, events.ReasonRelocate)
+	repo.publishSlotChange(toSlot, events.ReasonRelocate)
+	return &move, nil
+}
+
+// GetSlotMoves returns the moves of a parking lot, latest first. A car ID or a
+// ticket session ID narrows them down to the moves of one vehicle.
+func (repo *Repository) GetSlotMoves(parkingLotID uint, carID, ticketSessionID uint) ([]models.SlotMove, error) {
+	query := repo.DB.Where("parking_lot_id = ?", parkingLotID)
+	if carID != 0 {
+		query = query.Where("car_id = ?", carID)
+	}
+	if ticketSessionID != 0 {
+		query = query.Where("ticket_session_id = ?", ticketSessionID)
+	}
+
+	var moves []models.SlotMove
+	if err := query.Order("moved_at DESC").Find(&moves).Error; err != nil {
+		return nil, err
+	}
+	return moves, nil
+}
+
+// relocationTarget locks the slot a vehicle is moved to. A slot chosen by
+// staff must be free, in service and neither held nor reserved for planned
+// maintenance, like any slot that would be allocated. The target has the
+// slot type of the vehicle's slot, and a charger only when the vehicle was
+// parked at a charger with the same connector. The first available slot is
+// never one with a charger, chargers are kept for the vehicles that use them.
+func (repo *Repository) relocationTarget(tx *gorm.DB, fromSlot models.ParkingSlot, toRelativeID uint) (*models.ParkingSlot, error) {
+	if toRelativeID == 0 {
+		var parkingSlot models.ParkingSlot
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Scopes(repo.allocatable(tx, time.Now())).
+			Where("parking_lot_id = ? AND slot_type = ?", fromSlot.ParkingLotID, fromSlot.SlotType).
+			Where("COALESCE(charger_connector, '') = ''").
+			Order("relative_id").
+			First(&parkingSlot).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoAvailableParkingSlot
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &parkingSlot, nil
+	}
+
+	var parkingSlot models.ParkingSlot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(inService).
+		Where("parking_lot_id = ? AND relative_id = ?", fromSlot.ParkingLotID, toRelativeID).
+		First(&parkingSlot).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownParkingSlot
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parkingSlot.ID == fromSlot.ID || parkingSlot.IsBooked || parkingSlot.IsInMaintenance || parkingSlot.IsDraining {
+		return nil, ErrTargetSlotUnavailable
+	}
+	if parkingSlot.SlotType != fromSlot.SlotType ||
+		(parkingSlot.ChargerConnector != "" && parkingSlot.ChargerConnector != fromSlot.ChargerConnector) {
+		return nil, ErrTargetSlotIncompatible
+	}
+
+	var reserved int64
+	now := time.Now()
+	if err := tx.Model(&models.ParkingSlot{}).
+		Where("id = ?", parkingSlot.ID).
+		Where("id IN (?) OR id IN (?)", plannedMaintenanceSlots(tx, now, repo.MaintenanceLeadTime), heldSlots(tx, now)).
+		Count(&reserved).
+		Error; err != nil {
+		return nil, err
+	}
+	if reserved > 0 {
+		return nil, ErrTargetSlotUnavailable
+	}
+	return &parkingSlot, nil
+}
+
+// moveVisitRecords points everything kept about the visit at the slot the
+// vehicle was moved to.
+func moveVisitRecords(tx *gorm.DB, move models.SlotMove, movedAt time.Time) error {
+	if move.CarID != nil {
+		if err := tx.Model(&models.Car{}).
+			Where("id = ?", *move.CarID).
+			Update("parking_slot_id", move.ToSlotID).
+			Error; err != nil {
+			return err
+		}
+	}
+	if move.TicketSessionID != nil {
+		slotColumns := map[string]interface{}{
+			"parking_slot_id":  move.ToSlotID,
+			"relative_slot_id": move.ToRelativeID,
+		}
+		if err := tx.Model(&models.TicketSession{}).
+			Where("id = ?", *move.TicketSessionID).
+			Updates(slotColumns).
+			Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ValetTicket{}).
+			Where("ticket_session_id = ?", *move.TicketSessionID).
+			Updates(slotColumns).
+			Error; err != nil {
+			return err
+		}
+	}
+
+	visitColumns := map[string]interface{}{
+		"parking_slot_id": move.ToSlotID,
+		"relative_id":     move.ToRelativeID,
+	}
+	if err := tx.Model(&models.OverstayViolation{}).
+		Where("parking_slot_id = ? AND parked_at = ?", move.FromSlotID, move.ParkedAt).
+		Updates(visitColumns).
+		Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.ChargingSession{}).
+		Where("parking_slot_id = ? AND parked_at = ? AND status = ?", move.FromSlotID, move.ParkedAt, models.ChargingSessionCharging).
+		Updates(map[string]interface{}{
+			"status":       models.ChargingSessionCompleted,
+			"completed_at": movedAt,
+		}).
+		Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.ChargingSession{}).
+		Where("parking_slot_id = ? AND parked_at = ?", move.FromSlotID, move.ParkedAt).
+		Updates(visitColumns).
+		Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"errors"
+	"parkingManagementSystem/models"
+	"testing"
+	"time"
+)
+
+func TestRelocateParkedCarTarget(t *testing.T) {
+	repo := openTestRepository(t)
+	parkedAt := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+
+	// Every lot has the car in slot 1 and the other slots free, each slot is
+	// given as its slot type and charger connector.
+	type slot struct{ slotType, connector string }
+	standard := slot{slotType: models.SlotTypeStandard}
+	tests := []struct {
+		name         string
+		slots        []slot
+		toRelativeID uint
+		wantTarget   uint
+		wantErr      error
+	}{
+		{name: "first available of the same type", slots: []slot{standard, {slotType: models.SlotTypeCompact}, standard}, wantTarget: 3},
+		{name: "first available skips chargers", slots: []slot{{models.SlotTypeStandard, models.ConnectorType2}, {models.SlotTypeStandard, models.ConnectorType2}, standard}, wantTarget: 3},
+		{name: "no slot of the same type", slots: []slot{{slotType: models.SlotTypeAccessible}, standard}, wantErr: ErrNoAvailableParkingSlot},
+		{name: "chosen slot of the same type", slots: []slot{standard, standard}, toRelativeID: 2, wantTarget: 2},
+		{name: "chosen slot of another type", slots: []slot{standard, {slotType: models.SlotTypeMotorcycle}}, toRelativeID: 2, wantErr: ErrTargetSlotIncompatible},
+		{name: "chosen charger for a car without one", slots: []slot{standard, {models.SlotTypeStandard, models.ConnectorCCS}}, toRelativeID: 2, wantErr: ErrTargetSlotIncompatible},
+		{name: "chosen charger with the same connector", slots: []slot{{models.SlotTypeStandard, models.ConnectorCCS}, {models.SlotTypeStandard, models.ConnectorCCS}}, toRelativeID: 2, wantTarget: 2},
+		{name: "chosen charger with another connector", slots: []slot{{models.SlotTypeStandard, models.ConnectorCCS}, {models.SlotTypeStandard, models.ConnectorCHAdeMO}}, toRelativeID: 2, wantErr: ErrTargetSlotIncompatible},
+		{name: "chosen slot without a charger for a car at one", slots: []slot{{models.SlotTypeStandard, models.ConnectorCCS}, standard}, toRelativeID: 2, wantTarget: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parkingLot := models.ParkingLot{Location: tt.name}
+			if err := repo.DB.Create(&parkingLot).Error; err != nil {
+				t.Fatal(err)
+			}
+			var fromSlot models.ParkingSlot
+			for i, s := range tt.slots {
+				parkingSlot := models.ParkingSlot{
+					ParkingLotID:     parkingLot.ID,
+					RelativeID:       uint(i + 1),
+					SlotType:         s.slotType,
+					ChargerConnector: s.connector,
+				}
+				if i == 0 {
+					parkingSlot.IsBooked = true
+					parkingSlot.ParkedAt = &parkedAt
+				}
+				if err := repo.DB.Create(&parkingSlot).Error; err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 {
+					fromSlot = parkingSlot
+				}
+			}
+
+			move, err := repo.RelocateParkedCar(fromSlot.ID, tt.toRelativeID, "attendant", "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("RelocateParkedCar error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if move.ToRelativeID != tt.wantTarget {
+				t.Errorf("moved to slot %d, want %d", move.ToRelativeID, tt.wantTarget)
+			}
+		})
+	}
+}
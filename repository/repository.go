@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/migrations"
+	"parkingManagementSystem/models"
+	"strings"
+	"time"
+)
+
+// Repository stores the parking system in PostgreSQL, or in SQLite for
+// single-site deployments. The dialect is chosen by NewRepository.
+type Repository struct {
+	*gorm.DB
+	// SensorAwareAllocation skips slots whose ground sensor reports a vehicle
+	// when looking for an available slot.
+	SensorAwareAllocation bool
+	// Events receives every committed slot change, nil disables publishing.
+	Events *events.Bus
+	// MaintenanceLeadTime is how long before its planned start a maintenance
+	// work order keeps its slots out of allocation.
+	MaintenanceLeadTime time.Duration
+}
+
+// NewRepository connects to the database of the URL. A sqlite:// URL opens
+// a SQLite database file, anything else is a PostgreSQL connection string.
+func NewRepository(databaseUrl string) (*Repository, error) {
+	dialector := postgres.Open(databaseUrl)
+	if strings.HasPrefix(databaseUrl, sqliteScheme) {
+		dialector = sqlite.Open(sqliteDSN(databaseUrl))
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{DB: db}, nil
+}
+
+// Migrate applies the pending schema migrations.
+func (repo *Repository) Migrate() error {
+	migrator, err := repo.SchemaMigrator()
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up()
+	return err
+}
+
+// CheckReady returns why the database cannot serve requests, if it cannot be
+// reached or has migrations pending.
+func (repo *Repository) CheckReady(ctx context.Context) error {
+	sqlDB, err := repo.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+	migrator, err := migrations.NewMigrator(repo.DB.WithContext(ctx), repo.DB.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations pending", pending)
+	}
+	return nil
+}
+
+// SchemaMigrator returns the migrator of the schema in the dialect of the database.
+func (repo *Repository) SchemaMigrator() (*migrations.Migrator, error) {
+	return migrations.NewMigrator(repo.DB, repo.DB.Dialector.Name())
+}
+
+func (repo *Repository) GetFirstAvailableParkingSlot(parkingLotID uint) (*models.ParkingSlot, error) {
+	return repo.firstAvailableParkingSlot(repo.DB, parkingLotID)
+}
+
+// allocatable narrows a slot query to the slots an entry may be given at now:
+// in service, free, not held for a redirected vehicle, not due for planned
+// maintenance and, with sensor-aware allocation, not reported occupied.
+func (repo *Repository) allocatable(tx *gorm.DB, now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(inService).
+			Where("is_booked = ?", false).
+			Where("id NOT IN (?)", plannedMaintenanceSlots(tx, now, repo.MaintenanceLeadTime)).
+			Where("id NOT IN (?)", heldSlots(tx, now))
+		if repo.SensorAwareAllocation {
+			db = db.Where("id NOT IN (?)", tx.Model(&models.SlotSensorState{}).
+				Select("parking_slot_id").
+				Where("occupied = ?", true))
+		}
+		return db
+	}
+}
+
+func (repo *Repository) firstAvailableParkingSlot(tx *gorm.DB, parkingLotID uint) (*models.ParkingSlot, error) {
+	return repo.firstAvailableParkingSlotOfType(tx, parkingLotID, "")
+}
+
+// firstAvailableParkingSlotOfType is firstAvailableParkingSlot restricted to
+// slots of slotType, or any type when it is empty.
+func (repo *Repository) firstAvailableParkingSlotOfType(tx *gorm.DB, parkingLotID uint, slotType string) (*models.ParkingSlot, error) {
+	var parkingSlot models.ParkingSlot
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Scopes(repo.allocatable(tx, time.Now())).
+		Where("parking_lot_id = ?", parkingLotID)
+	if slotType != "" {
+		query = query.Where("slot_type = ?", slotType)
+	}
+	if err := query.
+		Order("relative_id").
+		First(&parkingSlot).
+		Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &parkingSlot, nil
+}
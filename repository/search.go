@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// GetGeolocatedLots returns the parking lots that have coordinates.
+func (repo *Repository) GetGeolocatedLots() ([]models.ParkingLot, error) {
+	var parkingLots []models.ParkingLot
+	if err := repo.DB.Where("latitude IS NOT NULL AND longitude IS NOT NULL").
+		Find(&parkingLots).
+		Error; err != nil {
+		return nil, err
+	}
+	return parkingLots, nil
+}
+
+// GetFreeSlotCounts returns the number of slots of every parking lot that an
+// entry could be given now, by slot type. Like allocation, it leaves out held
+// slots and slots due for planned maintenance.
+func (repo *Repository) GetFreeSlotCounts() (map[uint]map[string]int, error) {
+	var rows []struct {
+		ParkingLotID uint
+		SlotType     string
+		Count        int
+	}
+	if err := repo.DB.Model(&models.ParkingSlot{}).
+		Scopes(repo.allocatable(repo.DB, time.Now())).
+		Select("parking_lot_id, slot_type, COUNT(*) AS count").
+		Group("parking_lot_id, slot_type").
+		Scan(&rows).
+		Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]map[string]int)
+	for _, row := range rows {
+		if counts[row.ParkingLotID] == nil {
+			counts[row.ParkingLotID] = make(map[string]int)
+		}
+		counts[row.ParkingLotID][row.SlotType] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// RecordSensorReading stores the latest state reported by the sensor of a slot.
+func (repo *Repository) RecordSensorReading(parkingSlotID uint, occupied bool, reportedAt time.Time) (*models.SlotSensorState, error) {
+	var sensorState models.SlotSensorState
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.ParkingSlot{}, parkingSlotID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&sensorState, "parking_slot_id = ?", parkingSlotID).
+			Error
+		notFound := errors.Is(err, gorm.ErrRecordNotFound)
+		if err != nil && !notFound {
+			return err
+		}
+		if notFound || sensorState.Occupied != occupied {
+			sensorState.ChangedAt = reportedAt
+		}
+		sensorState.ParkingSlotID = parkingSlotID
+		sensorState.Occupied = occupied
+		sensorState.ReportedAt = reportedAt
+		return tx.Save(&sensorState).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sensorState, nil
+}
+
+// ReconcileSensors compares every sensor with the booking state of its slot.
+// It opens a discrepancy for a slot that is occupied but unbooked, or booked
+// but vacant for at least vacantAfter, and resolves discrepancies that no
+// longer hold. The newly opened discrepancies are returned.
+func (repo *Repository) ReconcileSensors(vacantAfter time.Duration, now time.Time) ([]models.SensorDiscrepancy, error) {
+	var sensorStates []models.SlotSensorState
+	if err := repo.DB.Find(&sensorStates).Error; err != nil {
+		return nil, err
+	}
+
+	var opened []models.SensorDiscrepancy
+	for _, sensorState := range sensorStates {
+		var parkingSlot models.ParkingSlot
+		if err := repo.DB.First(&parkingSlot, sensorState.ParkingSlotID).Error; err != nil {
+			return nil, err
+		}
+
+		kind := sensorDiscrepancyKind(sensorState, parkingSlot, vacantAfter, now)
+		discrepancy, err := repo.updateSensorDiscrepancy(parkingSlot, kind, now)
+		if err != nil {
+			return nil, err
+		}
+		if discrepancy != nil {
+			opened = append(opened, *discrepancy)
+		}
+	}
+	return opened, nil
+}
+
+// GetOpenSensorDiscrepancies returns the unresolved discrepancies of a parking lot.
+func (repo *Repository) GetOpenSensorDiscrepancies(parkingLotID uint) ([]models.SensorDiscrepancy, error) {
+	var discrepancies []models.SensorDiscrepancy
+	if err := repo.DB.Where("parking_lot_id = ? AND resolved_at IS NULL", parkingLotID).
+		Order("detected_at").
+		Find(&discrepancies).
+		Error; err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
+
+// updateSensorDiscrepancy resolves the open discrepancies of the slot that
+// differ from kind and opens one of kind if it is not open yet. It returns
+// the opened discrepancy, if any.
+func (repo *Repository) updateSensorDiscrepancy(parkingSlot models.ParkingSlot, kind string, now time.Time) (*models.SensorDiscrepancy, error) {
+	var discrepancy *models.SensorDiscrepancy
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SensorDiscrepancy{}).
+			Where("parking_slot_id = ? AND resolved_at IS NULL AND kind <> ?", parkingSlot.ID, kind).
+			Update("resolved_at", now).
+			Error; err != nil {
+			return err
+		}
+		if kind == "" {
+			return nil
+		}
+
+		var open int64
+		if err := tx.Model(&models.SensorDiscrepancy{}).
+			Where("parking_slot_id = ? AND resolved_at IS NULL AND kind = ?", parkingSlot.ID, kind).
+			Count(&open).
+			Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return nil
+		}
+
+		discrepancy = &models.SensorDiscrepancy{
+			ParkingSlotID: parkingSlot.ID,
+			ParkingLotID:  parkingSlot.ParkingLotID,
+			RelativeID:    parkingSlot.RelativeID,
+			Kind:          kind,
+			DetectedAt:    now,
+		}
+		return tx.Create(discrepancy).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return discrepancy, nil
+}
+
+func sensorDiscrepancyKind(sensorState models.SlotSensorState, parkingSlot models.ParkingSlot, vacantAfter time.Duration, now time.Time) string {
+	if sensorState.Occupied && !parkingSlot.IsBooked {
+		return models.SensorDiscrepancyOccupiedUnbooked
+	}
+	if sensorState.Occupied || !parkingSlot.IsBooked || parkingSlot.IsInMaintenance || parkingSlot.ParkedAt == nil {
+		return ""
+	}
+
+	// The slot only counts as vacant since the later of the sensor change and the park
+	vacantSince := sensorState.ChangedAt
+	if parkingSlot.ParkedAt.After(vacantSince) {
+		vacantSince = *parkingSlot.ParkedAt
+	}
+	if now.Sub(vacantSince) >= vacantAfter {
+		return models.SensorDiscrepancyBookedVacant
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"net/url"
+	"strings"
+)
+
+// sqliteScheme prefixes a DATABASE_URL that selects the SQLite store, as in
+// sqlite:///var/lib/pms/pms.db or sqlite://pms.db for a relative path.
+const sqliteScheme = "sqlite://"
+
+// sqliteDefaults are the connection parameters the repository relies on
+// under SQLite unless the URL sets them. SQLite has no row locks: every
+// transaction takes the database write lock when it begins, so two entries
+// never read the same free slot, and waits up to the busy timeout for it.
+// The write-ahead log lets readers go on while a transaction writes.
+var sqliteDefaults = map[string]string{
+	"_txlock":       "immediate",
+	"_busy_timeout": "10000",
+	"_journal_mode": "WAL",
+	"_foreign_keys": "on",
+}
+
+// sqliteDSN turns a sqlite:// URL into the file name and parameters of the driver.
+func sqliteDSN(databaseUrl string) string {
+	path, query, _ := strings.Cut(strings.TrimPrefix(databaseUrl, sqliteScheme), "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		params = url.Values{}
+	}
+	for key, value := range sqliteDefaults {
+		if params.Get(key) == "" {
+			params.Set(key, value)
+		}
+	}
+	return path + "?" + params.Encode()
+}
@@ -0,0 +1,454 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var (
+	ErrEdgeOperated  = errors.New("parking lot is operated by an edge site")
+	ErrEdgeLotLinked = errors.New("parking lot is already linked")
+)
+
+// syncedEventTypes are the outbox events an edge site sends to the central
+// instance: the visits, and with the exits what was charged for them.
+var syncedEventTypes = []string{
+	models.WebhookEventCarParked,
+	models.WebhookEventCarUnparked,
+	models.WebhookEventCarRelocated,
+}
+
+// edgeOperatedLots selects the IDs of the central lots mirroring an edge site.
+func edgeOperatedLots(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&models.EdgeLotLink{}).Select("parking_lot_id")
+}
+
+// checkEdgeOperated refuses entries recorded centrally into a lot operated by
+// an edge site. Its vehicles enter and leave through the site.
+func (repo *Repository) checkEdgeOperated(parkingLotID uint) error {
+	var links int64
+	if err := repo.DB.Model(&models.EdgeLotLink{}).
+		Where("parking_lot_id = ?", parkingLotID).
+		Count(&links).
+		Error; err != nil {
+		return err
+	}
+	if links > 0 {
+		return ErrEdgeOperated
+	}
+	return nil
+}
+
+// GetUnsyncedEvents returns up to limit outbox events of this edge site that
+// were not sent to the central instance yet, oldest first.
+func (repo *Repository) GetUnsyncedEvents(limit int) ([]models.OutboxEvent, error) {
+	var outboxEvents []models.OutboxEvent
+	if err := repo.DB.Where("synced_at IS NULL AND event_type IN ?", syncedEventTypes).
+		Order("id").
+		Limit(limit).
+		Find(&outboxEvents).
+		Error; err != nil {
+		return nil, err
+	}
+	return outboxEvents, nil
+}
+
+// CountUnsyncedEvents returns the number of outbox events waiting to be sent
+// to the central instance.
+func (repo *Repository) CountUnsyncedEvents() (int64, error) {
+	var count int64
+	err := repo.DB.Model(&models.OutboxEvent{}).
+		Where("synced_at IS NULL AND event_type IN ?", syncedEventTypes).
+		Count(&count).
+		Error
+	return count, err
+}
+
+// MarkEventsSynced records that the central instance applied the outbox events.
+func (repo *Repository) MarkEventsSynced(outboxEventIDs []uint) error {
+	if len(outboxEventIDs) == 0 {
+		return nil
+	}
+	return repo.DB.Model(&models.OutboxEvent{}).
+		Where("id IN ?", outboxEventIDs).
+		Update("synced_at", time.Now()).
+		Error
+}
+
+// LinkEdgeLot makes a central lot the mirror of a lot of an edge site. The
+// central lot stops accepting entries of its own.
+func (repo *Repository) LinkEdgeLot(link *models.EdgeLotLink) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.ParkingLot{}, link.ParkingLotID).Error; err != nil {
+			return err
+		}
+
+		var linked int64
+		if err := tx.Model(&models.EdgeLotLink{}).
+			Where("(site_id = ? AND edge_lot_id = ?) OR parking_lot_id = ?", link.SiteID, link.EdgeLotID, link.ParkingLotID).
+			Count(&linked).
+			Error; err != nil {
+			return err
+		}
+		if linked > 0 {
+			return ErrEdgeLotLinked
+		}
+		return tx.Create(link).Error
+	})
+}
+
+// GetEdgeLotLinks returns the links of an edge site, or of every site when
+// the site ID is empty.
+func (repo *Repository) GetEdgeLotLinks(siteID string) ([]models.EdgeLotLink, error) {
+	query := repo.DB.Order("id")
+	if siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+
+	var links []models.EdgeLotLink
+	if err := query.Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// GetSyncConflicts returns the conflicts raised by the events of an edge
+// site, latest first, optionally only those of one central lot.
+func (repo *Repository) GetSyncConflicts(siteID string, parkingLotID uint, limit int) ([]models.SyncConflict, error) {
+	query := repo.DB.Order("id DESC").Limit(limit)
+	if siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+	if parkingLotID != 0 {
+		query = query.Where("parking_lot_id = ?", parkingLotID)
+	}
+
+	var conflicts []models.SyncConflict
+	if err := query.Find(&conflicts).Error; err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// ApplyEdgeEvents applies the events of an edge site to the central lots
+// mirroring its lots, each in its own transaction and in the order given.
+// Events applied before are skipped, so a batch can safely be sent again.
+// It stops at the first event that cannot be applied and returns it as the
+// error, together with the IDs of the events applied up to there.
+func (repo *Repository) ApplyEdgeEvents(siteID string, syncEvents []models.SyncEvent) ([]uint, []models.SyncConflict, error) {
+	applied := make([]uint, 0, len(syncEvents))
+	var conflicts []models.SyncConflict
+	for _, syncEvent := range syncEvents {
+		var changed []models.ParkingSlot
+		var raised []models.SyncConflict
+		err := repo.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			changed, raised, err = applyEdgeEvent(tx, siteID, syncEvent)
+			return err
+		})
+		if err != nil {
+			return applied, conflicts, fmt.Errorf("event %d: %w", syncEvent.ID, err)
+		}
+
+		applied = append(applied, syncEvent.ID)
+		conflicts = append(conflicts, raised...)
+		for _, parkingSlot := range changed {
+			repo.publishSlotChange(parkingSlot, edgeEventReason(syncEvent.EventType))
+		}
+	}
+	return applied, conflicts, nil
+}
+
+// edgeVisitTime brings a time of an edge site to the precision and zone the
+// central database keeps, so visits can be matched by the time they started.
+func edgeVisitTime(t time.Time) time.Time {
+	return t.Round(time.Microsecond).Local()
+}
+
+func edgeEventReason(eventType string) string {
+	switch eventType {
+	case models.WebhookEventCarUnparked:
+		return events.ReasonUnpark
+	case models.WebhookEventCarRelocated:
+		return events.ReasonRelocate
+	default:
+		return events.ReasonPark
+	}
+}
+
+// edgeEvent is an edge event being applied to the central lot mirroring the
+// edge lot, with the conflicts it raised.
+type edgeEvent struct {
+	tx           *gorm.DB
+	siteID       string
+	syncEvent    models.SyncEvent
+	parkingLotID uint
+	occurredAt   time.Time
+	conflicts    []models.SyncConflict
+}
+
+func applyEdgeEvent(tx *gorm.DB, siteID string, syncEvent models.SyncEvent) ([]models.ParkingSlot, []models.SyncConflict, error) {
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(syncEvent.Data, &payload); err != nil {
+		return nil, nil, err
+	}
+
+	// An event already applied is acknowledged again without applying it
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SyncedEvent{
+		SiteID:    siteID,
+		EventID:   syncEvent.ID,
+		EventType: syncEvent.EventType,
+		AppliedAt: time.Now(),
+	})
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, nil
+	}
+
+	// An event of a lot that is not linked is skipped rather than holding up
+	// the events queued behind it at the site
+	var link models.EdgeLotLink
+	err := tx.Where("site_id = ? AND edge_lot_id = ?", siteID, payload.ParkingLotID).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		event := &edgeEvent{tx: tx, siteID: siteID, syncEvent: syncEvent, occurredAt: edgeVisitTime(payload.OccurredAt)}
+		resolution := fmt.Sprintf("event of edge lot %d skipped, the lot is not linked", payload.ParkingLotID)
+		if err := event.conflict(models.SyncConflictLotUnlinked, 0, resolution); err != nil {
+			return nil, nil, err
+		}
+		return nil, event.conflicts, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event := &edgeEvent{
+		tx:           tx,
+		siteID:       siteID,
+		syncEvent:    syncEvent,
+		parkingLotID: link.ParkingLotID,
+		occurredAt:   edgeVisitTime(payload.OccurredAt),
+	}
+	var changed []models.ParkingSlot
+	switch syncEvent.EventType {
+	case models.WebhookEventCarParked:
+		changed, err = event.applyPark(payload)
+	case models.WebhookEventCarUnparked:
+		changed, err = event.applyUnpark(payload)
+	case models.WebhookEventCarRelocated:
+		changed, err = event.applyRelocation(payload)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return changed, event.conflicts, nil
+}
+
+// applyPark books the central slot for a vehicle that entered at the site.
+// The vehicle is there, so the slot is booked whatever it was used for
+// centrally.
+func (e *edgeEvent) applyPark(payload models.WebhookPayload) ([]models.ParkingSlot, error) {
+	if payload.Slot == nil || payload.Slot.ParkedAt == nil {
+		return nil, nil
+	}
+	parkedAt := edgeVisitTime(*payload.Slot.ParkedAt)
+
+	parkingSlot, err := e.slot(payload.Slot.RelativeID)
+	if err != nil || parkingSlot == nil {
+		return nil, err
+	}
+	if parkingSlot.IsBooked && parkingSlot.ParkedAt != nil && parkingSlot.ParkedAt.Equal(parkedAt) {
+		return nil, nil
+	}
+	if parkingSlot.IsBooked {
+		if err := e.conflict(models.SyncConflictSlotOccupied, parkingSlot.RelativeID, "central visit replaced by the edge visit"); err != nil {
+			return nil, err
+		}
+	}
+
+	parkingSlot.CarID = nil
+	parkingSlot.TicketSessionID = nil
+	if err := occupyParkingSlot(e.tx, parkingSlot, parkedAt); err != nil {
+		return nil, err
+	}
+	return []models.ParkingSlot{*parkingSlot}, nil
+}
+
+// applyUnpark frees the central slot of a vehicle that left the site and adds
+// what the site charged to the history. The charge was collected at the exit,
+// so it is recorded even when the visit is not found centrally.
+func (e *edgeEvent) applyUnpark(payload models.WebhookPayload) ([]models.ParkingSlot, error) {
+	if payload.Slot == nil || payload.ParkedAt == nil {
+		return nil, nil
+	}
+	parkedAt := edgeVisitTime(*payload.ParkedAt)
+	charge := models.ParkingCharge{}
+	if payload.Charge != nil {
+		charge = *payload.Charge
+	}
+	if err := recordParkingHistory(e.tx, e.occurredAt, charge); err != nil {
+		return nil, err
+	}
+
+	parkingSlot, err := e.visitSlot(payload.Slot.RelativeID, parkedAt, "visit ended where it was moved centrally")
+	if err != nil || parkingSlot == nil {
+		return nil, err
+	}
+
+	parkingSlot.IsBooked = false
+	parkingSlot.CarID = nil
+	parkingSlot.TicketSessionID = nil
+	parkingSlot.ParkedAt = nil
+	parkingSlot.UnparkedAt = &e.occurredAt
+	if parkingSlot.IsDraining {
+		parkingSlot.IsDraining = false
+		parkingSlot.DecommissionedAt = &e.occurredAt
+	}
+	if err := e.tx.Save(parkingSlot).Error; err != nil {
+		return nil, err
+	}
+
+	if err := enqueueOutboxEvent(e.tx, models.WebhookPayload{
+		EventType:    models.WebhookEventCarUnparked,
+		ParkingLotID: parkingSlot.ParkingLotID,
+		Slot:         parkingSlot,
+		Charge:       &charge,
+		ParkedAt:     &parkedAt,
+		OccurredAt:   e.occurredAt,
+	}); err != nil {
+		return nil, err
+	}
+	return []models.ParkingSlot{*parkingSlot}, nil
+}
+
+// applyRelocation moves the central booking of a visit along with a vehicle
+// moved at the site. A move made centrally in the meantime is overridden.
+func (e *edgeEvent) applyRelocation(payload models.WebhookPayload) ([]models.ParkingSlot, error) {
+	if payload.Slot == nil || payload.FromSlot == nil || payload.Slot.ParkedAt == nil {
+		return nil, nil
+	}
+	parkedAt := edgeVisitTime(*payload.Slot.ParkedAt)
+
+	fromSlot, err := e.visitSlot(payload.FromSlot.RelativeID, parkedAt, "visit moved from where it was moved centrally")
+	if err != nil {
+		return nil, err
+	}
+	toSlot, err := e.slot(payload.Slot.RelativeID)
+	if err != nil {
+		return nil, err
+	}
+	if fromSlot != nil && toSlot != nil && fromSlot.ID == toSlot.ID {
+		return nil, nil
+	}
+
+	var changed []models.ParkingSlot
+	if fromSlot != nil {
+		fromSlot.IsBooked = false
+		fromSlot.CarID = nil
+		fromSlot.TicketSessionID = nil
+		fromSlot.ParkedAt = nil
+		fromSlot.UnparkedAt = &e.occurredAt
+		if err := e.tx.Save(fromSlot).Error; err != nil {
+			return nil, err
+		}
+		changed = append(changed, *fromSlot)
+	}
+	if toSlot == nil {
+		return changed, nil
+	}
+	if toSlot.IsBooked {
+		if err := e.conflict(models.SyncConflictSlotOccupied, toSlot.RelativeID, "central visit replaced by the edge visit"); err != nil {
+			return nil, err
+		}
+	}
+	toSlot.IsBooked = true
+	toSlot.CarID = nil
+	toSlot.TicketSessionID = nil
+	toSlot.ParkedAt = &parkedAt
+	toSlot.UnparkedAt = nil
+	if err := e.tx.Save(toSlot).Error; err != nil {
+		return nil, err
+	}
+
+	if err := enqueueOutboxEvent(e.tx, models.WebhookPayload{
+		EventType:    models.WebhookEventCarRelocated,
+		ParkingLotID: toSlot.ParkingLotID,
+		Slot:         toSlot,
+		FromSlot:     fromSlot,
+		OccurredAt:   e.occurredAt,
+	}); err != nil {
+		return nil, err
+	}
+	return append(changed, *toSlot), nil
+}
+
+// slot locks the central slot with the relative ID of an edge slot. A slot
+// missing centrally raises a conflict and nil is returned.
+func (e *edgeEvent) slot(relativeID uint) (*models.ParkingSlot, error) {
+	var parkingSlot models.ParkingSlot
+	err := e.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(inService).
+		Where("parking_lot_id = ? AND relative_id = ?", e.parkingLotID, relativeID).
+		First(&parkingSlot).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, e.conflict(models.SyncConflictSlotMissing, relativeID, "visit not mirrored centrally")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &parkingSlot, nil
+}
+
+// visitSlot locks the central slot holding the visit that started at parkedAt
+// in the edge slot with the relative ID. The visit is looked up across the
+// lot, as it may have been moved centrally, which raises a conflict with the
+// given resolution. A visit missing centrally raises a conflict and nil is
+// returned.
+func (e *edgeEvent) visitSlot(relativeID uint, parkedAt time.Time, movedResolution string) (*models.ParkingSlot, error) {
+	var parkingSlots []models.ParkingSlot
+	if err := e.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("parking_lot_id = ? AND is_booked = ? AND parked_at = ?", e.parkingLotID, true, parkedAt).
+		Order("relative_id").
+		Find(&parkingSlots).
+		Error; err != nil {
+		return nil, err
+	}
+	if len(parkingSlots) == 0 {
+		return nil, e.conflict(models.SyncConflictVisitMissing, relativeID, "nothing to free centrally")
+	}
+	for i := range parkingSlots {
+		if parkingSlots[i].RelativeID == relativeID {
+			return &parkingSlots[i], nil
+		}
+	}
+	if err := e.conflict(models.SyncConflictMovedCentrally, relativeID, movedResolution); err != nil {
+		return nil, err
+	}
+	return &parkingSlots[0], nil
+}
+
+func (e *edgeEvent) conflict(kind string, relativeID uint, resolution string) error {
+	conflict := models.SyncConflict{
+		SiteID:       e.siteID,
+		ParkingLotID: e.parkingLotID,
+		EventID:      e.syncEvent.ID,
+		EventType:    e.syncEvent.EventType,
+		Kind:         kind,
+		RelativeID:   relativeID,
+		Resolution:   resolution,
+		OccurredAt:   e.occurredAt,
+	}
+	if err := e.tx.Create(&conflict).Error; err != nil {
+		return err
+	}
+	e.conflicts = append(e.conflicts, conflict)
+	return nil
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"strings"
+	"time"
+)
+
+var ErrTicketAlreadyClosed = errors.New("ticket session is already closed")
+
+// IssueTicket parks an unregistered vehicle in the first available slot of
+// the parking lot and opens a walk-in session for it. The plate is optional
+// and only used to find the session again if the ticket gets lost.
+func (repo *Repository) IssueTicket(parkingLotID uint, plate string) (*models.TicketSession, error) {
+	// Refuse blocklisted plates before allocating anything
+	if err := repo.checkEntry(parkingLotID, plate, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := repo.checkOpen(parkingLotID, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := repo.checkSelfService(parkingLotID); err != nil {
+		return nil, err
+	}
+	if err := repo.checkEdgeOperated(parkingLotID); err != nil {
+		return nil, err
+	}
+
+	var session *models.TicketSession
+	var parkingSlot *models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		session, parkingSlot, err = repo.openTicketSession(tx, parkingLotID, plate, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo.publishSlotChange(*parkingSlot, events.ReasonPark)
+	return session, nil
+}
+
+// openTicketSession parks a vehicle in the slot held for its plate, or else
+// the first available one, and opens a walk-in session for the visit.
+func (repo *Repository) openTicketSession(tx *gorm.DB, parkingLotID uint, plate string, isValet bool) (*models.TicketSession, *models.ParkingSlot, error) {
+	parkingSlot, err := repo.allocateParkingSlot(tx, parkingLotID, nil, plate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parkingSlot == nil {
+		return nil, nil, ErrNoAvailableParkingSlot
+	}
+
+	ticketCode, err := newTicketCode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentTime := time.Now()
+	session := &models.TicketSession{
+		TicketCode:     ticketCode,
+		Plate:          NormalizePlate(plate),
+		ParkingLotID:   parkingLotID,
+		ParkingSlotID:  parkingSlot.ID,
+		RelativeSlotID: parkingSlot.RelativeID,
+		EnteredAt:      currentTime,
+		IsValet:        isValet,
+	}
+	if err := tx.Create(session).Error; err != nil {
+		return nil, nil, err
+	}
+
+	parkingSlot.TicketSessionID = &session.ID
+	if err := occupyParkingSlot(tx, parkingSlot, currentTime); err != nil {
+		return nil, nil, err
+	}
+	return session, parkingSlot, nil
+}
+
+// GetTicketSession looks up a walk-in session by its ticket code.
+func (repo *Repository) GetTicketSession(ticketCode string) (*models.TicketSession, error) {
+	var session models.TicketSession
+	if err := repo.DB.Where("ticket_code = ?", normalizeTicketCode(ticketCode)).
+		First(&session).
+		Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CloseTicket ends the walk-in session of the ticket, frees its slot and
+// charges the parking fee the same way as for registered cars. The attendant
+// who closed it from the ticket code, rather than from a scanned ticket or a
+// plate read, is recorded on the session.
+func (repo *Repository) CloseTicket(ticketCode, attendant string) (*models.TicketSession, error) {
+	session, err := repo.GetTicketSession(ticketCode)
+	if err != nil {
+		return nil, err
+	}
+	if session.ExitedAt != nil {
+		return nil, ErrTicketAlreadyClosed
+	}
+	if session.IsValet {
+		return nil, ErrValetTicket
+	}
+
+	// Allowlisted plates park free of charge
+	calculateCharge := CalculateParkingCharge
+	entry, err := findAccessListEntry(repo.DB, models.AccessListAllow, session.ParkingLotID, session.Plate, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		calculateCharge = allowlistedCharge
+	}
+
+	var updates map[string]interface{}
+	if attendant != "" {
+		updates = map[string]interface{}{"handled_by": attendant}
+	}
+	if _, err := repo.closeTicketSession(session, calculateCharge, updates); err != nil {
+		return nil, err
+	}
+
+	session.HandledBy = attendant
+	return session, nil
+}
+
+// closeTicketSession frees the slot of an open session and stores what was
+// charged for it, together with any extra column updates of the exit flow.
+func (repo *Repository) closeTicketSession(session *models.TicketSession, calculateCharge chargeFunc, updates map[string]interface{}) (*models.ParkingCharge, error) {
+	var parkingSlot *models.ParkingSlot
+	var charge *models.ParkingCharge
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		parkingSlot, charge, err = closeTicketSessionTx(tx, session, calculateCharge, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo.publishSlotChange(*parkingSlot, events.ReasonUnpark)
+	return charge, nil
+}
+
+// closeTicketSessionTx closes the session within the transaction. The freed
+// slot is returned so the change can be published after the commit.
+func closeTicketSessionTx(tx *gorm.DB, session *models.TicketSession, calculateCharge chargeFunc, updates map[string]interface{}) (*models.ParkingSlot, *models.ParkingCharge, error) {
+	// Lock the slot, then the session, in the order relocations lock them
+	var parkingSlot models.ParkingSlot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&parkingSlot, session.ParkingSlotID).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(session, session.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	// Another exit or a move may have been committed since the session was read
+	if session.ExitedAt != nil {
+		return nil, nil, ErrTicketAlreadyClosed
+	}
+	if session.ParkingSlotID != parkingSlot.ID ||
+		parkingSlot.TicketSessionID == nil || *parkingSlot.TicketSessionID != session.ID {
+		return nil, nil, ErrNotParked
+	}
+
+	charge, err := releaseParkingSlot(tx, &parkingSlot, calculateCharge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := map[string]interface{}{
+		"exited_at":               parkingSlot.UnparkedAt,
+		"total_parking_time":      charge.TotalParkingTime,
+		"total_amount_to_be_paid": charge.TotalAmountToBePaid,
+	}
+	for column, value := range updates {
+		columns[column] = value
+	}
+
+	// Only the first exit of a ticket may close it
+	result := tx.Model(session).
+		Where("exited_at IS NULL").
+		Updates(columns)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrTicketAlreadyClosed
+	}
+
+	session.ExitedAt = parkingSlot.UnparkedAt
+	session.TotalParkingTime = charge.TotalParkingTime
+	session.TotalAmountToBePaid = charge.TotalAmountToBePaid
+	return &parkingSlot, charge, nil
+}
+
+// newTicketCode returns a random code that is short enough to be typed in
+// by an attendant.
+func newTicketCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+func normalizeTicketCode(ticketCode string) string {
+	return strings.ToUpper(strings.TrimSpace(ticketCode))
+}
+
+// NormalizePlate strips spaces and dashes so plates typed by attendants match
+// the ones captured at entry.
+func NormalizePlate(plate string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(plate)))
+}
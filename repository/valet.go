@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+var (
+	ErrValetOnly           = errors.New("parking lot only accepts valet parking")
+	ErrNotValetLot         = errors.New("parking lot is not in valet mode")
+	ErrValetTicket         = errors.New("valet tickets are closed by delivering the car")
+	ErrKeyTagInUse         = errors.New("key tag is already in use in the parking lot")
+	ErrValetTicketSequence = errors.New("valet ticket is not in a state that allows this step")
+)
+
+// checkSelfService refuses drivers parking themselves in a valet lot.
+func (repo *Repository) checkSelfService(parkingLotID uint) error {
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return err
+	}
+	if parkingLot.ValetMode {
+		return ErrValetOnly
+	}
+	return nil
+}
+
+// ParkValetCar records a car parked by the valet staff. The visit is a walk-in
+// session whose ticket code is the claim code handed to the driver.
+func (repo *Repository) ParkValetCar(parkingLotID uint, plate, keyTag, attendant string) (*models.ValetTicket, error) {
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return nil, err
+	}
+	if !parkingLot.ValetMode {
+		return nil, ErrNotValetLot
+	}
+	if err := repo.checkEntry(parkingLotID, plate, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := repo.checkOpen(parkingLotID, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := repo.checkEdgeOperated(parkingLotID); err != nil {
+		return nil, err
+	}
+
+	var valetTicket models.ValetTicket
+	var parkingSlot *models.ParkingSlot
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		// A key tag identifies the keys of a single car on the key board. The
+		// count gives the usual answer, the unique index of the undelivered
+		// tickets settles two check-ins racing for the same tag.
+		var inUse int64
+		if err := tx.Model(&models.ValetTicket{}).
+			Where("parking_lot_id = ? AND key_tag = ? AND status <> ?", parkingLotID, keyTag, models.ValetTicketDelivered).
+			Count(&inUse).
+			Error; err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return ErrKeyTagInUse
+		}
+
+		session, slot, err := repo.openTicketSession(tx, parkingLotID, plate, true)
+		if err != nil {
+			return err
+		}
+		parkingSlot = slot
+
+		valetTicket = models.ValetTicket{
+			TicketSessionID: session.ID,
+			ClaimCode:       session.TicketCode,
+			ParkingLotID:    parkingLotID,
+			ParkingSlotID:   session.ParkingSlotID,
+			RelativeSlotID:  session.RelativeSlotID,
+			KeyTag:          keyTag,
+			Plate:           session.Plate,
+			ParkedBy:        attendant,
+			ParkedAt:        session.EnteredAt,
+			Status:          models.ValetTicketParked,
+		}
+		if err := tx.Create(&valetTicket).Error; err != nil {
+			if isDuplicatedKey(tx, err) {
+				return ErrKeyTagInUse
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo.publishSlotChange(*parkingSlot, events.ReasonPark)
+	return &valetTicket, nil
+}
+
+// GetValetTicket looks up a valet ticket by the claim code of the driver.
+func (repo *Repository) GetValetTicket(claimCode string) (*models.ValetTicket, error) {
+	var valetTicket models.ValetTicket
+	if err := repo.DB.Where("claim_code = ?", normalizeTicketCode(claimCode)).
+		First(&valetTicket).
+		Error; err != nil {
+		return nil, err
+	}
+	return &valetTicket, nil
+}
+
+// RequestValetCar asks for the car of the claim code to be brought to the
+// pickup point at pickupAt, or as soon as possible when it is in the past.
+// A driver may request again to change the pickup time until an attendant
+// is on the way.
+func (repo *Repository) RequestValetCar(claimCode string, pickupAt time.Time) (*models.ValetTicket, error) {
+	valetTicket, err := repo.GetValetTicket(claimCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.advanceValetTicket(valetTicket.ID, []string{models.ValetTicketParked, models.ValetTicketRequested},
+		func(valetTicket *models.ValetTicket, parkingLot models.ParkingLot, now time.Time) {
+			eta := now.Add(time.Duration(parkingLot.ValetRetrievalMinutes) * time.Minute)
+			if pickupAt.After(eta) {
+				eta = pickupAt
+			}
+			valetTicket.Status = models.ValetTicketRequested
+			valetTicket.PickupAt = &pickupAt
+			valetTicket.RequestedAt = &now
+			valetTicket.PickupETA = &eta
+		})
+}
+
+// GetValetQueue returns the cars the valet staff have to bring out, the ones
+// due first at the top.
+func (repo *Repository) GetValetQueue(parkingLotID uint) ([]models.ValetTicket, error) {
+	var valetTickets []models.ValetTicket
+	if err := repo.DB.Where("parking_lot_id = ? AND status IN ?", parkingLotID,
+		[]string{models.ValetTicketRequested, models.ValetTicketRetrieving, models.ValetTicketReady}).
+		Order("pickup_eta").
+		Find(&valetTickets).
+		Error; err != nil {
+		return nil, err
+	}
+	return valetTickets, nil
+}
+
+// StartValetRetrieval records the attendant on the way to the car. Staff may
+// also bring out a car that was not requested. An eta of zero keeps the
+// expected pickup time, or uses the retrieval time of the lot if there is none.
+func (repo *Repository) StartValetRetrieval(valetTicketID uint, attendant string, eta time.Duration) (*models.ValetTicket, error) {
+	return repo.advanceValetTicket(valetTicketID, []string{models.ValetTicketParked, models.ValetTicketRequested},
+		func(valetTicket *models.ValetTicket, parkingLot models.ParkingLot, now time.Time) {
+			if eta <= 0 && valetTicket.PickupETA == nil {
+				eta = time.Duration(parkingLot.ValetRetrievalMinutes) * time.Minute
+			}
+			if eta > 0 {
+				pickupETA := now.Add(eta)
+				valetTicket.PickupETA = &pickupETA
+			}
+			valetTicket.Status = models.ValetTicketRetrieving
+			valetTicket.RetrievedBy = attendant
+		})
+}
+
+// MarkValetCarReady records the car waiting at the pickup point.
+func (repo *Repository) MarkValetCarReady(valetTicketID uint) (*models.ValetTicket, error) {
+	return repo.advanceValetTicket(valetTicketID, []string{models.ValetTicketRetrieving},
+		func(valetTicket *models.ValetTicket, parkingLot models.ParkingLot, now time.Time) {
+			valetTicket.Status = models.ValetTicketReady
+			valetTicket.ReadyAt = &now
+			valetTicket.PickupETA = &now
+		})
+}
+
+// DeliverValetCar hands the car back to the driver. The walk-in session of the
+// visit is closed and charged with the valet fee of the lot on top.
+func (repo *Repository) DeliverValetCar(valetTicketID uint, attendant string) (*models.ValetTicket, *models.ParkingCharge, error) {
+	var valetTicket models.ValetTicket
+	var parkingSlot *models.ParkingSlot
+	var charge *models.ParkingCharge
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the slot before the valet ticket, in the order relocations
+		// lock them. A move committed in between is caught when the session
+		// is closed.
+		if err := tx.First(&valetTicket, valetTicketID).Error; err != nil {
+			return err
+		}
+		var slot models.ParkingSlot
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&slot, valetTicket.ParkingSlotID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&valetTicket, valetTicketID).Error; err != nil {
+			return err
+		}
+		if valetTicket.Status == models.ValetTicketDelivered {
+			return ErrTicketAlreadyClosed
+		}
+
+		var parkingLot models.ParkingLot
+		if err := tx.First(&parkingLot, valetTicket.ParkingLotID).Error; err != nil {
+			return err
+		}
+		var session models.TicketSession
+		if err := tx.First(&session, valetTicket.TicketSessionID).Error; err != nil {
+			return err
+		}
+
+		// Allowlisted plates park free of charge, the valet service included
+		calculateCharge := CalculateParkingCharge
+		entry, err := findAccessListEntry(tx, models.AccessListAllow, session.ParkingLotID, session.Plate, nil, time.Now())
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			calculateCharge = allowlistedCharge
+		}
+
+		parkingSlot, charge, err = closeTicketSessionTx(tx, &session, valetCharge(calculateCharge, parkingLot.ValetFee), nil)
+		if err != nil {
+			return err
+		}
+
+		valetTicket.Status = models.ValetTicketDelivered
+		valetTicket.DeliveredAt = session.ExitedAt
+		valetTicket.DeliveredBy = attendant
+		return tx.Save(&valetTicket).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo.publishSlotChange(*parkingSlot, events.ReasonUnpark)
+	return &valetTicket, charge, nil
+}
+
+// advanceValetTicket applies a step of the retrieval to the valet ticket if
+// it is in one of the given states.
+func (repo *Repository) advanceValetTicket(valetTicketID uint, from []string, step func(*models.ValetTicket, models.ParkingLot, time.Time)) (*models.ValetTicket, error) {
+	var valetTicket models.ValetTicket
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&valetTicket, valetTicketID).Error; err != nil {
+			return err
+		}
+		allowed := false
+		for _, status := range from {
+			allowed = allowed || valetTicket.Status == status
+		}
+		if !allowed {
+			return ErrValetTicketSequence
+		}
+
+		var parkingLot models.ParkingLot
+		if err := tx.First(&parkingLot, valetTicket.ParkingLotID).Error; err != nil {
+			return err
+		}
+		step(&valetTicket, parkingLot, time.Now())
+		return tx.Save(&valetTicket).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &valetTicket, nil
+}
+
+// UpdateValetSettings turns the valet mode of a parking lot on or off and
+// sets its valet fee and the time it takes to bring a car out.
+func (repo *Repository) UpdateValetSettings(parkingLotID uint, valetMode bool, valetFee, retrievalMinutes int) (*models.ParkingLot, error) {
+	var parkingLot models.ParkingLot
+	if err := repo.DB.First(&parkingLot, parkingLotID).Error; err != nil {
+		return nil, err
+	}
+	parkingLot.ValetMode = valetMode
+	parkingLot.ValetFee = valetFee
+	parkingLot.ValetRetrievalMinutes = retrievalMinutes
+	if err := repo.DB.Model(&parkingLot).
+		Select("valet_mode", "valet_fee", "valet_retrieval_minutes").
+		Updates(&parkingLot).
+		Error; err != nil {
+		return nil, err
+	}
+	return &parkingLot, nil
+}
+
+// valetCharge adds the valet fee to the charge of the stay, unless the stay
+// is free of charge.
+func valetCharge(calculateCharge chargeFunc, valetFee int) chargeFunc {
+	return func(parkedAt, unparkedAt time.Time) models.ParkingCharge {
+		charge := calculateCharge(parkedAt, unparkedAt)
+		if !charge.IsFreeOfCharge && valetFee > 0 {
+			charge.ValetFee = valetFee
+			charge.TotalAmountToBePaid += valetFee
+		}
+		return charge
+	}
+}
+
+// isDuplicatedKey reports whether err is the violation of a unique index.
+func isDuplicatedKey(db *gorm.DB, err error) bool {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
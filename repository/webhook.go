@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"parkingManagementSystem/models"
+	"time"
+)
+
+// enqueueOutboxEvent writes a lifecycle event to the outbox. It must run in
+// the transaction of the state change so the event is stored if and only if
+// the change is committed.
+func enqueueOutboxEvent(tx *gorm.DB, payload models.WebhookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		EventType:    payload.EventType,
+		ParkingLotID: payload.ParkingLotID,
+		Payload:      string(data),
+	}).Error
+}
+
+func (repo *Repository) CreateWebhookSubscription(sub *models.WebhookSubscription) error {
+	return repo.DB.Create(sub).Error
+}
+
+func (repo *Repository) GetWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := repo.DB.Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeactivateWebhookSubscription stops new deliveries to the subscription.
+// Its delivery log is kept.
+func (repo *Repository) DeactivateWebhookSubscription(subscriptionID uint) error {
+	result := repo.DB.Model(&models.WebhookSubscription{}).
+		Where("id = ?", subscriptionID).
+		Update("is_active", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FanOutOutboxEvents turns up to limit undispatched outbox events into one
+// pending delivery per matching subscription and marks them dispatched. Rows
+// claimed by another replica are skipped. It returns the number of events handled.
+func (repo *Repository) FanOutOutboxEvents(limit int) (int, error) {
+	var handled int
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var outboxEvents []models.OutboxEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&outboxEvents).
+			Error; err != nil {
+			return err
+		}
+		if len(outboxEvents) == 0 {
+			return nil
+		}
+
+		var subs []models.WebhookSubscription
+		if err := tx.Where("is_active = ?", true).Find(&subs).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, outboxEvent := range outboxEvents {
+			for _, sub := range subs {
+				if !sub.Matches(outboxEvent.EventType, outboxEvent.ParkingLotID) {
+					continue
+				}
+				if err := tx.Create(&models.WebhookDelivery{
+					SubscriptionID: sub.ID,
+					OutboxEventID:  outboxEvent.ID,
+					EventType:      outboxEvent.EventType,
+					Status:         models.WebhookDeliveryPending,
+					NextAttemptAt:  now,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&outboxEvent).Update("dispatched_at", now).Error; err != nil {
+				return err
+			}
+		}
+		handled = len(outboxEvents)
+		return nil
+	})
+	return handled, err
+}
+
+// ClaimDueWebhookDeliveries returns up to limit pending deliveries whose next
+// attempt is due, with their subscription and event loaded. Their next
+// attempt is pushed back by lease so no other replica sends them meanwhile.
+// Deliveries of inactive subscriptions stay pending until the subscription
+// is activated again.
+func (repo *Repository) ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Where("subscription_id IN (?)", tx.Model(&models.WebhookSubscription{}).Select("id").Where("is_active = ?", true)).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).
+			Error; err != nil {
+			return err
+		}
+
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = now.Add(lease)
+			if err := tx.Model(&deliveries[i]).Update("next_attempt_at", deliveries[i].NextAttemptAt).Error; err != nil {
+				return err
+			}
+			if err := tx.First(&deliveries[i].Subscription, deliveries[i].SubscriptionID).Error; err != nil {
+				return err
+			}
+			if err := tx.First(&deliveries[i].OutboxEvent, deliveries[i].OutboxEventID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SaveWebhookDelivery stores the outcome of a delivery attempt.
+func (repo *Repository) SaveWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return repo.DB.Model(delivery).
+		Omit(clause.Associations).
+		Select("status", "attempts", "last_status_code", "last_error", "next_attempt_at", "delivered_at").
+		Updates(delivery).
+		Error
+}
+
+// GetWebhookDeliveries returns the delivery log of a subscription, newest first.
+func (repo *Repository) GetWebhookDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := repo.DB.Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook queues a delivery to be sent again right away, whatever
+// its current status, with a fresh retry budget.
+func (repo *Repository) RedeliverWebhook(deliveryID uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := repo.DB.First(&delivery, deliveryID).Error; err != nil {
+		return nil, err
+	}
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+	if err := repo.SaveWebhookDelivery(&delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
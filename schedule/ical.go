@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"parkingManagementSystem/models"
+	"strings"
+	"time"
+)
+
+// maxEventDays bounds how many closed dates a single calendar event may expand to.
+const maxEventDays = 31
+
+// ParseHolidays reads the events of an iCalendar file as closed dates. An
+// event closes every day from its start date up to, but not including, its
+// end date, or only its start date when it has no end. The event summary
+// becomes the reason; a date covered by several events keeps the first.
+func ParseHolidays(r io.Reader) ([]models.LotExceptionDate, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []models.LotExceptionDate
+	seen := make(map[string]bool)
+	var inEvent bool
+	var start, end time.Time
+	var summary string
+	for number, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";") // Parameters such as VALUE=DATE are not needed
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent = true
+				start, end, summary = time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			date, err := parseDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				start = date
+			} else {
+				end = date
+			}
+		case "SUMMARY":
+			if inEvent {
+				summary = unescape(value)
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("line %d: event without DTSTART", number+1)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day, n := start, 0; day.Before(end) && n < maxEventDays; day, n = day.AddDate(0, 0, 1), n+1 {
+				if seen[day.Format(DateLayout)] {
+					continue
+				}
+				seen[day.Format(DateLayout)] = true
+				holidays = append(holidays, models.LotExceptionDate{
+					Date:   day.Format(DateLayout),
+					Closed: true,
+					Reason: summary,
+				})
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// unfold joins the continuation lines of a content line, which start with a
+// space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseDate reads the calendar date of a DATE or DATE-TIME value.
+func parseDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+func unescape(text string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(text)
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"parkingManagementSystem/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHolidays(t *testing.T) {
+	tests := []struct {
+		name    string
+		ics     string
+		want    []models.LotExceptionDate
+		wantErr bool
+	}{
+		{
+			name: "all-day event",
+			ics: `BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20241225
+DTEND;VALUE=DATE:20241226
+SUMMARY:Christmas Day
+END:VEVENT
+END:VCALENDAR`,
+			want: []models.LotExceptionDate{{Date: "2024-12-25", Closed: true, Reason: "Christmas Day"}},
+		},
+		{
+			name: "end date is excluded",
+			ics: `BEGIN:VEVENT
+DTSTART;VALUE=DATE:20241224
+DTEND;VALUE=DATE:20241227
+SUMMARY:Holidays
+END:VEVENT`,
+			want: []models.LotExceptionDate{
+				{Date: "2024-12-24", Closed: true, Reason: "Holidays"},
+				{Date: "2024-12-25", Closed: true, Reason: "Holidays"},
+				{Date: "2024-12-26", Closed: true, Reason: "Holidays"},
+			},
+		},
+		{
+			name: "no end date closes the start date",
+			ics: `BEGIN:VEVENT
+DTSTART:20240101
+SUMMARY:New Year
+END:VEVENT`,
+			want: []models.LotExceptionDate{{Date: "2024-01-01", Closed: true, Reason: "New Year"}},
+		},
+		{
+			name: "date-time values and CRLF line endings",
+			ics:  "BEGIN:VEVENT\r\nDTSTART:20240501T000000Z\r\nDTEND:20240501T235900Z\r\nSUMMARY:Labour Day\r\nEND:VEVENT\r\n",
+			want: []models.LotExceptionDate{{Date: "2024-05-01", Closed: true, Reason: "Labour Day"}},
+		},
+		{
+			name: "folded and escaped summary",
+			ics:  "BEGIN:VEVENT\nDTSTART:20241003\nSUMMARY:Day of German\n  Unity\\, national holiday\nEND:VEVENT",
+			want: []models.LotExceptionDate{{Date: "2024-10-03", Closed: true, Reason: "Day of German Unity, national holiday"}},
+		},
+		{
+			name: "overlapping events keep the first reason",
+			ics: `BEGIN:VEVENT
+DTSTART:20241225
+DTEND:20241227
+SUMMARY:Christmas
+END:VEVENT
+BEGIN:VEVENT
+DTSTART:20241226
+SUMMARY:Boxing Day
+END:VEVENT`,
+			want: []models.LotExceptionDate{
+				{Date: "2024-12-25", Closed: true, Reason: "Christmas"},
+				{Date: "2024-12-26", Closed: true, Reason: "Christmas"},
+			},
+		},
+		{
+			name: "dates outside events are ignored",
+			ics: `BEGIN:VCALENDAR
+DTSTART:20240101
+END:VCALENDAR`,
+			want: nil,
+		},
+		{
+			name:    "event without start",
+			ics:     "BEGIN:VEVENT\nSUMMARY:Someday\nEND:VEVENT",
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			ics:     "BEGIN:VEVENT\nDTSTART:2024-12-25\nEND:VEVENT",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHolidays(strings.NewReader(tt.ics))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHolidays() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHolidays() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHolidaysLongEvent(t *testing.T) {
+	ics := "BEGIN:VEVENT\nDTSTART:20240101\nDTEND:20241231\nSUMMARY:Construction\nEND:VEVENT"
+	holidays, err := ParseHolidays(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holidays) != maxEventDays {
+		t.Errorf("event over a year expanded to %d dates, want %d", len(holidays), maxEventDays)
+	}
+}
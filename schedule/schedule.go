@@ -0,0 +1,196 @@
+package schedule
+
+import (
+	"fmt"
+	"parkingManagementSystem/models"
+	"sort"
+	"time"
+)
+
+// DateLayout is the layout of exception dates.
+const DateLayout = "2006-01-02"
+
+// lookahead bounds how far ahead NextChange looks for the next opening or closing.
+const lookahead = 366
+
+// Schedule tells when a parking lot is open from its weekly opening hours and
+// exception dates. A day without an exception and without weekly hours is
+// open all day, so a lot without any hours is always open.
+type Schedule struct {
+	location   *time.Location
+	weekly     map[time.Weekday][]models.OpeningHours
+	exceptions map[string]models.LotExceptionDate
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+// New builds the schedule of a lot whose hours are given in timezone.
+func New(timezone string, hours []models.OpeningHours, exceptions []models.LotExceptionDate) (*Schedule, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schedule{
+		location:   location,
+		weekly:     make(map[time.Weekday][]models.OpeningHours),
+		exceptions: make(map[string]models.LotExceptionDate, len(exceptions)),
+	}
+	for _, h := range hours {
+		s.weekly[time.Weekday(h.Weekday)] = append(s.weekly[time.Weekday(h.Weekday)], h)
+	}
+	for _, exception := range exceptions {
+		s.exceptions[exception.Date] = exception
+	}
+	return s, nil
+}
+
+// ParseClock parses an HH:MM time of day, 24:00 included, as the offset from midnight.
+func ParseClock(clock string) (time.Duration, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hours, &minutes); err != nil || len(clock) != 5 {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", clock)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", clock)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// IsOpen reports whether the lot is open at t.
+func (s *Schedule) IsOpen(t time.Time) bool {
+	_, ok := s.containing(t)
+	return ok
+}
+
+// NextChange returns when the lot next closes if it is open at t, or next
+// opens if it is closed. It reports false when that does not happen within a year.
+func (s *Schedule) NextChange(t time.Time) (time.Time, bool) {
+	if current, ok := s.containing(t); ok {
+		if current.end.IsZero() {
+			return time.Time{}, false
+		}
+		return current.end, true
+	}
+	for _, i := range s.intervals(t, t.AddDate(0, 0, lookahead)) {
+		if i.start.After(t) {
+			return i.start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ClosedDuring reports whether the lot was closed at any time between from and to.
+func (s *Schedule) ClosedDuring(from, to time.Time) bool {
+	for _, i := range s.intervals(from, to) {
+		if !i.start.After(from) && !i.end.Before(to) {
+			return false
+		}
+	}
+	return true
+}
+
+// containing returns the merged open interval containing t. Its end is zero
+// when the lot stays open for the whole lookahead.
+func (s *Schedule) containing(t time.Time) (interval, bool) {
+	for _, i := range s.intervals(t, t.AddDate(0, 0, lookahead)) {
+		if !i.start.After(t) && i.end.After(t) {
+			if !i.end.Before(t.AddDate(0, 0, lookahead)) {
+				i.end = time.Time{}
+			}
+			return i, true
+		}
+	}
+	return interval{}, false
+}
+
+// intervals returns the merged open intervals of the days from the day
+// before from, for periods running past midnight, until the day of to.
+func (s *Schedule) intervals(from, to time.Time) []interval {
+	first := midnight(from.In(s.location)).AddDate(0, 0, -1)
+	last := midnight(to.In(s.location))
+
+	var open []interval
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		open = append(open, s.dayIntervals(day)...)
+	}
+	sort.Slice(open, func(a, b int) bool { return open[a].start.Before(open[b].start) })
+
+	var merged []interval
+	for _, i := range open {
+		if n := len(merged); n > 0 && !i.start.After(merged[n-1].end) {
+			if i.end.After(merged[n-1].end) {
+				merged[n-1].end = i.end
+			}
+			continue
+		}
+		merged = append(merged, i)
+	}
+	return merged
+}
+
+// dayIntervals returns the opening periods starting on the day beginning at midnight.
+func (s *Schedule) dayIntervals(day time.Time) []interval {
+	if exception, ok := s.exceptions[day.Format(DateLayout)]; ok {
+		if exception.Closed {
+			return nil
+		}
+		if i, ok := period(day, exception.Opens, exception.Closes); ok {
+			return []interval{i}
+		}
+		return nil
+	}
+
+	hours, ok := s.weekly[day.Weekday()]
+	if !ok {
+		if len(s.weekly) == 0 {
+			return []interval{{start: day, end: day.AddDate(0, 0, 1)}}
+		}
+		return nil
+	}
+
+	var periods []interval
+	for _, h := range hours {
+		if i, ok := period(day, h.Opens, h.Closes); ok {
+			periods = append(periods, i)
+		}
+	}
+	return periods
+}
+
+// period turns opening and closing times of day into an interval on the
+// day, running into the next day when the lot closes at or before it opens.
+func period(day time.Time, opens, closes string) (interval, bool) {
+	openOffset, err := ParseClock(opens)
+	if err != nil {
+		return interval{}, false
+	}
+	closeOffset, err := ParseClock(closes)
+	if err != nil {
+		return interval{}, false
+	}
+
+	start := atOffset(day, openOffset)
+	end := atOffset(day, closeOffset)
+	if !end.After(start) {
+		end = atOffset(day.AddDate(0, 0, 1), closeOffset)
+	}
+	return interval{start: start, end: end}, true
+}
+
+// atOffset returns the wall clock time offset from midnight of day, so days
+// with a daylight saving change keep their opening times.
+func atOffset(day time.Time, offset time.Duration) time.Time {
+	hours := int(offset / time.Hour)
+	minutes := int(offset % time.Hour / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location())
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,205 @@
+package schedule
+
+import (
+	"parkingManagementSystem/models"
+	"testing"
+	"time"
+)
+
+// weekdayHours opens Monday to Friday 08:00-18:00 and Saturday night
+// 22:00-02:00. Sunday has no hours, so it is closed.
+func weekdayHours(t *testing.T) *Schedule {
+	t.Helper()
+	var hours []models.OpeningHours
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		hours = append(hours, models.OpeningHours{Weekday: int(weekday), Opens: "08:00", Closes: "18:00"})
+	}
+	hours = append(hours, models.OpeningHours{Weekday: int(time.Saturday), Opens: "22:00", Closes: "02:00"})
+	exceptions := []models.LotExceptionDate{
+		{Date: "2024-03-05", Closed: true, Reason: "Inventory"},
+		{Date: "2024-03-06", Opens: "10:00", Closes: "14:00"},
+	}
+	s, err := New("UTC", hours, exceptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// everyDay opens every day between opens and closes in timezone.
+func everyDay(t *testing.T, timezone, opens, closes string) *Schedule {
+	t.Helper()
+	var hours []models.OpeningHours
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		hours = append(hours, models.OpeningHours{Weekday: int(weekday), Opens: opens, Closes: closes})
+	}
+	s, err := New(timezone, hours, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func utc(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestNewUnknownTimezone(t *testing.T) {
+	if _, err := New("Mars/Olympus_Mons", nil, nil); err == nil {
+		t.Error("New with an unknown time zone succeeded")
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		clock   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{clock: "00:00", want: 0},
+		{clock: "08:30", want: 8*time.Hour + 30*time.Minute},
+		{clock: "24:00", want: 24 * time.Hour},
+		{clock: "24:01", wantErr: true},
+		{clock: "12:60", wantErr: true},
+		{clock: "8:30", wantErr: true},
+		{clock: "noon", wantErr: true},
+		{clock: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseClock(tt.clock)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseClock(%q) = %s, %v, want %s, error %v", tt.clock, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	s := weekdayHours(t)
+	// 2024-03-04 is a Monday
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "before opening", at: utc(3, 4, 7, 59), want: false},
+		{name: "at opening", at: utc(3, 4, 8, 0), want: true},
+		{name: "before closing", at: utc(3, 4, 17, 59), want: true},
+		{name: "at closing", at: utc(3, 4, 18, 0), want: false},
+		{name: "closed exception date", at: utc(3, 5, 12, 0), want: false},
+		{name: "before special hours", at: utc(3, 6, 9, 0), want: false},
+		{name: "within special hours", at: utc(3, 6, 12, 0), want: true},
+		{name: "after special hours", at: utc(3, 6, 14, 0), want: false},
+		{name: "overnight before midnight", at: utc(3, 9, 23, 0), want: true},
+		{name: "overnight after midnight", at: utc(3, 10, 1, 0), want: true},
+		{name: "after overnight closing", at: utc(3, 10, 3, 0), want: false},
+		{name: "day without hours", at: utc(3, 10, 12, 0), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.IsOpen(tt.at); got != tt.want {
+				t.Errorf("IsOpen(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextChange(t *testing.T) {
+	alwaysOpen, err := New("UTC", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		at       time.Time
+		want     time.Time
+		wantOK   bool
+	}{
+		{name: "open, closes the same day", schedule: weekdayHours(t), at: utc(3, 4, 12, 0), want: utc(3, 4, 18, 0), wantOK: true},
+		{name: "closed, skips a closed exception date", schedule: weekdayHours(t), at: utc(3, 4, 19, 0), want: utc(3, 6, 10, 0), wantOK: true},
+		{name: "open overnight, closes after midnight", schedule: weekdayHours(t), at: utc(3, 9, 23, 0), want: utc(3, 10, 2, 0), wantOK: true},
+		{name: "closed sunday, opens monday", schedule: weekdayHours(t), at: utc(3, 10, 3, 0), want: utc(3, 11, 8, 0), wantOK: true},
+		{name: "around the clock hours merge", schedule: everyDay(t, "UTC", "00:00", "24:00"), at: utc(3, 4, 12, 0), wantOK: false},
+		{name: "no hours at all", schedule: alwaysOpen, at: utc(3, 4, 12, 0), wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.schedule.NextChange(tt.at)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("NextChange(%s) = %s, %v, want %s, %v", tt.at, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestClosedDuring(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		from, to time.Time
+		want     bool
+	}{
+		{name: "within opening hours", schedule: weekdayHours(t), from: utc(3, 4, 9, 0), to: utc(3, 4, 17, 0), want: false},
+		{name: "exactly the opening hours", schedule: weekdayHours(t), from: utc(3, 4, 8, 0), to: utc(3, 4, 18, 0), want: false},
+		{name: "over night", schedule: weekdayHours(t), from: utc(3, 4, 17, 0), to: utc(3, 5, 9, 0), want: true},
+		{name: "half an hour before closing", schedule: weekdayHours(t), from: utc(3, 4, 17, 0), to: utc(3, 4, 17, 30), want: false},
+		{name: "overnight period across midnight", schedule: weekdayHours(t), from: utc(3, 9, 23, 0), to: utc(3, 10, 1, 30), want: false},
+		{name: "beyond overnight period", schedule: weekdayHours(t), from: utc(3, 9, 23, 0), to: utc(3, 10, 2, 30), want: true},
+		{name: "adjacent days merge", schedule: everyDay(t, "UTC", "00:00", "24:00"), from: utc(3, 4, 20, 0), to: utc(3, 5, 4, 0), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.ClosedDuring(tt.from, tt.to); got != tt.want {
+				t.Errorf("ClosedDuring(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDaylightSavingTime checks that opening hours stay on the wall clock
+// across the changes of 2024 in Berlin: 31 March, when 02:00 CET became
+// 03:00 CEST, and 27 October, when 03:00 CEST became 02:00 CET.
+func TestDaylightSavingTime(t *testing.T) {
+	daytime := everyDay(t, "Europe/Berlin", "08:00", "20:00")
+	aroundTheClock := everyDay(t, "Europe/Berlin", "00:00", "24:00")
+
+	isOpen := []struct {
+		name     string
+		schedule *Schedule
+		at       time.Time
+		want     bool
+	}{
+		{name: "winter time, 08:00 CET", schedule: daytime, at: utc(3, 30, 7, 0), want: true},
+		{name: "winter time, 07:30 CET", schedule: daytime, at: utc(3, 30, 6, 30), want: false},
+		{name: "change day, 08:00 CEST", schedule: daytime, at: utc(3, 31, 6, 0), want: true},
+		{name: "change day, 07:59 CEST", schedule: daytime, at: utc(3, 31, 5, 59), want: false},
+		{name: "change day, 19:59 CEST", schedule: daytime, at: utc(3, 31, 17, 59), want: true},
+		{name: "change day, 20:00 CEST", schedule: daytime, at: utc(3, 31, 18, 0), want: false},
+		{name: "back to winter time, 07:30 CET", schedule: daytime, at: utc(10, 27, 6, 30), want: false},
+		{name: "back to winter time, 08:00 CET", schedule: daytime, at: utc(10, 27, 7, 0), want: true},
+		{name: "around the clock during the skipped hour", schedule: aroundTheClock, at: utc(3, 31, 1, 30), want: true},
+		{name: "around the clock during the repeated hour", schedule: aroundTheClock, at: utc(10, 27, 0, 30), want: true},
+	}
+	for _, tt := range isOpen {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.IsOpen(tt.at); got != tt.want {
+				t.Errorf("IsOpen(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+
+	// Closed at 21:00 CET the evening before, opens at 08:00 CEST
+	if got, ok := daytime.NextChange(utc(3, 30, 20, 0)); !ok || !got.Equal(utc(3, 31, 6, 0)) {
+		t.Errorf("NextChange before spring forward = %s, %v, want %s", got, ok, utc(3, 31, 6, 0))
+	}
+	// Open at 12:00 CEST, closes at 20:00 CET after falling back
+	if got, ok := daytime.NextChange(utc(10, 27, 11, 0)); !ok || !got.Equal(utc(10, 27, 19, 0)) {
+		t.Errorf("NextChange on fall back = %s, %v, want %s", got, ok, utc(10, 27, 19, 0))
+	}
+	if aroundTheClock.ClosedDuring(utc(3, 30, 22, 0), utc(4, 1, 2, 0)) {
+		t.Error("around the clock lot closed across spring forward")
+	}
+	if aroundTheClock.ClosedDuring(utc(10, 26, 22, 0), utc(10, 28, 2, 0)) {
+		t.Error("around the clock lot closed across fall back")
+	}
+}
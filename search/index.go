@@ -0,0 +1,168 @@
+package search
+
+import (
+	"math"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"parkingManagementSystem/schedule"
+	"sort"
+	"sync"
+	"time"
+)
+
+// earthRadiusMeters is the mean radius of the Earth used by Distance.
+const earthRadiusMeters = 6371008.8
+
+// Result is a parking lot found near a position.
+type Result struct {
+	ParkingLotID   uint           `json:"parking_lot_id"`
+	Name           string         `json:"name,omitempty"`
+	Operator       string         `json:"operator,omitempty"`
+	Location       string         `json:"location"`
+	Address        models.Address `json:"address"`
+	Latitude       float64        `json:"latitude"`
+	Longitude      float64        `json:"longitude"`
+	DistanceMeters float64        `json:"distance_meters"`
+	FreeSlots      map[string]int `json:"free_slots"` // By slot type
+	TotalFreeSlots int            `json:"total_free_slots"`
+	HourlyRate     int            `json:"hourly_rate"`
+	IsOpen         bool           `json:"is_open"`
+}
+
+// entry is a cached parking lot with what is needed to answer a search.
+type entry struct {
+	lot       models.ParkingLot
+	schedule  *schedule.Schedule
+	freeSlots map[string]int
+}
+
+// Index answers nearby-availability searches from a snapshot of the
+// geolocated lots and their free slots, reloaded once it is older than ttl.
+// Distances are computed in Go, so the database needs no spatial extension.
+type Index struct {
+	repo *repository.Repository
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	entries   []entry
+	loadedAt  time.Time
+	reloading bool // A search is loading a new snapshot
+}
+
+func NewIndex(repo *repository.Repository, ttl time.Duration) *Index {
+	return &Index{repo: repo, ttl: ttl}
+}
+
+// Nearby returns the lots within radius meters of the position, nearest
+// first. With a slot type set, only lots with a free slot of that type are
+// returned. A positive limit caps the number of results.
+func (i *Index) Nearby(latitude, longitude, radius float64, slotType string, limit int) ([]Result, error) {
+	entries, err := i.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	results := make([]Result, 0)
+	for _, e := range entries {
+		distance := Distance(latitude, longitude, *e.lot.Latitude, *e.lot.Longitude)
+		if distance > radius {
+			continue
+		}
+		if slotType != "" && e.freeSlots[slotType] == 0 {
+			continue
+		}
+
+		total := 0
+		for _, count := range e.freeSlots {
+			total += count
+		}
+		results = append(results, Result{
+			ParkingLotID:   e.lot.ID,
+			Name:           e.lot.Name,
+			Operator:       e.lot.Operator,
+			Location:       e.lot.Location,
+			Address:        e.lot.Address,
+			Latitude:       *e.lot.Latitude,
+			Longitude:      *e.lot.Longitude,
+			DistanceMeters: math.Round(distance),
+			FreeSlots:      e.freeSlots,
+			TotalFreeSlots: total,
+			HourlyRate:     repository.HourlyParkingRate,
+			IsOpen:         e.schedule.IsOpen(now),
+		})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].DistanceMeters < results[b].DistanceMeters
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// snapshot returns the cached lots, reloading them when they are stale. The
+// reload runs outside the lock, and searches meanwhile get the stale lots
+// rather than waiting or reloading as well.
+func (i *Index) snapshot() ([]entry, error) {
+	i.mu.Lock()
+	entries := i.entries
+	if entries != nil && (time.Since(i.loadedAt) < i.ttl || i.reloading) {
+		i.mu.Unlock()
+		return entries, nil
+	}
+	i.reloading = true
+	i.mu.Unlock()
+
+	loaded, err := i.load()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.reloading = false
+	if err != nil {
+		return nil, err
+	}
+	i.entries = loaded
+	i.loadedAt = time.Now()
+	return loaded, nil
+}
+
+// load reads the geolocated lots with their schedules and free slots.
+func (i *Index) load() ([]entry, error) {
+	parkingLots, err := i.repo.GetGeolocatedLots()
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := i.repo.GetLotSchedules(parkingLots)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := i.repo.GetFreeSlotCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(parkingLots))
+	for _, parkingLot := range parkingLots {
+		freeSlots := counts[parkingLot.ID]
+		if freeSlots == nil {
+			freeSlots = map[string]int{}
+		}
+		entries = append(entries, entry{lot: parkingLot, schedule: schedules[parkingLot.ID], freeSlots: freeSlots})
+	}
+	return entries, nil
+}
+
+// Distance returns the great-circle distance in meters between two
+// positions given in degrees, using the haversine formula.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	oneDegree := earthRadiusMeters * math.Pi / 180
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want, tolerance        float64
+	}{
+		{name: "same position", lat1: 48.8566, lon1: 2.3522, lat2: 48.8566, lon2: 2.3522, want: 0, tolerance: 0},
+		{name: "one degree along the equator", lat1: 0, lon1: 0, lat2: 0, lon2: 1, want: oneDegree, tolerance: 0.001},
+		{name: "one degree along a meridian", lat1: 45, lon1: 7, lat2: 46, lon2: 7, want: oneDegree, tolerance: 0.001},
+		{name: "across the antimeridian", lat1: 0, lon1: 179.5, lat2: 0, lon2: -179.5, want: oneDegree, tolerance: 0.001},
+		{name: "equator to pole", lat1: 0, lon1: 30, lat2: 90, lon2: 0, want: 90 * oneDegree, tolerance: 0.001},
+		{name: "antipodes", lat1: 10, lon1: 20, lat2: -10, lon2: -160, want: 180 * oneDegree, tolerance: 0.001},
+		{name: "Paris to London", lat1: 48.8566, lon1: 2.3522, lat2: 51.5074, lon2: -0.1278, want: 343_557, tolerance: 1},
+		{name: "Berlin to Munich", lat1: 52.5200, lon1: 13.4050, lat2: 48.1351, lon2: 11.5820, want: 504_416, tolerance: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("Distance(%g, %g, %g, %g) = %f, want %f", tt.lat1, tt.lon1, tt.lat2, tt.lon2, got, tt.want)
+			}
+			if back := Distance(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-6 {
+				t.Errorf("Distance is not symmetric: %f there, %f back", got, back)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package sensors
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"time"
+)
+
+// Reconciler periodically compares the ground sensors with the booking state
+// of their slots and raises an alert for every new discrepancy.
+type Reconciler struct {
+	repo        *repository.Repository
+	interval    time.Duration
+	vacantAfter time.Duration
+}
+
+func NewReconciler(repo *repository.Repository, interval, vacantAfter time.Duration) *Reconciler {
+	return &Reconciler{repo: repo, interval: interval, vacantAfter: vacantAfter}
+}
+
+// Run reconciles every interval until the context is cancelled.
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Reconcile(); err != nil {
+				log.Error().Err(err).Msg("sensor reconciliation failed")
+			}
+		}
+	}
+}
+
+// Reconcile runs a single reconciliation pass and returns the discrepancies it opened.
+func (r *Reconciler) Reconcile() ([]models.SensorDiscrepancy, error) {
+	discrepancies, err := r.repo.ReconcileSensors(r.vacantAfter, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, discrepancy := range discrepancies {
+		log.Warn().
+			Uint("parking_lot_id", discrepancy.ParkingLotID).
+			Uint("relative_id", discrepancy.RelativeID).
+			Str("kind", discrepancy.Kind).
+			Msg("sensor discrepancy detected")
+	}
+	return discrepancies, nil
+}
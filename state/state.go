@@ -2,27 +2,139 @@ package state
 
 import (
 	"github.com/rs/zerolog/log"
+	"parkingManagementSystem/alerts"
+	"parkingManagementSystem/anpr"
 	"parkingManagementSystem/config"
+	"parkingManagementSystem/edgesync"
+	"parkingManagementSystem/enforcement"
+	"parkingManagementSystem/events"
+	"parkingManagementSystem/gates"
+	"parkingManagementSystem/maintenance"
+	"parkingManagementSystem/overflow"
 	"parkingManagementSystem/repository"
+	"parkingManagementSystem/search"
+	"parkingManagementSystem/sensors"
+	"parkingManagementSystem/ticketing"
+	"parkingManagementSystem/webhooks"
+	"time"
 )
 
 type State struct {
-	Cfg        *config.Config
-	Repository *repository.PgRepository
+	Cfg         *config.Config
+	Repository  *repository.Repository
+	Events      *events.Bus
+	Tickets     *ticketing.Signer
+	Gates       *gates.Manager
+	Anpr        *anpr.Processor
+	Sensors     *sensors.Reconciler
+	Webhooks    *webhooks.Dispatcher
+	Alerts      *alerts.Monitor
+	Overstays   *enforcement.Scanner
+	Maintenance *maintenance.Scheduler
+	Search      *search.Index
+	Overflow    *overflow.Redirector
+	Sync        *edgesync.Syncer // Nil unless the service runs at an edge site
 }
 
 func NewState(cfg *config.Config) *State {
-	db, err := repository.NewPgRepository(cfg.DatabaseUrl)
-	if err != nil {
-		log.Fatal().Err(err).Msg("pg repository error")
+	db := connectRepository(cfg.DatabaseUrl, time.Duration(cfg.DatabaseConnectSeconds)*time.Second)
+	db.SensorAwareAllocation = cfg.SensorAwareAllocation
+	db.Events = events.NewBus()
+	db.MaintenanceLeadTime = time.Duration(cfg.MaintenanceLeadMinutes) * time.Minute
+	if cfg.AutoMigrate {
+		err := db.Migrate()
+		if err != nil {
+			log.Fatal().Err(err).Msg("repository error")
+		}
 	}
-	err = db.Migrate()
-	if err != nil {
-		log.Fatal().Err(err).Msg("pg repository error")
+
+	if ticketing.IsPlaceholderKey(cfg.TicketSigningKey) {
+		log.Fatal().Msg("TICKET_SIGNING_KEY is a published example key, set a random one")
+	}
+	tickets := ticketing.NewSigner(cfg.TicketSigningKey)
+	if cfg.TicketSigningKey == "" {
+		log.Warn().Msg("TICKET_SIGNING_KEY is not set, ticket tokens will not survive a restart")
+		var err error
+		tickets, err = ticketing.NewRandomSigner()
+		if err != nil {
+			log.Fatal().Err(err).Msg("ticket signer error")
+		}
+	}
+
+	gateTimeout := time.Duration(cfg.GateTimeoutSeconds) * time.Second
+	var gateController gates.GateController
+	if cfg.GateControllerAddr != "" {
+		gateController = gates.NewTCPController(cfg.GateControllerAddr, gateTimeout)
+	}
+
+	gateManager := gates.NewManager(gateController, db, gateTimeout)
+
+	anprDedupWindow := time.Duration(cfg.AnprDedupSeconds) * time.Second
+	sensorInterval := time.Duration(cfg.SensorReconcileSeconds) * time.Second
+	sensorVacantAfter := time.Duration(cfg.SensorVacantMinutes) * time.Minute
+	webhookInterval := time.Duration(cfg.WebhookDispatchSeconds) * time.Second
+	webhookTimeout := time.Duration(cfg.WebhookTimeoutSeconds) * time.Second
+	overstayInterval := time.Duration(cfg.OverstayScanSeconds) * time.Second
+	maintenanceInterval := time.Duration(cfg.MaintenanceScheduleSeconds) * time.Second
+	searchCacheTTL := time.Duration(cfg.SearchCacheSeconds) * time.Second
+	overflowHold := time.Duration(cfg.OverflowHoldMinutes) * time.Minute
+	searchIndex := search.NewIndex(db, searchCacheTTL)
+
+	notifiers := []alerts.Notifier{alerts.LogNotifier{}}
+	if cfg.AlertSmtpAddr != "" {
+		notifiers = append(notifiers, alerts.NewSMTPNotifier(cfg.AlertSmtpAddr, cfg.AlertSmtpFrom, cfg.AlertSmtpTo))
+	}
+
+	var syncer *edgesync.Syncer
+	if cfg.CentralUrl != "" {
+		if cfg.EdgeSiteID == "" || cfg.SyncSecret == "" {
+			log.Fatal().Msg("EDGE_SITE_ID and SYNC_SECRET are required with CENTRAL_URL")
+		}
+		syncInterval := time.Duration(cfg.SyncIntervalSeconds) * time.Second
+		syncTimeout := time.Duration(cfg.SyncTimeoutSeconds) * time.Second
+		syncer = edgesync.NewSyncer(db, cfg.CentralUrl, cfg.EdgeSiteID, cfg.SyncSecret, syncInterval, syncTimeout)
 	}
 
 	return &State{
-		Cfg:        cfg,
-		Repository: db,
+		Cfg:         cfg,
+		Repository:  db,
+		Events:      db.Events,
+		Tickets:     tickets,
+		Gates:       gateManager,
+		Anpr:        anpr.NewProcessor(db, gateManager, cfg.AnprMinConfidence, anprDedupWindow),
+		Sensors:     sensors.NewReconciler(db, sensorInterval, sensorVacantAfter),
+		Webhooks:    webhooks.NewDispatcher(db, webhookInterval, webhookTimeout),
+		Alerts:      alerts.NewMonitor(db, db.Events, notifiers...),
+		Overstays:   enforcement.NewScanner(db, overstayInterval),
+		Maintenance: maintenance.NewScheduler(db, maintenanceInterval),
+		Search:      searchIndex,
+		Overflow:    overflow.NewRedirector(db, searchIndex, cfg.OverflowRadiusMeters, overflowHold),
+		Sync:        syncer,
+	}
+}
+
+// maxConnectBackoff caps the wait between attempts to reach the database.
+const maxConnectBackoff = 10 * time.Second
+
+// connectRepository connects to the database, retrying with exponential
+// backoff while it cannot be reached, for example while it is still starting
+// next to the service. It gives up once the timeout is over.
+func connectRepository(databaseUrl string, timeout time.Duration) *repository.Repository {
+	deadline := time.Now().Add(timeout)
+	delay := 500 * time.Millisecond
+	for {
+		db, err := repository.NewRepository(databaseUrl)
+		if err == nil {
+			return db
+		}
+		if time.Now().Add(delay).After(deadline) {
+			log.Fatal().Err(err).Msg("repository error")
+		}
+		log.Warn().Err(err).Dur("retry_in", delay).Msg("Database not reachable, retrying")
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxConnectBackoff {
+			delay = maxConnectBackoff
+		}
 	}
 }
@@ -0,0 +1,106 @@
+package ticketing
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"image"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+const (
+	qrModuleSize      = 8   // Pixels per QR module
+	qrQuietZone       = 4   // Modules of white border required around a QR code
+	code128BarWidth   = 2   // Pixels per Code128 module
+	code128Height     = 100 // Bar height in pixels
+	code128QuietZone  = 10  // Modules of white border left and right of the bars
+	svgContentType    = "image/svg+xml"
+	pngContentType    = "image/png"
+	unsupportedFormat = "unsupported ticket image format %q"
+)
+
+// QRCode renders the token as a QR code image in the given format and
+// returns the image together with its content type.
+func QRCode(token, format string) ([]byte, string, error) {
+	code, err := qr.Encode(token, qr.M, qr.Auto)
+	if err != nil {
+		return nil, "", err
+	}
+	return render(code, format, qrModuleSize, qrModuleSize, qrQuietZone, qrQuietZone)
+}
+
+// Barcode renders the token as a Code128 barcode image in the given format
+// and returns the image together with its content type.
+func Barcode(token, format string) ([]byte, string, error) {
+	code, err := code128.Encode(token)
+	if err != nil {
+		return nil, "", err
+	}
+	moduleHeight := code128Height / code.Bounds().Dy()
+	return render(code, format, code128BarWidth, moduleHeight, code128QuietZone, 0)
+}
+
+func render(code barcode.Barcode, format string, moduleWidth, moduleHeight, quietX, quietY int) ([]byte, string, error) {
+	switch format {
+	case FormatPNG, "":
+		bounds := code.Bounds()
+		scaled, err := barcode.Scale(code, bounds.Dx()*moduleWidth, bounds.Dy()*moduleHeight)
+		if err != nil {
+			return nil, "", err
+		}
+		canvas := image.NewGray(image.Rect(0, 0, scaled.Bounds().Dx()+2*quietX*moduleWidth, scaled.Bounds().Dy()+2*quietY*moduleHeight))
+		draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(canvas, scaled.Bounds().Add(image.Pt(quietX*moduleWidth, quietY*moduleHeight)), scaled, scaled.Bounds().Min, draw.Src)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, canvas); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), pngContentType, nil
+	case FormatSVG:
+		return renderSVG(code, moduleWidth, moduleHeight, quietX, quietY), svgContentType, nil
+	default:
+		return nil, "", fmt.Errorf(unsupportedFormat, format)
+	}
+}
+
+// renderSVG draws every dark module of the code as a rectangle, merging
+// horizontal runs to keep the document small.
+func renderSVG(code barcode.Barcode, moduleWidth, moduleHeight, quietX, quietY int) []byte {
+	bounds := code.Bounds()
+	width := (bounds.Dx() + 2*quietX) * moduleWidth
+	height := (bounds.Dy() + 2*quietY) * moduleHeight
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, width, height, width, height)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, width, height)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; {
+			if !isDark(code, x, y) {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < bounds.Max.X && isDark(code, x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d"/>`,
+				(x-bounds.Min.X+quietX)*moduleWidth, (y-bounds.Min.Y+quietY)*moduleHeight, run*moduleWidth, moduleHeight)
+			x += run
+		}
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+func isDark(code barcode.Barcode, x, y int) bool {
+	r, g, b, _ := code.At(x, y).RGBA()
+	return r+g+b < 3*0x8000
+}
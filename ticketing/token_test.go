@@ -0,0 +1,80 @@
+package ticketing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner("test-key")
+	token := signer.Sign("A1B2C3")
+	ticketCode, signature, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name     string
+		signer   *Signer
+		token    string
+		wantCode string
+		wantErr  bool
+	}{
+		{name: "signed token", signer: signer, token: token, wantCode: "A1B2C3"},
+		{name: "surrounding whitespace", signer: signer, token: " " + token + "\n", wantCode: "A1B2C3"},
+		{name: "other key", signer: NewSigner("other-key"), token: token, wantErr: true},
+		{name: "changed code", signer: signer, token: "A1B2C4." + signature, wantErr: true},
+		{name: "truncated signature", signer: signer, token: ticketCode + "." + signature[:len(signature)-2], wantErr: true},
+		{name: "signature not base64", signer: signer, token: ticketCode + ".!!!", wantErr: true},
+		{name: "no signature", signer: signer, token: ticketCode, wantErr: true},
+		{name: "no code", signer: signer, token: "." + signature, wantErr: true},
+		{name: "empty", signer: signer, token: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := tt.signer.Verify(tt.token)
+			if tt.wantErr {
+				if err != ErrInvalidTicketToken {
+					t.Fatalf("Verify(%q) error = %v, want ErrInvalidTicketToken", tt.token, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify(%q) error = %v", tt.token, err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("Verify(%q) = %q, want %q", tt.token, code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRandomSignersDiffer(t *testing.T) {
+	first, err := NewRandomSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRandomSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Verify(first.Sign("A1B2C3")); err == nil {
+		t.Error("a token of one random signer verified with another")
+	}
+}
+
+func TestIsPlaceholderKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"change-me", true},
+		{" Change-Me ", true},
+		{"changeme", true},
+		{"secret", true},
+		{"", false},
+		{"5f0c1e8a9b7d4c3e2f1a0b9c8d7e6f5a", false},
+	}
+	for _, tt := range tests {
+		if got := IsPlaceholderKey(tt.key); got != tt.want {
+			t.Errorf("IsPlaceholderKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
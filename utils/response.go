@@ -15,8 +15,13 @@ type CommonResponse struct {
 
 func RespondWithError(w http.ResponseWriter, message string, statusCode int, logger zerolog.Logger) {
 	//logger.Error().Str("code", "error").Str("message", message).Msg("Responding with error")
+	RespondWithErrorCode(w, "error", message, statusCode, logger)
+}
+
+// RespondWithErrorCode responds with an error that clients can tell apart by its code.
+func RespondWithErrorCode(w http.ResponseWriter, code string, message string, statusCode int, logger zerolog.Logger) {
 	RespondWithJSON(w, statusCode, CommonResponse{
-		Code:    "error",
+		Code:    code,
 		Message: message,
 	}, logger)
 }
@@ -0,0 +1,203 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"parkingManagementSystem/models"
+	"parkingManagementSystem/repository"
+	"strconv"
+	"time"
+)
+
+const (
+	batchSize = 100
+	// MaxAttempts is how often a delivery is tried before it is marked failed.
+	MaxAttempts = 10
+	// initialBackoff doubles after every failed attempt, up to maxBackoff.
+	initialBackoff = 10 * time.Second
+	maxBackoff     = time.Hour
+	// deliveryLease keeps other replicas away from a delivery being sent.
+	deliveryLease = time.Minute
+	// leaseSlack is the part of the lease kept for claiming and saving.
+	leaseSlack = 10 * time.Second
+)
+
+// Dispatcher moves lifecycle events from the outbox to the subscribed
+// endpoints. Every request body is signed with the subscription secret:
+//
+//	X-PMS-Signature: sha256=hex(HMAC-SHA256(secret, X-PMS-Timestamp + "." + body))
+//
+// Failed deliveries are retried with exponential backoff.
+type Dispatcher struct {
+	repo       *repository.Repository
+	client     *http.Client
+	interval   time.Duration
+	claimLimit int           // Deliveries claimed at once, sized to be sent within lease
+	lease      time.Duration // How long claimed deliveries are kept from other replicas
+}
+
+func NewDispatcher(repo *repository.Repository, interval, timeout time.Duration) *Dispatcher {
+	claimLimit, lease := claimSize(timeout)
+	return &Dispatcher{
+		repo:       repo,
+		client:     &http.Client{Timeout: timeout},
+		interval:   interval,
+		claimLimit: claimLimit,
+		lease:      lease,
+	}
+}
+
+// claimSize returns how many deliveries to claim at once, and for how long,
+// so that sending them one after another, each taking up to timeout, ends
+// before the lease runs out and another replica sends them again.
+func claimSize(timeout time.Duration) (int, time.Duration) {
+	limit := int((deliveryLease - leaseSlack) / timeout)
+	if limit < 1 {
+		return 1, timeout + leaseSlack
+	}
+	if limit > batchSize {
+		limit = batchSize
+	}
+	return limit, deliveryLease
+}
+
+// envelope is the body posted to subscribers.
+type envelope struct {
+	ID        uint            `json:"id"` // Outbox event ID, the same across redeliveries
+	EventType string          `json:"event_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Run dispatches every interval until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Dispatch(ctx)
+		}
+	}
+}
+
+// Dispatch fans out pending outbox events and sends the deliveries that are due.
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	for {
+		handled, err := d.repo.FanOutOutboxEvents(batchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("webhook fan-out failed")
+			break
+		}
+		if handled < batchSize {
+			break
+		}
+	}
+
+	// Small batches are claimed one after another, so none outlives its lease
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.ClaimDueWebhookDeliveries(d.claimLimit, d.lease)
+		if err != nil {
+			log.Error().Err(err).Msg("claiming webhook deliveries failed")
+			return
+		}
+		for i := range deliveries {
+			d.deliver(ctx, &deliveries[i])
+		}
+		if len(deliveries) < d.claimLimit {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(backoff(delivery.Attempts))
+	}
+	if err != nil {
+		log.Warn().Err(err).Uint("delivery_id", delivery.ID).Int("attempts", delivery.Attempts).Msg("webhook delivery failed")
+	}
+
+	if err := d.repo.SaveWebhookDelivery(delivery); err != nil {
+		log.Error().Err(err).Uint("delivery_id", delivery.ID).Msg("saving webhook delivery failed")
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(envelope{
+		ID:        delivery.OutboxEvent.ID,
+		EventType: delivery.OutboxEvent.EventType,
+		CreatedAt: delivery.OutboxEvent.CreatedAt,
+		Data:      json.RawMessage(delivery.OutboxEvent.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PMS-Event", delivery.EventType)
+	req.Header.Set("X-PMS-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-PMS-Timestamp", timestamp)
+	req.Header.Set("X-PMS-Signature", "sha256="+Sign(delivery.Subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 subscribers use to verify a delivery.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func backoff(attempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
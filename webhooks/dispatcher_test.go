@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		wantLimit int
+		wantLease time.Duration
+	}{
+		{name: "default timeout", timeout: 10 * time.Second, wantLimit: 5, wantLease: deliveryLease},
+		{name: "short timeout capped at batch size", timeout: 100 * time.Millisecond, wantLimit: batchSize, wantLease: deliveryLease},
+		{name: "timeout filling the lease", timeout: deliveryLease - leaseSlack, wantLimit: 1, wantLease: deliveryLease},
+		{name: "timeout longer than the lease", timeout: 2 * time.Minute, wantLimit: 1, wantLease: 2*time.Minute + leaseSlack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, lease := claimSize(tt.timeout)
+			if limit != tt.wantLimit || lease != tt.wantLease {
+				t.Errorf("claimSize(%s) = %d, %s, want %d, %s", tt.timeout, limit, lease, tt.wantLimit, tt.wantLease)
+			}
+			// Sending a full batch must end before the lease runs out
+			if time.Duration(limit)*tt.timeout > lease {
+				t.Errorf("claimSize(%s): %d deliveries can take longer than the %s lease", tt.timeout, limit, lease)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: initialBackoff},
+		{attempts: 2, want: 2 * initialBackoff},
+		{attempts: 4, want: 8 * initialBackoff},
+		{attempts: 20, want: maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}